@@ -3,7 +3,19 @@ module auth-service
 go 1.24.5
 
 require (
+	github.com/FUNfarik/finance_microservices/proto/go v0.0.0-00010101000000-000000000000
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.41.0
+	google.golang.org/grpc v1.75.0
 )
+
+require (
+	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/protobuf v1.36.8 // indirect
+)
+
+replace github.com/FUNfarik/finance_microservices/proto/go => ../proto/go
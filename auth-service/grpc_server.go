@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/FUNfarik/finance_microservices/proto/go/auth"
+)
+
+// authServer implements the AuthService defined in auth.proto
+type authServer struct {
+	pb.UnimplementedAuthServiceServer
+	db *sql.DB
+}
+
+func grpcAddr() string {
+	port := os.Getenv("AUTH_GRPC_PORT")
+	if port == "" {
+		port = "8006"
+	}
+	return ":" + port
+}
+
+// startGRPCServer listens on AUTH_GRPC_PORT and serves AuthService in the background
+func startGRPCServer(db *sql.DB) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", grpcAddr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", grpcAddr(), err)
+	}
+
+	server := grpc.NewServer()
+	pb.RegisterAuthServiceServer(server, &authServer{db: db})
+
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			fmt.Printf("gRPC server stopped: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("gRPC AuthService listening on %s\n", grpcAddr())
+	return server, nil
+}
+
+// VerifyToken checks the signature and expiry of a JWT issued by /login and that it was not revoked
+func (s *authServer) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.VerifyTokenResponse, error) {
+	if req.Token == "" {
+		return &pb.VerifyTokenResponse{Valid: false, ErrorMessage: "token is required"}, nil
+	}
+
+	claims, err := verifyAccessToken(s.db, req.Token)
+	if err != nil {
+		return &pb.VerifyTokenResponse{Valid: false, ErrorMessage: err.Error()}, nil
+	}
+
+	return &pb.VerifyTokenResponse{
+		Valid:    true,
+		UserId:   int32(claims.UserID),
+		Username: claims.Username,
+	}, nil
+}
+
+// GetUser returns the users row for the given id
+func (s *authServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	var username, email string
+	var cash float64
+
+	err := s.db.QueryRowContext(ctx, "SELECT username, email, cash FROM users WHERE id = $1", req.UserId).Scan(&username, &email, &cash)
+	if err == sql.ErrNoRows {
+		return &pb.GetUserResponse{UserId: req.UserId, ErrorMessage: "user not found"}, nil
+	}
+	if err != nil {
+		fmt.Printf("GetUser failed for user %d: %v\n", req.UserId, err)
+		return &pb.GetUserResponse{UserId: req.UserId, ErrorMessage: "failed to load user"}, nil
+	}
+
+	return &pb.GetUserResponse{
+		UserId:      req.UserId,
+		Username:    username,
+		Email:       email,
+		CashBalance: cash,
+	}, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one private key of the keyring, identified in tokens by its kid
+type signingKey struct {
+	ID         string
+	Method     jwt.SigningMethod // RS256 for RSA keys, EdDSA for Ed25519 keys
+	Private    crypto.Signer
+	ActiveFrom time.Time // when the key starts signing, if it is in the schedule
+}
+
+// keyring holds every key tokens may be signed with. All of them verify and are
+// published at /.well-known/jwks.json; the scheduled key activated most recently signs.
+type keyring struct {
+	keys     map[string]*signingKey
+	schedule []*signingKey // scheduled keys, ordered by ActiveFrom
+}
+
+// signingKeys is loaded in main before any token is issued or checked
+var signingKeys *keyring
+
+// loadKeyring reads PEM private keys (PKCS#1 or PKCS#8, RSA of at least 2048 bits or Ed25519):
+//   - JWT_KEYS_DIR: one <kid>.pem file per key
+//   - JWT_PRIVATE_KEY: a single PEM key in the environment, with kid JWT_KEY_ID (default "default")
+//
+// JWT_KEY_SCHEDULE ("kid=2025-07-01T00:00:00Z,...") rotates keys by saying when each one starts
+// signing; keys left out of it only verify. It may be omitted when there is a single key.
+func loadKeyring() (*keyring, error) {
+	ring := &keyring{keys: make(map[string]*signingKey)}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if err := ring.add(strings.TrimSuffix(filepath.Base(path), ".pem"), data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	if pemData := os.Getenv("JWT_PRIVATE_KEY"); pemData != "" {
+		kid := os.Getenv("JWT_KEY_ID")
+		if kid == "" {
+			kid = "default"
+		}
+		if err := ring.add(kid, []byte(pemData)); err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: %w", err)
+		}
+	}
+
+	if len(ring.keys) == 0 {
+		return nil, fmt.Errorf("no JWT signing keys configured: set JWT_KEYS_DIR or JWT_PRIVATE_KEY")
+	}
+
+	schedule := os.Getenv("JWT_KEY_SCHEDULE")
+	if schedule == "" {
+		if len(ring.keys) > 1 {
+			return nil, fmt.Errorf("JWT_KEY_SCHEDULE is required with more than one key")
+		}
+		for _, key := range ring.keys {
+			ring.schedule = append(ring.schedule, key)
+		}
+		return ring, nil
+	}
+
+	for _, entry := range strings.Split(schedule, ",") {
+		kid, at, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid JWT_KEY_SCHEDULE entry %q, expected kid=RFC3339 time", entry)
+		}
+		key, ok := ring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("JWT_KEY_SCHEDULE names unknown key %q", kid)
+		}
+		activeFrom, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_KEY_SCHEDULE time for key %q: %w", kid, err)
+		}
+		key.ActiveFrom = activeFrom
+		ring.schedule = append(ring.schedule, key)
+	}
+	sort.Slice(ring.schedule, func(i, j int) bool {
+		return ring.schedule[i].ActiveFrom.Before(ring.schedule[j].ActiveFrom)
+	})
+	if _, err := ring.signer(time.Now()); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// add parses a PEM private key and stores it under kid
+func (k *keyring) add(kid string, data []byte) error {
+	if kid == "" {
+		return fmt.Errorf("key id is empty")
+	}
+	if _, exists := k.keys[kid]; exists {
+		return fmt.Errorf("duplicate key id %q", kid)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("no PEM data found")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported PEM block %q, expected a private key", block.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := &signingKey{ID: kid}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < 2048 {
+			return fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Private = private
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Private = private
+	default:
+		return fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", parsed)
+	}
+	k.keys[kid] = key
+	return nil
+}
+
+// signer returns the key that signs tokens at now
+func (k *keyring) signer(now time.Time) (*signingKey, error) {
+	for i := len(k.schedule) - 1; i >= 0; i-- {
+		if !k.schedule[i].ActiveFrom.After(now) {
+			return k.schedule[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no JWT signing key is active yet; check JWT_KEY_SCHEDULE")
+}
+
+// sign signs claims with the currently active key and names it in the kid header
+func (k *keyring) sign(claims jwt.Claims) (string, error) {
+	key, err := k.signer(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// verificationKey finds the public key for a token by its kid, refusing tokens whose
+// algorithm does not match the key
+func (k *keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.Private.Public(), nil
+}
+
+// jsonWebKey is the public half of a signing key in JWK form (RFC 7517, RFC 8037)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// jwks lists the public keys of every key in the ring, including ones scheduled to sign
+// later, so verifiers already know them when rotation happens
+func (k *keyring) jwks() []jsonWebKey {
+	ids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+
+	keys := make([]jsonWebKey, 0, len(ids))
+	for _, kid := range ids {
+		key := k.keys[kid]
+		jwk := jsonWebKey{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		keys = append(keys, jwk)
+	}
+	return keys
+}
+
+// jwksHandler serves GET /.well-known/jwks.json
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": signingKeys.jwks()})
+}
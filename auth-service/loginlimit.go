@@ -0,0 +1,380 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// attempts is the failed login history of one account or client address
+type attempts struct {
+	Failures int
+	Last     time.Time // when the most recent failure happened
+}
+
+// AttemptStore keeps failed login counts. The limiter uses one store keyed by email for
+// accounts and one keyed by address for clients.
+type AttemptStore interface {
+	// Get returns the failures recorded for key
+	Get(key string) (attempts, error)
+	// Attempt atomically checks key against policy and, unless it must wait, counts an
+	// attempt at now as a failure before it is verified. It returns the wait still due,
+	// in which case nothing is counted, and the failures including this attempt.
+	Attempt(key string, now time.Time, policy lockoutPolicy) (attempts, time.Duration, error)
+	// Forgive takes back one counted attempt once it turned out to be right
+	Forgive(key string) error
+	// Reset forgets the failures recorded for key
+	Reset(key string) error
+}
+
+// dbAttemptStore persists account failures on users (failed_login_attempts, last_failed_login_at)
+// so lockouts survive restarts and apply across instances. Unknown emails are not recorded.
+type dbAttemptStore struct {
+	db *sql.DB
+}
+
+func (s *dbAttemptStore) Get(email string) (attempts, error) {
+	var a attempts
+	var last sql.NullTime
+	err := s.db.QueryRow("SELECT failed_login_attempts, last_failed_login_at FROM users WHERE email = $1", email).Scan(&a.Failures, &last)
+	if err == sql.ErrNoRows {
+		return attempts{}, nil
+	}
+	a.Last = last.Time
+	return a, err
+}
+
+func (s *dbAttemptStore) Attempt(email string, now time.Time, policy lockoutPolicy) (attempts, time.Duration, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return attempts{}, 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes parallel attempts on one account check and count in turn
+	var a attempts
+	var last sql.NullTime
+	err = tx.QueryRow("SELECT failed_login_attempts, last_failed_login_at FROM users WHERE email = $1 FOR UPDATE",
+		email).Scan(&a.Failures, &last)
+	if err == sql.ErrNoRows {
+		return attempts{}, 0, nil
+	}
+	if err != nil {
+		return attempts{}, 0, err
+	}
+	a.Last = last.Time
+
+	if wait := policy.retryAfter(a, now); wait > 0 {
+		return a, wait, nil
+	}
+	a = policy.count(a, now)
+	_, err = tx.Exec("UPDATE users SET failed_login_attempts = $1, last_failed_login_at = $2 WHERE email = $3",
+		a.Failures, a.Last.UTC(), email)
+	if err != nil {
+		return attempts{}, 0, err
+	}
+	return a, 0, tx.Commit()
+}
+
+func (s *dbAttemptStore) Forgive(email string) error {
+	_, err := s.db.Exec("UPDATE users SET failed_login_attempts = failed_login_attempts - 1 WHERE email = $1 AND failed_login_attempts > 0", email)
+	return err
+}
+
+func (s *dbAttemptStore) Reset(email string) error {
+	_, err := s.db.Exec("UPDATE users SET failed_login_attempts = 0, last_failed_login_at = NULL WHERE email = $1 AND failed_login_attempts > 0", email)
+	return err
+}
+
+// memoryAttemptStore keeps failures in process memory; it tracks client addresses and
+// stands in for the database in tests
+type memoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]attempts
+}
+
+func newMemoryAttemptStore() *memoryAttemptStore {
+	return &memoryAttemptStore{entries: make(map[string]attempts)}
+}
+
+func (s *memoryAttemptStore) Get(key string) (attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *memoryAttemptStore) Attempt(key string, now time.Time, policy lockoutPolicy) (attempts, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.entries[key]
+	if wait := policy.retryAfter(a, now); wait > 0 {
+		return a, wait, nil
+	}
+	a = policy.count(a, now)
+	s.entries[key] = a
+	return a, 0, nil
+}
+
+func (s *memoryAttemptStore) Forgive(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if a.Failures <= 1 {
+		delete(s.entries, key)
+		return nil
+	}
+	a.Failures--
+	s.entries[key] = a
+	return nil
+}
+
+func (s *memoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// purge periodically drops entries whose last failure is older than maxAge
+func (s *memoryAttemptStore) purge(maxAge, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mu.Lock()
+		for key, a := range s.entries {
+			if now.Sub(a.Last) > maxAge {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// lockoutPolicy turns a failure count into how long the next attempt must wait
+type lockoutPolicy struct {
+	FreeAttempts int           // failures allowed before any wait
+	BaseDelay    time.Duration // wait after the first failure past FreeAttempts, doubling with each further one
+	MaxFailures  int           // failures that lock the key out
+	Lockout      time.Duration // how long a lockout lasts, and how long failures are remembered
+}
+
+// retryAfter returns how long after now the next attempt must wait, or 0 if it may go ahead
+func (p lockoutPolicy) retryAfter(a attempts, now time.Time) time.Duration {
+	if a.Failures == 0 || now.Sub(a.Last) > p.Lockout {
+		return 0
+	}
+
+	var wait time.Duration
+	switch {
+	case a.Failures >= p.MaxFailures:
+		wait = p.Lockout
+	case a.Failures > p.FreeAttempts:
+		doublings := a.Failures - p.FreeAttempts - 1
+		wait = time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(doublings)))
+		if wait > p.Lockout || wait <= 0 {
+			wait = p.Lockout
+		}
+	default:
+		return 0
+	}
+
+	if remaining := a.Last.Add(wait).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// count adds a failure at now to a, first forgetting failures older than the lockout
+func (p lockoutPolicy) count(a attempts, now time.Time) attempts {
+	if now.Sub(a.Last) > p.Lockout {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.Last = now
+	return a
+}
+
+// loginLimiter slows down and then locks out password guessing, per account and per client
+// address, so that neither guessing one account from many addresses nor many accounts from
+// one address goes unchecked
+type loginLimiter struct {
+	accounts      AttemptStore
+	clients       AttemptStore
+	accountPolicy lockoutPolicy
+	clientPolicy  lockoutPolicy
+}
+
+// newLoginLimiter reads LOGIN_MAX_FAILURES (failures locking an account, default 10),
+// LOGIN_IP_MAX_FAILURES (failures locking a client address, default 50) and LOGIN_LOCKOUT
+// (e.g. "15m", how long a lockout lasts)
+func newLoginLimiter(accounts, clients AttemptStore) *loginLimiter {
+	lockout, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT"))
+	if err != nil || lockout <= 0 {
+		lockout = 15 * time.Minute
+	}
+	return &loginLimiter{
+		accounts: accounts,
+		clients:  clients,
+		accountPolicy: lockoutPolicy{
+			FreeAttempts: 3,
+			BaseDelay:    time.Second,
+			MaxFailures:  envInt("LOGIN_MAX_FAILURES", 10),
+			Lockout:      lockout,
+		},
+		clientPolicy: lockoutPolicy{
+			FreeAttempts: 10,
+			BaseDelay:    time.Second,
+			MaxFailures:  envInt("LOGIN_IP_MAX_FAILURES", 50),
+			Lockout:      lockout,
+		},
+	}
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// begin returns how long a login for email from ip must wait, or 0 if it may go ahead. An
+// attempt that may go ahead is counted as a failure before its password or second factor is
+// checked, so parallel guesses cannot all pass the check; forgive or succeed takes it back.
+func (l *loginLimiter) begin(email, ip string) (time.Duration, error) {
+	now := time.Now()
+	_, wait, err := l.clients.Attempt(ip, now, l.clientPolicy)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	account, wait, err := l.accounts.Attempt(email, now, l.accountPolicy)
+	if err == nil && wait == 0 {
+		if account.Failures == l.accountPolicy.MaxFailures {
+			fmt.Printf("Account locked for %s after %d failed logins\n", l.accountPolicy.Lockout, account.Failures)
+		}
+		return 0, nil
+	}
+	// The attempt is refused, so it does not count against the client
+	if forgiveErr := l.clients.Forgive(ip); forgiveErr != nil && err == nil {
+		err = forgiveErr
+	}
+	return wait, err
+}
+
+// forgive takes back an attempt whose password was right but which still needs a second factor
+func (l *loginLimiter) forgive(email, ip string) error {
+	if err := l.accounts.Forgive(email); err != nil {
+		return err
+	}
+	return l.clients.Forgive(ip)
+}
+
+// succeed clears the account's failures once a login completes. The client address only
+// has this attempt taken back, so that logging into an account of one's own does not reset
+// guessing at others.
+func (l *loginLimiter) succeed(email, ip string) error {
+	if err := l.accounts.Reset(email); err != nil {
+		return err
+	}
+	return l.clients.Forgive(ip)
+}
+
+// clientIP returns the address a request came from. Behind the nginx gateway every request
+// arrives from nginx, so with TRUST_PROXY_HEADERS=true the X-Real-IP it sets is used instead.
+func clientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeTooManyAttempts answers a login that must wait with 429 and Retry-After in whole seconds
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte(fmt.Sprintf(`{"error": "Too many failed login attempts, try again later", "status": "error", "retry_after": %d}`, seconds)))
+}
+
+// UnlockRequest names the account and/or client address an admin unlocks
+type UnlockRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+// unlockHandler serves POST /admin/unlock {"email": "...", "ip": "..."}, clearing failed
+// logins before the lockout runs out. It requires the X-Admin-Key header to match
+// ADMIN_API_KEY and is disabled when that is not set.
+func unlockHandler(limiter *loginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		adminKey := os.Getenv("ADMIN_API_KEY")
+		if adminKey == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(adminKey)) != 1 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Forbidden", "status": "error"}`))
+			return
+		}
+
+		var unlockReq UnlockRequest
+		if err := readJSON(r, &unlockReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if unlockReq.Email == "" && unlockReq.IP == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "email or ip is required", "status": "error"}`))
+			return
+		}
+
+		if unlockReq.Email != "" {
+			if err := limiter.accounts.Reset(unlockReq.Email); err != nil {
+				fmt.Printf("Failed to unlock account: %v\n", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "Could not unlock", "status": "error"}`))
+				return
+			}
+		}
+		if unlockReq.IP != "" {
+			if err := limiter.clients.Reset(unlockReq.IP); err != nil {
+				fmt.Printf("Failed to unlock client address: %v\n", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "Could not unlock", "status": "error"}`))
+				return
+			}
+		}
+
+		fmt.Println("Admin cleared failed login attempts")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Unlocked", "status": "success"}`))
+	}
+}
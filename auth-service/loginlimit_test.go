@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyRetryAfter(t *testing.T) {
+	policy := lockoutPolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: 15 * time.Minute}
+	last := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		failures int
+		elapsed  time.Duration
+		want     time.Duration
+	}{
+		{"no failures", 0, 0, 0},
+		{"free attempts", 3, 0, 0},
+		{"first delay", 4, 0, time.Second},
+		{"doubling", 5, 0, 2 * time.Second},
+		{"doubling again", 6, 0, 4 * time.Second},
+		{"last delay before lockout", 9, 0, 32 * time.Second},
+		{"part of the delay passed", 5, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"delay passed", 5, 3 * time.Second, 0},
+		{"locked out", 10, 0, 15 * time.Minute},
+		{"past max failures", 12, time.Minute, 14 * time.Minute},
+		{"lockout over", 10, 16 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		got := policy.retryAfter(attempts{Failures: tt.failures, Last: last}, last.Add(tt.elapsed))
+		if got != tt.want {
+			t.Errorf("%s: retryAfter(%d failures, %s later) = %s, want %s", tt.name, tt.failures, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutPolicyDelayIsCapped(t *testing.T) {
+	// Without a lower MaxFailures the doubling delay must still stop at the lockout
+	policy := lockoutPolicy{FreeAttempts: 0, BaseDelay: time.Second, MaxFailures: 1000, Lockout: time.Hour}
+	last := time.Now()
+	for _, failures := range []int{13, 40, 200} {
+		if got := policy.retryAfter(attempts{Failures: failures, Last: last}, last); got != time.Hour {
+			t.Errorf("retryAfter(%d failures) = %s, want %s", failures, got, time.Hour)
+		}
+	}
+}
+
+func TestMemoryAttemptStore(t *testing.T) {
+	store := newMemoryAttemptStore()
+	policy := lockoutPolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: time.Minute}
+	start := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		a, wait, err := store.Attempt("key", start.Add(time.Duration(i)*time.Second), policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != 0 || a.Failures != i {
+			t.Errorf("attempt %d: waited %s with %d failures, want no wait and %d", i, wait, a.Failures, i)
+		}
+	}
+
+	// Taking back an attempt leaves the others
+	if err := store.Forgive("key"); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := store.Get("key"); a.Failures != 2 {
+		t.Errorf("after Forgive Get reported %d failures, want 2", a.Failures)
+	}
+
+	// An attempt after the lockout window starts counting again
+	a, _, _ := store.Attempt("key", start.Add(5*time.Minute), policy)
+	if a.Failures != 1 {
+		t.Errorf("attempt after the window counted %d failures, want 1", a.Failures)
+	}
+
+	if err := store.Reset("key"); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := store.Get("key"); a.Failures != 0 {
+		t.Errorf("after Reset Get reported %d failures, want 0", a.Failures)
+	}
+}
+
+func TestMemoryAttemptStoreRefusesWithoutCounting(t *testing.T) {
+	store := newMemoryAttemptStore()
+	policy := lockoutPolicy{FreeAttempts: 0, BaseDelay: time.Minute, MaxFailures: 10, Lockout: time.Hour}
+	now := time.Now()
+
+	store.Attempt("key", now, policy)
+	a, wait, err := store.Attempt("key", now.Add(time.Second), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != 59*time.Second || a.Failures != 1 {
+		t.Errorf("refused attempt waited %s with %d failures, want 59s and 1", wait, a.Failures)
+	}
+	if a, _ := store.Get("key"); !a.Last.Equal(now) {
+		t.Errorf("refused attempt moved the last failure to %s", a.Last)
+	}
+}
+
+func TestLoginLimiter(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "")
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "")
+	t.Setenv("LOGIN_LOCKOUT", "")
+	limiter := newLoginLimiter(newMemoryAttemptStore(), newMemoryAttemptStore())
+
+	// Four wrong passwords: each attempt is counted when it begins and never taken back
+	for i := 0; i < 4; i++ {
+		if wait, err := limiter.begin("victim@example.com", "203.0.113.7"); err != nil || wait != 0 {
+			t.Fatalf("attempt %d: wait %s, err %v", i+1, wait, err)
+		}
+	}
+
+	wait, err := limiter.begin("victim@example.com", "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("after 4 failures the account waits %s, want up to 1s", wait)
+	}
+	// The refused attempt did not count against the address
+	if client, _ := limiter.clients.Get("203.0.113.7"); client.Failures != 4 {
+		t.Errorf("the address has %d failures after a refused attempt, want 4", client.Failures)
+	}
+
+	// The address is still within its free attempts for other accounts
+	if wait, _ := limiter.begin("other@example.com", "203.0.113.7"); wait != 0 {
+		t.Errorf("another account from the same address waits %s, want 0", wait)
+	}
+
+	// Logging in clears the account and takes back only that attempt from the address
+	if err := limiter.succeed("other@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if client, _ := limiter.clients.Get("203.0.113.7"); client.Failures != 4 {
+		t.Errorf("the address has %d failures after a login, want 4", client.Failures)
+	}
+	if err := limiter.accounts.Reset("victim@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := limiter.begin("victim@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("after the account was cleared it waits %s, want 0", wait)
+	}
+}
+
+func TestLoginLimiterParallelGuesses(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "")
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "")
+	t.Setenv("LOGIN_LOCKOUT", "")
+	limiter := newLoginLimiter(newMemoryAttemptStore(), newMemoryAttemptStore())
+
+	// Guesses sent at once from many addresses must not all pass the check
+	const guesses = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wait, err := limiter.begin("victim@example.com", fmt.Sprintf("198.51.100.%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Three free attempts and the one after them, which sets the first delay
+	if want := limiter.accountPolicy.FreeAttempts + 1; allowed != want {
+		t.Errorf("%d of %d parallel guesses went ahead, want %d", allowed, guesses, want)
+	}
+}
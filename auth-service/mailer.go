@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer sends account emails such as verification and password reset links
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// smtpMailer delivers mail through an SMTP relay, authenticating when a username is set
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	// Addresses come from user input; a line break would let them add headers
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send mail via %s: %w", m.addr, err)
+	}
+	return nil
+}
+
+// logMailer writes mail to a file, or to stdout when path is empty, for local development
+type logMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (m *logMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := fmt.Sprintf("--- %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), to, subject, body)
+	if m.path == "" {
+		fmt.Print(entry)
+		return nil
+	}
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(entry)
+	return err
+}
+
+// newMailer sends through SMTP_HOST (with SMTP_PORT, default 587, SMTP_USERNAME, SMTP_PASSWORD
+// and MAIL_FROM) when it is set, and otherwise writes mail to MAIL_LOG_FILE or stdout
+func newMailer() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return &logMailer{path: os.Getenv("MAIL_LOG_FILE")}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	mailer := &smtpMailer{addr: net.JoinHostPort(host, port), from: from}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		mailer.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return mailer
+}
+
+// mailerName describes where mail goes, for the startup log
+func mailerName(m Mailer) string {
+	switch m := m.(type) {
+	case *smtpMailer:
+		return "SMTP via " + m.addr
+	case *logMailer:
+		if m.path != "" {
+			return "file " + m.path
+		}
+	}
+	return "stdout"
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -58,6 +59,7 @@ func enableCORS(next http.HandlerFunc) http.HandlerFunc {
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -69,7 +71,8 @@ func enableCORS(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// jwtMiddleware lets requests through only with a valid, unrevoked access token
+func jwtMiddleware(db *sql.DB, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -82,30 +85,125 @@ func jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := verifyAccessToken(db, tokenString)
+		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		claims := token.Claims.(jwt.MapClaims)
-		userID := int(claims["user_id"].(float64))
-		username := claims["username"].(string)
 
-		fmt.Printf("User %s (ID: %d) authenticated\n", username, userID)
+		fmt.Printf("User %s (ID: %d) authenticated\n", claims.Username, claims.UserID)
 		next(w, r)
 	}
 }
 
-var jwtSecret = []byte(getJWTSecret())
+// accessClaims is the identity carried by an access token
+type accessClaims struct {
+	UserID    int
+	Username  string
+	ID        string // jti, the key for revoking the token
+	ExpiresAt time.Time
+}
+
+// parseToken validates a signed JWT and returns the user it was issued for.
+// It does not consult the revocation list; see verifyAccessToken.
+func parseToken(tokenString string) (*accessClaims, error) {
+	token, err := jwt.Parse(tokenString, signingKeys.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired(),
+		jwt.WithIssuer(tokenIssuer()), jwt.WithAudience(tokenAudience()))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
 
-func getJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "default-secret-key"
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("user_id not found in token")
+	}
+	username, ok := claims["username"].(string)
+	if !ok {
+		return nil, fmt.Errorf("username not found in token")
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, fmt.Errorf("jti not found in token")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, err
 	}
-	return secret
+	return &accessClaims{UserID: int(userID), Username: username, ID: jti, ExpiresAt: exp.Time}, nil
+}
+
+// loginResponse is the JSON body returned when a login completes
+func loginResponse(tokens *tokenPair, userID int, username, email string, cash float64, emailVerified bool) string {
+	return fmt.Sprintf(`{
+			"message": "Login successful", 
+			"status": "success",
+			"token": "%s",
+			"refresh_token": "%s",
+			"expires_in": %d,
+			"user": {
+				"id": %d,
+				"username": "%s",
+				"email": "%s",
+				"email_verified": %t,
+				"cash": %.2f
+			}
+		}`, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn, userID, username, email, emailVerified, cash)
+}
+
+// getInitialCash returns the opening balance credited to new accounts (INITIAL_CASH, default 10000.00)
+func getInitialCash() string {
+	initialCash := os.Getenv("INITIAL_CASH")
+	if amount, err := strconv.ParseFloat(initialCash, 64); err != nil || amount < 0 {
+		return "10000.00"
+	}
+	return initialCash
+}
+
+// createUser inserts a user and records their opening balance in the cash ledger
+func createUser(db *sql.DB, username string, passwordHash []byte, email string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	initialCash := getInitialCash()
+	var userID int
+	err = tx.QueryRow("INSERT INTO users (username, password_hash, email, cash, created_at) VALUES ($1, $2, $3, $4, NOW()) RETURNING id",
+		username, passwordHash, email, initialCash).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Open the books: journal the opening balance and record it in the cash ledger
+	var journalID int
+	err = tx.QueryRow("INSERT INTO journal_entries (user_id, entry_type, description) VALUES ($1, 'OPENING', 'opening balance') RETURNING id",
+		userID).Scan(&journalID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO postings (entry_id, user_id, account, amount)
+		VALUES ($1, $2, 'CASH', $3::numeric), ($1, $2, 'OPENING_BALANCE', -$3::numeric)`, journalID, userID, initialCash)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO cash_ledger (user_id, entry_type, debit_account, credit_account, amount, balance_after, journal_entry_id, description)
+		VALUES ($1, 'OPENING', 'CASH', 'OPENING_BALANCE', $2, $2, $3, 'opening balance')`, userID, initialCash, journalID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
 }
 
 func connectDB() (*sql.DB, error) {
@@ -144,22 +242,40 @@ func connectDB() (*sql.DB, error) {
 	return db, nil
 }
 
-func generateJWT(userID int, username string) (string, error) {
+// generateJWT signs a short-lived access token and returns it with its jti
+func generateJWT(userID int, username string) (string, string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"jti":      jti,
+		"iss":      tokenIssuer(),
+		"aud":      tokenAudience(),
+		"exp":      time.Now().Add(accessTokenTTL()).Unix(),
 		"iat":      time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := signingKeys.sign(claims)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	return tokenString, nil
+	return tokenString, jti, nil
 }
 
 func main() {
+	// Refuse to start without key material rather than sign with a guessable default
+	var err error
+	signingKeys, err = loadKeyring()
+	if err != nil {
+		fmt.Printf("Couldn't load JWT signing keys: %v\n", err)
+		os.Exit(1)
+	}
+	if key, err := signingKeys.signer(time.Now()); err == nil {
+		fmt.Printf("Signing tokens with key %s (%s), %d key(s) published\n", key.ID, key.Method.Alg(), len(signingKeys.keys))
+	}
+
 	db, err := connectDB()
 	if err != nil {
 		fmt.Printf("Couldn't connect to PostgreSQL: %v\n", err)
@@ -169,8 +285,28 @@ func main() {
 
 	fmt.Println("Auth Service is running...")
 
+	// Start gRPC server next to the HTTP endpoints
+	grpcServer, err := startGRPCServer(db)
+	if err != nil {
+		fmt.Printf("Couldn't start gRPC server: %v\n", err)
+		return
+	}
+	defer grpcServer.GracefulStop()
+
+	// Drop revoked and refresh tokens once they have expired
+	go purgeExpiredTokens(db, time.Hour)
+
+	// Verification and password reset emails
+	mailer := newMailer()
+	fmt.Printf("Sending account emails to %s\n", mailerName(mailer))
+
+	// Failed logins: persisted per account on users, kept in memory per client address
+	clientAttempts := newMemoryAttemptStore()
+	limiter := newLoginLimiter(&dbAttemptStore{db: db}, clientAttempts)
+	go clientAttempts.purge(limiter.clientPolicy.Lockout, 10*time.Minute)
+
 	// Protected profile endpoint
-	http.HandleFunc("/profile", enableCORS(jwtMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/profile", enableCORS(jwtMiddleware(db, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"message": "Protected endpoint", "status": "success", "note": "You are authenticated!"}`))
 	})))
@@ -213,14 +349,33 @@ func main() {
 			return
 		}
 
+		// Refuse guesses from a locked-out account or client until their wait is over; the
+		// attempt counts as a failure until the password proves right
+		ip := clientIP(r)
+		wait, err := limiter.begin(loginReq.Email, ip)
+		if err != nil {
+			fmt.Printf("Failed to check login attempts: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not process login", "status": "error"}`))
+			return
+		}
+		if wait > 0 {
+			writeTooManyAttempts(w, wait)
+			return
+		}
+
 		// Query user by email
 		var username, passwordHash string
 		var userID int
 		var cash float64
+		var totpEnabled, emailVerified bool
 
-		err = db.QueryRow("SELECT id, username, password_hash, cash FROM users WHERE email = $1", loginReq.Email).Scan(&userID, &username, &passwordHash, &cash)
+		err = db.QueryRow("SELECT id, username, password_hash, cash, totp_enabled, email_verified FROM users WHERE email = $1",
+			loginReq.Email).Scan(&userID, &username, &passwordHash, &cash, &totpEnabled, &emailVerified)
 		if err != nil {
-			fmt.Printf("Login attempt failed for email: %s - %v\n", loginReq.Email, err)
+			if err != sql.ErrNoRows {
+				fmt.Printf("Login lookup failed: %v\n", err)
+			}
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(`{"error": "Invalid email or password", "status": "error"}`))
 			return
@@ -229,14 +384,38 @@ func main() {
 		// Check password
 		err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(loginReq.Password))
 		if err != nil {
-			fmt.Printf("Password verification failed for user: %s\n", username)
+			fmt.Printf("Password verification failed for user ID %d\n", userID)
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(`{"error": "Invalid email or password", "status": "error"}`))
 			return
 		}
 
-		// Generate JWT token
-		token, err := generateJWT(userID, username)
+		// With two-factor authentication the password only earns a challenge for /login/2fa,
+		// and failed attempts are cleared once that succeeds
+		if totpEnabled {
+			if err := limiter.forgive(loginReq.Email, ip); err != nil {
+				fmt.Printf("Failed to take back login attempt for user ID %d: %v\n", userID, err)
+			}
+			challenge, err := createLoginChallenge(db, userID)
+			if err != nil {
+				fmt.Printf("Login challenge failed for user %s: %v\n", username, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "Could not start two-factor login", "status": "error"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf(`{
+			"message": "Two-factor authentication required",
+			"status": "success",
+			"two_factor_required": true,
+			"challenge_token": "%s",
+			"expires_in": %d
+		}`, challenge, int(challengeTTL.Seconds()))))
+			return
+		}
+
+		// Start a session: a short-lived access token and a refresh token
+		tokens, err := login(db, userID, username)
 		if err != nil {
 			fmt.Printf("JWT generation failed for user %s: %v\n", username, err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -244,23 +423,15 @@ func main() {
 			return
 		}
 
+		if err := limiter.succeed(loginReq.Email, ip); err != nil {
+			fmt.Printf("Failed to clear login attempts for user ID %d: %v\n", userID, err)
+		}
+
 		// Success response
 		fmt.Printf("User %s (ID: %d) logged in successfully\n", username, userID)
 
-		response := fmt.Sprintf(`{
-			"message": "Login successful", 
-			"status": "success",
-			"token": "%s",
-			"user": {
-				"id": %d,
-				"username": "%s",
-				"email": "%s",
-				"cash": %.2f
-			}
-		}`, token, userID, username, loginReq.Email, cash)
-
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(response))
+		w.Write([]byte(loginResponse(tokens, userID, username, loginReq.Email, cash, emailVerified)))
 	}))
 
 	// Register endpoint - Updated to handle JSON
@@ -318,10 +489,8 @@ func main() {
 			return
 		}
 
-		// Insert new user with default cash amount
-		var newUserID int
-		err = db.QueryRow("INSERT INTO users (username, password_hash, email, cash, created_at) VALUES ($1, $2, $3, $4, NOW()) RETURNING id",
-			registerReq.Username, hashedPassword, registerReq.Email, 10000.00).Scan(&newUserID)
+		// Insert new user with the opening cash balance
+		newUserID, err := createUser(db, registerReq.Username, hashedPassword, registerReq.Email)
 		if err != nil {
 			fmt.Printf("Failed to create user: %v\n", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -329,11 +498,44 @@ func main() {
 			return
 		}
 
+		// Trading stays locked until the address is confirmed; a failed send can be retried at /email/verify/resend
+		go func() {
+			if err := sendVerificationEmail(db, mailer, newUserID, registerReq.Email); err != nil {
+				fmt.Printf("Failed to send verification email to user ID %d: %v\n", newUserID, err)
+			}
+		}()
+
 		fmt.Printf("✅ New user registered: %s (ID: %d)\n", registerReq.Username, newUserID)
 		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte(`{"message": "Successfully registered", "status": "success"}`))
+		w.Write([]byte(`{"message": "Successfully registered. Check your email to verify your address.", "status": "success"}`))
 	}))
 
+	// Exchange a refresh token for a new token pair
+	http.HandleFunc("/token/refresh", enableCORS(refreshHandler(db)))
+
+	// Second login step for users with two-factor authentication
+	http.HandleFunc("/login/2fa", enableCORS(loginTwoFactorHandler(db, limiter)))
+
+	// TOTP enrolment: generate a secret, confirm a code to enable, or turn it off again
+	http.HandleFunc("/2fa/enroll", enableCORS(twoFactorEnrollHandler(db)))
+	http.HandleFunc("/2fa/verify", enableCORS(twoFactorVerifyHandler(db)))
+	http.HandleFunc("/2fa/disable", enableCORS(twoFactorDisableHandler(db)))
+
+	// Password reset and email verification by emailed single-use links
+	http.HandleFunc("/password/forgot", enableCORS(forgotPasswordHandler(db, mailer)))
+	http.HandleFunc("/password/reset", enableCORS(resetPasswordHandler(db)))
+	http.HandleFunc("/email/verify", enableCORS(verifyEmailHandler(db)))
+	http.HandleFunc("/email/verify/resend", enableCORS(resendVerificationHandler(db, mailer)))
+
+	// Clear failed logins for a locked-out account or client address (requires ADMIN_API_KEY)
+	http.HandleFunc("/admin/unlock", unlockHandler(limiter))
+
+	// Revoke the session a refresh token belongs to
+	http.HandleFunc("/logout", enableCORS(logoutHandler(db)))
+
+	// Public keys for verifying tokens
+	http.HandleFunc("/.well-known/jwks.json", enableCORS(jwksHandler))
+
 	// Health check endpoint
 	http.HandleFunc("/health", enableCORS(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -343,9 +545,22 @@ func main() {
 	fmt.Println("Server is running on http://localhost:8001")
 	fmt.Println("Available endpoints:")
 	fmt.Println("- http://localhost:8001/login")
+	fmt.Println("- http://localhost:8001/login/2fa")
 	fmt.Println("- http://localhost:8001/register")
+	fmt.Println("- http://localhost:8001/token/refresh")
+	fmt.Println("- http://localhost:8001/logout")
+	fmt.Println("- http://localhost:8001/password/forgot")
+	fmt.Println("- http://localhost:8001/password/reset")
+	fmt.Println("- http://localhost:8001/email/verify")
+	fmt.Println("- http://localhost:8001/email/verify/resend")
 	fmt.Println("- http://localhost:8001/health")
 	fmt.Println("- http://localhost:8001/profile")
+	fmt.Println("- http://localhost:8001/2fa/enroll")
+	fmt.Println("- http://localhost:8001/2fa/verify")
+	fmt.Println("- http://localhost:8001/2fa/disable")
+	fmt.Println("- http://localhost:8001/.well-known/jwks.json")
+	fmt.Println("- http://localhost:8001/admin/unlock")
+	fmt.Printf("- gRPC AuthService on %s\n", grpcAddr())
 
 	http.ListenAndServe(":8001", nil)
 }
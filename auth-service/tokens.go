@@ -0,0 +1,381 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	errRefreshTokenReused  = errors.New("refresh token already used; all sessions from this login were revoked")
+)
+
+// RefreshRequest carries the refresh token for /token/refresh and /logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenPair is what a login or refresh hands to the client
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // access token lifetime in seconds
+}
+
+// accessTokenTTL reads ACCESS_TOKEN_TTL (e.g. "15m"), defaulting to 15 minutes
+func accessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// tokenIssuer reads JWT_ISSUER, the "iss" claim of access tokens, defaulting to "auth-service"
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "auth-service"
+}
+
+// tokenAudience reads JWT_AUDIENCE, the "aud" claim of access tokens, defaulting to "finance-api"
+func tokenAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "finance-api"
+}
+
+// refreshTokenTTL reads REFRESH_TOKEN_TTL (e.g. "720h"), defaulting to 30 days
+func refreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return ttl
+}
+
+// randomToken returns n random bytes encoded for use in URLs and JSON
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how refresh tokens are stored; only the client holds the token itself
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens signs an access token and stores a new refresh token in familyID,
+// starting a new family (a new login session) when familyID is empty
+func issueTokens(tx *sql.Tx, userID int, username string, familyID string) (*tokenPair, error) {
+	accessToken, jti, err := generateJWT(userID, username)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO refresh_tokens (user_id, family_id, token_hash, access_jti, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second', NOW() + $6 * INTERVAL '1 second')`,
+		userID, familyID, hashToken(refreshToken), jti, int(accessTokenTTL().Seconds()), int(refreshTokenTTL().Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// login starts a new session for a user whose credentials were checked
+func login(db *sql.DB, userID int, username string) (*tokenPair, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pair, err := issueTokens(tx, userID, username, "")
+	if err != nil {
+		return nil, err
+	}
+	return pair, tx.Commit()
+}
+
+// refreshTokens exchanges a refresh token for a new token pair in the same family. The old
+// refresh token is spent; presenting a spent or revoked one again means it was stolen or
+// replayed, so the whole family is revoked.
+func refreshTokens(db *sql.DB, refreshToken string) (*tokenPair, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	var username, familyID string
+	var expired, spent bool
+	err = tx.QueryRow(`SELECT r.id, r.user_id, u.username, r.family_id, r.expires_at < NOW(), r.used_at IS NOT NULL OR r.revoked_at IS NOT NULL
+		FROM refresh_tokens r JOIN users u ON u.id = r.user_id
+		WHERE r.token_hash = $1
+		FOR UPDATE OF r`, hashToken(refreshToken)).Scan(&id, &userID, &username, &familyID, &expired, &spent)
+	if err == sql.ErrNoRows {
+		return nil, errInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if spent {
+		if err := revokeFamily(tx, familyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Refresh token reuse detected for user %s (ID: %d); session revoked\n", username, userID)
+		return nil, errRefreshTokenReused
+	}
+	if expired {
+		return nil, errInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	pair, err := issueTokens(tx, userID, username, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return pair, tx.Commit()
+}
+
+// revokeFamily ends a login session: every refresh token in the family stops working and
+// the access tokens issued with them are added to the revocation list
+func revokeFamily(tx *sql.Tx, familyID string) error {
+	_, err := tx.Exec(`INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens
+		WHERE family_id = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING`, familyID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+	return err
+}
+
+// revokeUserSessions ends every login session of a user, as revokeFamily does for one
+func revokeUserSessions(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec(`INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens
+		WHERE user_id = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+// logout revokes the session a refresh token belongs to; returns false for an unknown token
+func logout(db *sql.DB, refreshToken string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRow("SELECT family_id FROM refresh_tokens WHERE token_hash = $1", hashToken(refreshToken)).Scan(&familyID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := revokeFamily(tx, familyID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// revokeAccessToken adds a single access token to the revocation list
+func revokeAccessToken(db *sql.DB, claims *accessClaims) error {
+	_, err := db.Exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING",
+		claims.ID, claims.ExpiresAt)
+	return err
+}
+
+// isTokenRevoked reports whether the access token with this jti was revoked
+func isTokenRevoked(db *sql.DB, jti string) (bool, error) {
+	var revoked bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	return revoked, err
+}
+
+// verifyAccessToken checks an access token's signature and expiry and that it was not revoked
+func verifyAccessToken(db *sql.DB, tokenString string) (*accessClaims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := isTokenRevoked(db, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+	return claims, nil
+}
+
+// purgeExpiredTokens periodically deletes revocation entries, refresh tokens, login
+// challenges and emailed account tokens that have expired
+func purgeExpiredTokens(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := db.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()"); err != nil {
+			fmt.Printf("Failed to purge revoked tokens: %v\n", err)
+		}
+		if _, err := db.Exec("DELETE FROM refresh_tokens WHERE expires_at < NOW()"); err != nil {
+			fmt.Printf("Failed to purge refresh tokens: %v\n", err)
+		}
+		if _, err := db.Exec("DELETE FROM login_challenges WHERE expires_at < NOW()"); err != nil {
+			fmt.Printf("Failed to purge login challenges: %v\n", err)
+		}
+		if _, err := db.Exec("DELETE FROM account_tokens WHERE expires_at < NOW()"); err != nil {
+			fmt.Printf("Failed to purge account tokens: %v\n", err)
+		}
+	}
+}
+
+// tokenResponse is the JSON body returned with a new token pair
+func tokenResponse(message string, pair *tokenPair) string {
+	return fmt.Sprintf(`{
+			"message": "%s",
+			"status": "success",
+			"token": "%s",
+			"refresh_token": "%s",
+			"expires_in": %d
+		}`, message, pair.AccessToken, pair.RefreshToken, pair.ExpiresIn)
+}
+
+// readRefreshRequest parses the refresh token out of a request body
+func readRefreshRequest(r *http.Request) (string, error) {
+	var refreshReq RefreshRequest
+	if err := readJSON(r, &refreshReq); err != nil {
+		return "", err
+	}
+	if refreshReq.RefreshToken == "" {
+		return "", fmt.Errorf("refresh_token is required")
+	}
+	return refreshReq.RefreshToken, nil
+}
+
+// refreshHandler serves POST /token/refresh {"refresh_token": "..."}
+func refreshHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		refreshToken, err := readRefreshRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+
+		pair, err := refreshTokens(db, refreshToken)
+		if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if err != nil {
+			fmt.Printf("Token refresh failed: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not refresh token", "status": "error"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(tokenResponse("Token refreshed", pair)))
+	}
+}
+
+// logoutHandler serves POST /logout {"refresh_token": "..."}. It revokes the whole session the
+// refresh token belongs to, and the bearer access token too when one is sent.
+func logoutHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		refreshToken, err := readRefreshRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+
+		if tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if claims, err := parseToken(tokenString); err == nil {
+				if err := revokeAccessToken(db, claims); err != nil {
+					fmt.Printf("Failed to revoke access token: %v\n", err)
+				}
+			}
+		}
+
+		found, err := logout(db, refreshToken)
+		if err != nil {
+			fmt.Printf("Logout failed: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not log out", "status": "error"}`))
+			return
+		}
+		if !found {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Invalid refresh token", "status": "error"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Logged out", "status": "success"}`))
+	}
+}
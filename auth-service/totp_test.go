@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 appendix B test vectors
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTPDriftWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset := int64(-totpSkew - 1); offset <= totpSkew+1; offset++ {
+		code, err := totpCode(rfc6238Secret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := matchTOTP(rfc6238Secret, code, now)
+		inWindow := offset >= -totpSkew && offset <= totpSkew
+		if ok != inWindow {
+			t.Errorf("code from %d periods away: matched %t, want %t", offset, ok, inWindow)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code from %d periods away matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestMatchTOTPRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082"} {
+		if _, ok := matchTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("matchTOTP accepted %q", code)
+		}
+	}
+	if _, ok := matchTOTP("not base32!", "287082", now); ok {
+		t.Error("matchTOTP accepted a code for an invalid secret")
+	}
+}
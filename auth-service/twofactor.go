@@ -0,0 +1,490 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	challengeTTL          = 5 * time.Minute // how long a password-checked login waits for its second factor
+	maxChallengeAttempts  = 5               // wrong codes before a challenge is thrown away
+	recoveryCodeCount     = 10
+	recoveryCodeGroupSize = 4
+)
+
+// TwoFactorCodeRequest carries a TOTP code, or a recovery code where one is accepted
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// LoginTwoFactorRequest completes a login that /login answered with a challenge
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// readJSON parses a request body into v, returning the message to show the client on failure
+func readJSON(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("Could not read request body")
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("Invalid JSON format")
+	}
+	return nil
+}
+
+// bearerClaims returns the user behind the request's access token
+func bearerClaims(db *sql.DB, r *http.Request) (*accessClaims, error) {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	return verifyAccessToken(db, tokenString)
+}
+
+// createLoginChallenge stores a single-use challenge for a user whose password was checked
+// and returns the token the client exchanges, with a code, at /login/2fa
+func createLoginChallenge(db *sql.DB, userID int) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec("INSERT INTO login_challenges (token_hash, user_id, expires_at) VALUES ($1, $2, NOW() + $3 * INTERVAL '1 second')",
+		hashToken(token), userID, int(challengeTTL.Seconds()))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// newRecoveryCode returns a random code formatted as XXXX-XXXX-XXXX-XXXX
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := totpEncoding.EncodeToString(b)
+	var groups []string
+	for i := 0; i < len(code); i += recoveryCodeGroupSize {
+		groups = append(groups, code[i:i+recoveryCodeGroupSize])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes in any case, with or without dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// replaceRecoveryCodes discards a user's recovery codes and stores hashes of a new set,
+// returning the codes themselves to be shown once
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code for a user with
+// two-factor authentication enabled. Each TOTP code and recovery code works only once.
+func checkSecondFactor(tx *sql.Tx, userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totpDigits {
+		var secret sql.NullString
+		if err := tx.QueryRow("SELECT totp_secret FROM users WHERE id = $1 AND totp_enabled", userID).Scan(&secret); err != nil {
+			if err == sql.ErrNoRows {
+				return false, nil
+			}
+			return false, err
+		}
+		step, ok := matchTOTP(secret.String, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// Refuse a code that was already used, even while it is still current
+		result, err := tx.Exec("UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)",
+			step, userID)
+		if err != nil {
+			return false, err
+		}
+		n, err := result.RowsAffected()
+		return n == 1, err
+	}
+
+	result, err := tx.Exec("UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// twoFactorEnrollHandler serves POST /2fa/enroll: it generates a new TOTP secret for the
+// authenticated user and returns it with an otpauth:// URI for authenticator apps.
+// Two-factor authentication stays off until a code is confirmed at /2fa/verify.
+func twoFactorEnrollHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		claims, err := bearerClaims(db, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Authentication required", "status": "error"}`))
+			return
+		}
+
+		secret, err := newTOTPSecret()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not generate secret", "status": "error"}`))
+			return
+		}
+
+		var email string
+		err = db.QueryRow(`UPDATE users SET totp_secret = $1, totp_last_step = NULL
+			WHERE id = $2 AND NOT totp_enabled RETURNING email`, secret, claims.UserID).Scan(&email)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Two-factor authentication is already enabled", "status": "error"}`))
+			return
+		}
+		if err != nil {
+			fmt.Printf("Failed to start 2FA enrolment for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not start enrolment", "status": "error"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "Scan the URI with an authenticator app, then confirm a code at /2fa/verify",
+			"status":      "success",
+			"secret":      secret,
+			"otpauth_uri": totpURI(secret, email),
+		})
+	}
+}
+
+// twoFactorVerifyHandler serves POST /2fa/verify {"code": "123456"}: a code from the enrolled
+// secret turns two-factor authentication on and returns a fresh set of recovery codes
+func twoFactorVerifyHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		claims, err := bearerClaims(db, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Authentication required", "status": "error"}`))
+			return
+		}
+
+		var codeReq TwoFactorCodeRequest
+		if err := readJSON(r, &codeReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not verify code", "status": "error"}`))
+			return
+		}
+		defer tx.Rollback()
+
+		var secret sql.NullString
+		var enabled bool
+		err = tx.QueryRow("SELECT totp_secret, totp_enabled FROM users WHERE id = $1 FOR UPDATE", claims.UserID).Scan(&secret, &enabled)
+		if err != nil {
+			fmt.Printf("Failed to load 2FA state for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not verify code", "status": "error"}`))
+			return
+		}
+		if enabled {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Two-factor authentication is already enabled", "status": "error"}`))
+			return
+		}
+		if !secret.Valid {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Start enrolment at /2fa/enroll first", "status": "error"}`))
+			return
+		}
+
+		step, ok := matchTOTP(secret.String, strings.TrimSpace(codeReq.Code), time.Now())
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Invalid code", "status": "error"}`))
+			return
+		}
+
+		_, err = tx.Exec("UPDATE users SET totp_enabled = TRUE, totp_last_step = $1 WHERE id = $2", step, claims.UserID)
+		if err != nil {
+			fmt.Printf("Failed to enable 2FA for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not enable two-factor authentication", "status": "error"}`))
+			return
+		}
+		codes, err := replaceRecoveryCodes(tx, claims.UserID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			fmt.Printf("Failed to enable 2FA for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not enable two-factor authentication", "status": "error"}`))
+			return
+		}
+
+		fmt.Printf("User %s (ID: %d) enabled two-factor authentication\n", claims.Username, claims.UserID)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe; they are shown only once.",
+			"status":         "success",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// twoFactorDisableHandler serves POST /2fa/disable {"code": "..."}: a current TOTP code or a
+// recovery code turns two-factor authentication off and discards the secret and recovery codes
+func twoFactorDisableHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		claims, err := bearerClaims(db, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Authentication required", "status": "error"}`))
+			return
+		}
+
+		var codeReq TwoFactorCodeRequest
+		if err := readJSON(r, &codeReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not disable two-factor authentication", "status": "error"}`))
+			return
+		}
+		defer tx.Rollback()
+
+		var enabled bool
+		if err := tx.QueryRow("SELECT totp_enabled FROM users WHERE id = $1 FOR UPDATE", claims.UserID).Scan(&enabled); err != nil {
+			fmt.Printf("Failed to load 2FA state for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not disable two-factor authentication", "status": "error"}`))
+			return
+		}
+		if !enabled {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Two-factor authentication is not enabled", "status": "error"}`))
+			return
+		}
+
+		ok, err := checkSecondFactor(tx, claims.UserID, codeReq.Code)
+		if err != nil {
+			fmt.Printf("Failed to check 2FA code for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not disable two-factor authentication", "status": "error"}`))
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Invalid code", "status": "error"}`))
+			return
+		}
+
+		_, err = tx.Exec("UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL WHERE id = $1", claims.UserID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", claims.UserID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			fmt.Printf("Failed to disable 2FA for user %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not disable two-factor authentication", "status": "error"}`))
+			return
+		}
+
+		fmt.Printf("User %s (ID: %d) disabled two-factor authentication\n", claims.Username, claims.UserID)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Two-factor authentication disabled", "status": "success"}`))
+	}
+}
+
+// loginTwoFactorHandler serves POST /login/2fa {"challenge_token": "...", "code": "..."}: the
+// second step of a login for users with two-factor authentication. A TOTP code or an unused
+// recovery code exchanges the challenge for the same tokens /login returns without 2FA.
+// Wrong codes count as failed logins for the account and client address.
+func loginTwoFactorHandler(db *sql.DB, limiter *loginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		var loginReq LoginTwoFactorRequest
+		if err := readJSON(r, &loginReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if loginReq.ChallengeToken == "" || loginReq.Code == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "challenge_token and code are required", "status": "error"}`))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not complete login", "status": "error"}`))
+			return
+		}
+		defer tx.Rollback()
+
+		// Lock the challenge so concurrent guesses are counted one at a time
+		var userID, attempts int
+		var expired bool
+		var username, email string
+		var cash float64
+		var emailVerified bool
+		err = tx.QueryRow(`SELECT c.user_id, c.attempts, c.expires_at < NOW(), u.username, u.email, u.cash, u.email_verified
+			FROM login_challenges c JOIN users u ON u.id = c.user_id
+			WHERE c.token_hash = $1
+			FOR UPDATE OF c`, hashToken(loginReq.ChallengeToken)).Scan(&userID, &attempts, &expired, &username, &email, &cash, &emailVerified)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Invalid or expired challenge token", "status": "error"}`))
+			return
+		}
+		if err != nil {
+			fmt.Printf("Failed to load login challenge: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not complete login", "status": "error"}`))
+			return
+		}
+		if expired || attempts >= maxChallengeAttempts {
+			if _, err := tx.Exec("DELETE FROM login_challenges WHERE token_hash = $1", hashToken(loginReq.ChallengeToken)); err == nil {
+				tx.Commit()
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Invalid or expired challenge token", "status": "error"}`))
+			return
+		}
+
+		ip := clientIP(r)
+		wait, err := limiter.begin(email, ip)
+		if err != nil {
+			fmt.Printf("Failed to check login attempts: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not complete login", "status": "error"}`))
+			return
+		}
+		if wait > 0 {
+			writeTooManyAttempts(w, wait)
+			return
+		}
+
+		ok, err := checkSecondFactor(tx, userID, loginReq.Code)
+		if err != nil {
+			fmt.Printf("Failed to check 2FA code for user %d: %v\n", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not complete login", "status": "error"}`))
+			return
+		}
+		if !ok {
+			_, err := tx.Exec("UPDATE login_challenges SET attempts = attempts + 1 WHERE token_hash = $1", hashToken(loginReq.ChallengeToken))
+			if err == nil {
+				err = tx.Commit()
+			}
+			if err != nil {
+				fmt.Printf("Failed to count 2FA attempt for user %d: %v\n", userID, err)
+			}
+			fmt.Printf("Two-factor verification failed for user ID %d\n", userID)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Invalid two-factor code", "status": "error"}`))
+			return
+		}
+
+		if _, err := tx.Exec("DELETE FROM login_challenges WHERE token_hash = $1", hashToken(loginReq.ChallengeToken)); err != nil {
+			fmt.Printf("Failed to consume login challenge for user %d: %v\n", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not complete login", "status": "error"}`))
+			return
+		}
+		tokens, err := issueTokens(tx, userID, username, "")
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			fmt.Printf("JWT generation failed for user %s: %v\n", username, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not generate token", "status": "error"}`))
+			return
+		}
+
+		if err := limiter.succeed(email, ip); err != nil {
+			fmt.Printf("Failed to clear login attempts for user ID %d: %v\n", userID, err)
+		}
+
+		fmt.Printf("User %s (ID: %d) logged in successfully with two-factor authentication\n", username, userID)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(loginResponse(tokens, userID, username, email, cash, emailVerified)))
+	}
+}
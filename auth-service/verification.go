@@ -0,0 +1,400 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Purposes of account tokens; a token only works for the purpose it was issued for
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+)
+
+const (
+	minPasswordLength = 8
+	accountMailEvery  = time.Minute // at most one verification or reset email per user this often
+)
+
+var errInvalidAccountToken = errors.New("invalid, expired or already used token")
+
+// ForgotPasswordRequest names the account to send a reset link to
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password with the token from a reset email
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest confirms an address with the token from a verification email
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// emailVerifyTTL reads EMAIL_VERIFY_TTL (e.g. "24h"), defaulting to 24 hours
+func emailVerifyTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("EMAIL_VERIFY_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// passwordResetTTL reads PASSWORD_RESET_TTL (e.g. "1h"), defaulting to 1 hour
+func passwordResetTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL"))
+	if err != nil || ttl <= 0 {
+		return time.Hour
+	}
+	return ttl
+}
+
+// appBaseURL reads APP_BASE_URL, the frontend address emailed links point at
+func appBaseURL() string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:3000"
+}
+
+// issueAccountToken signs a token for purpose with the JWT keys and records its jti, so that it
+// can be spent once. The user is carried in "sub" rather than "user_id", which keeps these
+// tokens from ever passing as access tokens.
+func issueAccountToken(db *sql.DB, userID int, purpose string, ttl time.Duration) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	tokenString, err := signingKeys.sign(jwt.MapClaims{
+		"sub":     strconv.Itoa(userID),
+		"purpose": purpose,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec("INSERT INTO account_tokens (jti, user_id, purpose, expires_at) VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')",
+		jti, userID, purpose, int(ttl.Seconds()))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// consumeAccountToken checks a token's signature, expiry and purpose and spends it in tx,
+// returning the user it was issued to
+func consumeAccountToken(tx *sql.Tx, tokenString string, purpose string) (int, error) {
+	token, err := jwt.Parse(tokenString, signingKeys.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return 0, errInvalidAccountToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return 0, errInvalidAccountToken
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		return 0, errInvalidAccountToken
+	}
+	jti, _ := claims["jti"].(string)
+
+	result, err := tx.Exec(`UPDATE account_tokens SET used_at = NOW()
+		WHERE jti = $1 AND user_id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > NOW()`, jti, userID, purpose)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n != 1 {
+		return 0, errInvalidAccountToken
+	}
+	return userID, nil
+}
+
+// recentlyMailed reports whether a token for purpose was issued to the user within accountMailEvery
+func recentlyMailed(db *sql.DB, userID int, purpose string) (bool, error) {
+	var recent bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM account_tokens
+		WHERE user_id = $1 AND purpose = $2 AND created_at > NOW() - $3 * INTERVAL '1 second')`,
+		userID, purpose, int(accountMailEvery.Seconds())).Scan(&recent)
+	return recent, err
+}
+
+// sendVerificationEmail mails a link confirming the user's address
+func sendVerificationEmail(db *sql.DB, mailer Mailer, userID int, email string) error {
+	token, err := issueAccountToken(db, userID, purposeVerifyEmail, emailVerifyTTL())
+	if err != nil {
+		return err
+	}
+	link := appBaseURL() + "/verify-email?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Confirm your email address to start trading:\n\n%s\n\nThe link expires in %s. "+
+		"If you did not create an account, ignore this email.", link, emailVerifyTTL())
+	return mailer.Send(email, "Confirm your email address", body)
+}
+
+// sendPasswordResetEmail mails a link for choosing a new password
+func sendPasswordResetEmail(db *sql.DB, mailer Mailer, userID int, email string) error {
+	token, err := issueAccountToken(db, userID, purposeResetPassword, passwordResetTTL())
+	if err != nil {
+		return err
+	}
+	link := appBaseURL() + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Someone asked to reset your password. Choose a new one here:\n\n%s\n\nThe link expires in %s "+
+		"and works once. If it was not you, ignore this email; your password is unchanged.", link, passwordResetTTL())
+	return mailer.Send(email, "Reset your password", body)
+}
+
+// forgotPasswordHandler serves POST /password/forgot {"email": "..."}. The answer is the same
+// whether or not the account exists, and the email is sent in the background so that the
+// response time does not tell either.
+func forgotPasswordHandler(db *sql.DB, mailer Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		var forgotReq ForgotPasswordRequest
+		if err := readJSON(r, &forgotReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if forgotReq.Email == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Email is required", "status": "error"}`))
+			return
+		}
+
+		go func(email string) {
+			var userID int
+			err := db.QueryRow("SELECT id FROM users WHERE email = $1", email).Scan(&userID)
+			if err != nil {
+				if err != sql.ErrNoRows {
+					fmt.Printf("Password reset lookup failed: %v\n", err)
+				}
+				return
+			}
+			if recent, err := recentlyMailed(db, userID, purposeResetPassword); err != nil || recent {
+				return
+			}
+			if err := sendPasswordResetEmail(db, mailer, userID, email); err != nil {
+				fmt.Printf("Failed to send password reset email to user ID %d: %v\n", userID, err)
+			}
+		}(forgotReq.Email)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "If an account exists for that email, a reset link has been sent", "status": "success"}`))
+	}
+}
+
+// resetPasswordHandler serves POST /password/reset {"token": "...", "password": "..."}. A new
+// password ends every session and clears failed logins, and since the link arrived by email
+// it also confirms the address.
+func resetPasswordHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		var resetReq ResetPasswordRequest
+		if err := readJSON(r, &resetReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if resetReq.Token == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "token is required", "status": "error"}`))
+			return
+		}
+		if len(resetReq.Password) < minPasswordLength {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "Password must be at least %d characters long", "status": "error"}`, minPasswordLength)))
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetReq.Password), bcrypt.DefaultCost)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Password hashing failed", "status": "error"}`))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not reset password", "status": "error"}`))
+			return
+		}
+		defer tx.Rollback()
+
+		userID, err := consumeAccountToken(tx, resetReq.Token, purposeResetPassword)
+		if errors.Is(err, errInvalidAccountToken) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if err == nil {
+			_, err = tx.Exec(`UPDATE users SET password_hash = $1, email_verified = TRUE,
+				failed_login_attempts = 0, last_failed_login_at = NULL WHERE id = $2`, hashedPassword, userID)
+		}
+		if err == nil {
+			// Earlier reset links stop working too
+			_, err = tx.Exec("UPDATE account_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+				userID, purposeResetPassword)
+		}
+		if err == nil {
+			err = revokeUserSessions(tx, userID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			fmt.Printf("Password reset failed: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not reset password", "status": "error"}`))
+			return
+		}
+
+		fmt.Printf("Password reset for user ID %d\n", userID)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Password updated. Please sign in with your new password.", "status": "success"}`))
+	}
+}
+
+// verifyEmailHandler serves POST /email/verify {"token": "..."}, confirming the user's address
+func verifyEmailHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		var verifyReq VerifyEmailRequest
+		if err := readJSON(r, &verifyReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if verifyReq.Token == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "token is required", "status": "error"}`))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not verify email", "status": "error"}`))
+			return
+		}
+		defer tx.Rollback()
+
+		userID, err := consumeAccountToken(tx, verifyReq.Token, purposeVerifyEmail)
+		if errors.Is(err, errInvalidAccountToken) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"error": "%s", "status": "error"}`, err)))
+			return
+		}
+		if err == nil {
+			_, err = tx.Exec("UPDATE users SET email_verified = TRUE WHERE id = $1", userID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			fmt.Printf("Email verification failed: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not verify email", "status": "error"}`))
+			return
+		}
+
+		fmt.Printf("Email verified for user ID %d\n", userID)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Email verified. You can now trade.", "status": "success"}`))
+	}
+}
+
+// resendVerificationHandler serves POST /email/verify/resend for the authenticated user
+func resendVerificationHandler(db *sql.DB, mailer Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error": "Method not allowed", "status": "error"}`))
+			return
+		}
+
+		claims, err := bearerClaims(db, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Authentication required", "status": "error"}`))
+			return
+		}
+
+		var email string
+		var verified bool
+		if err := db.QueryRow("SELECT email, email_verified FROM users WHERE id = $1", claims.UserID).Scan(&email, &verified); err != nil {
+			fmt.Printf("Failed to load user %d for verification email: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not send verification email", "status": "error"}`))
+			return
+		}
+		if verified {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Email is already verified", "status": "error"}`))
+			return
+		}
+
+		recent, err := recentlyMailed(db, claims.UserID, purposeVerifyEmail)
+		if err == nil && recent {
+			w.Header().Set("Retry-After", strconv.Itoa(int(accountMailEvery.Seconds())))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "A verification email was just sent, try again shortly", "status": "error"}`))
+			return
+		}
+		if err == nil {
+			err = sendVerificationEmail(db, mailer, claims.UserID, email)
+		}
+		if err != nil {
+			fmt.Printf("Failed to send verification email to user ID %d: %v\n", claims.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Could not send verification email", "status": "error"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Verification email sent", "status": "success"}`))
+	}
+}
@@ -1,18 +1,64 @@
+-- Commission plans. A trade's fee is flat_fee + per_share_fee * shares + percent_fee * value,
+-- clamped to [min_fee, max_fee]; with fee_tiers, the highest tier reached by the user's trade
+-- value so far this month sets per_share_fee and percent_fee instead. Schedules with a
+-- user_id were negotiated for that user; the rest are public plans. Users can only switch
+-- themselves to selectable plans; the others are assigned by operators.
+CREATE TABLE fee_schedules (
+    name VARCHAR(30) PRIMARY KEY,
+    user_id INTEGER,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    selectable BOOLEAN NOT NULL DEFAULT TRUE,
+    flat_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+    per_share_fee DECIMAL(10,4) NOT NULL DEFAULT 0,
+    percent_fee DECIMAL(8,6) NOT NULL DEFAULT 0,
+    min_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+    max_fee DECIMAL(10,2)
+);
+
+CREATE TABLE fee_tiers (
+    schedule VARCHAR(30) NOT NULL,
+    min_volume DECIMAL(15,2) NOT NULL,
+    per_share_fee DECIMAL(10,4) NOT NULL DEFAULT 0,
+    percent_fee DECIMAL(8,6) NOT NULL DEFAULT 0,
+    PRIMARY KEY(schedule, min_volume),
+    FOREIGN KEY(schedule) REFERENCES fee_schedules(name)
+);
+
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(100) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
     cash DECIMAL(15,2) DEFAULT 10000.00,
-    created_at TIMESTAMP DEFAULT NOW()
+    reserved_cash DECIMAL(15,2) NOT NULL DEFAULT 0,
+    account_type VARCHAR(10) NOT NULL DEFAULT 'CASH' CHECK (account_type IN ('CASH', 'MARGIN')),
+    cost_basis_method VARCHAR(10) NOT NULL DEFAULT 'FIFO' CHECK (cost_basis_method IN ('FIFO', 'LIFO', 'SPECIFIC', 'AVERAGE')),
+    -- Margin requirements are set by operators, never by users, and cannot go below 50% / 25%
+    initial_margin DECIMAL(5,4) NOT NULL DEFAULT 0.50 CHECK (initial_margin BETWEEN 0.50 AND 1),
+    maintenance_margin DECIMAL(5,4) NOT NULL DEFAULT 0.25 CHECK (maintenance_margin >= 0.25),
+    margin_interest_rate DECIMAL(7,4) NOT NULL DEFAULT 0.08,
+    interest_accrued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    fee_schedule VARCHAR(30) NOT NULL DEFAULT 'standard',
+    totp_secret VARCHAR(64),
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (maintenance_margin <= initial_margin),
+    FOREIGN KEY(fee_schedule) REFERENCES fee_schedules(name)
 );
 
+ALTER TABLE fee_schedules ADD FOREIGN KEY(user_id) REFERENCES users(id);
+
 CREATE TABLE holdings (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
     symbol VARCHAR(10) NOT NULL,
-    shares INTEGER NOT NULL,
-    avg_price DECIMAL(10,2) NOT NULL,
+    shares DECIMAL(18,6) NOT NULL, -- negative for short positions in margin accounts
+    reserved_shares DECIMAL(18,6) NOT NULL DEFAULT 0,
+    avg_price DECIMAL(15,4) NOT NULL,
     updated_at TIMESTAMP DEFAULT NOW(),
     UNIQUE(user_id, symbol),
     FOREIGN KEY(user_id) REFERENCES users(id)
@@ -22,14 +68,340 @@ CREATE TABLE transactions (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
     symbol VARCHAR(10) NOT NULL,
-    shares INTEGER NOT NULL,
+    shares DECIMAL(18,6) NOT NULL,
+    price DECIMAL(15,4) NOT NULL, -- per-share dividend for DIVIDEND, 0 for SPLIT
+    transaction_type VARCHAR(10) NOT NULL CHECK (transaction_type IN ('BUY', 'SELL', 'DIVIDEND', 'SPLIT', 'RENAME')),
+    total_amount DECIMAL(15,2) NOT NULL, -- negative for dividends paid on short positions
+    fee DECIMAL(10,2) NOT NULL DEFAULT 0, -- commission paid on top of a buy or out of a sale
+    realized_gain DECIMAL(15,2), -- set on trades that close tax lots
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE tax_lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    shares DECIMAL(18,6) NOT NULL, -- negative for short lots
+    remaining_shares DECIMAL(18,6) NOT NULL,
+    cost_per_share DECIMAL(15,4) NOT NULL,
+    acquired_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES transactions(id)
+);
+
+CREATE INDEX idx_tax_lots_open ON tax_lots(user_id, symbol) WHERE remaining_shares <> 0;
+
+CREATE TABLE lot_sales (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL,
+    lot_id INTEGER NOT NULL,
+    shares DECIMAL(18,6) NOT NULL,
+    cost_basis DECIMAL(15,2) NOT NULL,
+    proceeds DECIMAL(15,2) NOT NULL,
+    realized_gain DECIMAL(15,2) NOT NULL,
+    FOREIGN KEY(transaction_id) REFERENCES transactions(id),
+    FOREIGN KEY(lot_id) REFERENCES tax_lots(id)
+);
+
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    side VARCHAR(4) NOT NULL CHECK (side IN ('BUY', 'SELL')),
+    order_type VARCHAR(10) NOT NULL CHECK (order_type IN ('LIMIT', 'STOP', 'STOP_LIMIT')),
+    shares DECIMAL(18,6) NOT NULL CHECK (shares > 0),
+    filled_shares DECIMAL(18,6) NOT NULL DEFAULT 0,
+    limit_price DECIMAL(10,2),
+    stop_price DECIMAL(10,2),
+    time_in_force VARCHAR(3) NOT NULL CHECK (time_in_force IN ('DAY', 'GTC')),
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING', 'PARTIALLY_FILLED', 'FILLED', 'CANCELLED', 'REJECTED', 'EXPIRED')),
+    triggered BOOLEAN NOT NULL DEFAULT FALSE,
+    reserved_cash DECIMAL(15,2) NOT NULL DEFAULT 0,
+    reserved_shares DECIMAL(18,6) NOT NULL DEFAULT 0,
+    filled_price DECIMAL(10,2),
+    transaction_id INTEGER,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES transactions(id)
+);
+
+CREATE INDEX idx_orders_status ON orders(status);
+CREATE INDEX idx_orders_user ON orders(user_id);
+
+CREATE TABLE order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_events_order ON order_events(order_id);
+
+CREATE TABLE idempotency_keys (
+    user_id INTEGER NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    response_status INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY(user_id, idempotency_key),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+-- Refresh tokens, stored as SHA-256 hashes. A login starts a family; each refresh spends
+-- the presented token and adds its replacement to the family. Presenting a spent or
+-- revoked token again revokes the whole family.
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    access_jti VARCHAR(64) NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+-- Access tokens revoked before they expire, by jti claim
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Pending second login steps: /login stores one, by SHA-256 hash, for users with two-factor
+-- authentication and /login/2fa consumes it with a TOTP or recovery code
+CREATE TABLE login_challenges (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+-- Emailed email-verification and password-reset tokens by jti. The tokens are signed JWTs;
+-- this table makes each one single-use.
+CREATE TABLE account_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_account_tokens_user ON account_tokens(user_id, purpose);
+
+-- Single-use two-factor recovery codes, stored as SHA-256 hashes
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, code_hash),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+-- Double-entry journal behind every movement of cash or securities. Each entry's
+-- postings sum to zero (debits positive, credits negative); a user's balances are
+-- the sums of their postings per account, and for SECURITIES per symbol.
+CREATE TABLE journal_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entry_type VARCHAR(12) NOT NULL,
+    transaction_id INTEGER,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES transactions(id)
+);
+
+CREATE INDEX idx_journal_entries_user ON journal_entries(user_id);
+
+CREATE TABLE postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    account VARCHAR(20) NOT NULL,
+    symbol VARCHAR(10),
+    quantity DECIMAL(18,6) NOT NULL DEFAULT 0,
+    amount DECIMAL(15,2) NOT NULL,
+    FOREIGN KEY(entry_id) REFERENCES journal_entries(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_postings_entry ON postings(entry_id);
+CREATE INDEX idx_postings_user_account ON postings(user_id, account, symbol);
+
+-- Every change to users.cash, as a double-entry movement between the user's CASH
+-- account and a counter account; users.cash must equal the ledger's CASH balance
+CREATE TABLE cash_ledger (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entry_type VARCHAR(12) NOT NULL CHECK (entry_type IN ('OPENING', 'DEPOSIT', 'WITHDRAWAL', 'TRADE', 'FEE', 'DIVIDEND', 'INTEREST')),
+    debit_account VARCHAR(20) NOT NULL,
+    credit_account VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount >= 0),
+    balance_after DECIMAL(15,2) NOT NULL,
+    transaction_id INTEGER,
+    journal_entry_id INTEGER,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (debit_account <> credit_account AND 'CASH' IN (debit_account, credit_account)),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(transaction_id) REFERENCES transactions(id),
+    FOREIGN KEY(journal_entry_id) REFERENCES journal_entries(id)
+);
+
+CREATE INDEX idx_cash_ledger_user ON cash_ledger(user_id);
+
+CREATE TABLE margin_interest (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    borrowed DECIMAL(15,2) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE margin_calls (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    equity DECIMAL(15,2) NOT NULL,
+    requirement DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_margin_calls_open ON margin_calls(user_id) WHERE resolved_at IS NULL;
+
+CREATE TABLE portfolio_snapshots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    snapshot_date DATE NOT NULL,
+    total_value DECIMAL(15,2) NOT NULL,
+    cash DECIMAL(15,2) NOT NULL,
+    holdings_value DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, snapshot_date),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE snapshot_holdings (
+    id SERIAL PRIMARY KEY,
+    snapshot_id INTEGER NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    shares DECIMAL(18,6) NOT NULL,
     price DECIMAL(10,2) NOT NULL,
-    transaction_type VARCHAR(4) NOT NULL CHECK (transaction_type IN ('BUY', 'SELL')),
-    total_amount DECIMAL(15,2) NOT NULL,
+    value DECIMAL(15,2) NOT NULL,
+    FOREIGN KEY(snapshot_id) REFERENCES portfolio_snapshots(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_snapshot_holdings_snapshot ON snapshot_holdings(snapshot_id);
+
+-- Dividends, splits and ticker renames, applied to every holder of symbol once ex_date
+-- arrives. A split gives ratio_to new shares for every ratio_from old ones.
+CREATE TABLE corporate_actions (
+    id SERIAL PRIMARY KEY,
+    symbol VARCHAR(10) NOT NULL,
+    action_type VARCHAR(10) NOT NULL CHECK (action_type IN ('DIVIDEND', 'SPLIT', 'RENAME')),
+    ex_date DATE NOT NULL,
+    cash_amount DECIMAL(15,4), -- dividend per share
+    ratio_from DECIMAL(10,4),
+    ratio_to DECIMAL(10,4),
+    new_symbol VARCHAR(10),
+    status VARCHAR(10) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPLIED', 'FAILED')),
+    reason VARCHAR(255),
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (action_type <> 'DIVIDEND' OR cash_amount > 0),
+    CHECK (action_type <> 'SPLIT' OR (ratio_from > 0 AND ratio_to > 0 AND ratio_from <> ratio_to)),
+    CHECK (action_type <> 'RENAME' OR (new_symbol IS NOT NULL AND new_symbol <> symbol))
+);
+
+CREATE INDEX idx_corporate_actions_pending ON corporate_actions(ex_date) WHERE status = 'PENDING';
+
+-- Price and portfolio alerts; each notifies once through its channel when triggered
+CREATE TABLE alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    alert_type VARCHAR(20) NOT NULL CHECK (alert_type IN ('PRICE_ABOVE', 'PRICE_BELOW', 'PORTFOLIO_UP', 'PORTFOLIO_DOWN')),
+    symbol VARCHAR(10), -- price alerts only
+    threshold DECIMAL(15,4) NOT NULL CHECK (threshold > 0), -- a price, or a percent change for portfolio alerts
+    channel VARCHAR(10) NOT NULL DEFAULT 'INAPP' CHECK (channel IN ('INAPP', 'WEBHOOK', 'EMAIL')),
+    target VARCHAR(255), -- webhook URL or email address
+    status VARCHAR(10) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'TRIGGERED')),
+    triggered_at TIMESTAMP,
+    triggered_value DECIMAL(15,4),
+    delivery_error TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (alert_type NOT IN ('PRICE_ABOVE', 'PRICE_BELOW') OR symbol IS NOT NULL),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_alerts_active ON alerts(status) WHERE status = 'ACTIVE';
+CREATE INDEX idx_alerts_user ON alerts(user_id);
+
+-- In-app inbox
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    alert_id INTEGER,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(alert_id) REFERENCES alerts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at);
+
+CREATE TABLE watchlists (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, name),
     FOREIGN KEY(user_id) REFERENCES users(id)
 );
 
+CREATE TABLE watchlist_symbols (
+    watchlist_id INTEGER NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    added_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY(watchlist_id, symbol),
+    FOREIGN KEY(watchlist_id) REFERENCES watchlists(id) ON DELETE CASCADE
+);
+
+-- Daily closing prices kept for performance comparisons (the benchmark, SPY)
+CREATE TABLE price_history (
+    symbol VARCHAR(10) NOT NULL,
+    price_date DATE NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY(symbol, price_date)
+);
+
 CREATE TABLE stock_prices (
     symbol VARCHAR(10) PRIMARY KEY,
     name VARCHAR(255),
@@ -39,9 +411,21 @@ CREATE TABLE stock_prices (
 );
 
 
+-- Commission plans; accounts start on 'standard'
+INSERT INTO fee_schedules (name, description, selectable, flat_fee, per_share_fee, percent_fee, min_fee, max_fee) VALUES
+    ('standard', '$4.95 per trade', TRUE, 4.95, 0, 0, 0, NULL),
+    ('per_share', '$0.005 per share, $1.00 minimum, $50.00 maximum', TRUE, 0, 0.005, 0, 1.00, 50.00),
+    ('percentage', '0.10% of trade value', TRUE, 0, 0, 0.001, 0, NULL),
+    ('active_trader', '0.20% of trade value, falling to 0.10% above $100,000 and 0.05% above $1,000,000 traded this month', TRUE, 0, 0, 0.002, 0, NULL),
+    ('commission_free', 'No commission', FALSE, 0, 0, 0, 0, NULL);
+
+INSERT INTO fee_tiers (schedule, min_volume, per_share_fee, percent_fee) VALUES
+    ('active_trader', 100000.00, 0, 0.001),
+    ('active_trader', 1000000.00, 0, 0.0005);
+
 -- Password is 'password123' 
-INSERT INTO users (username, email, password_hash, cash) VALUES
-    ('testuser', 'test@example.com', '$2a$10$.CWcv9bgp6VWZcAiHu24peF2rJTStF8UFWeRyUhK2FWx5sDSMs5C6', 500.00);
+INSERT INTO users (username, email, password_hash, email_verified, cash) VALUES
+    ('testuser', 'test@example.com', '$2a$10$.CWcv9bgp6VWZcAiHu24peF2rJTStF8UFWeRyUhK2FWx5sDSMs5C6', TRUE, 500.00);
 
 
 INSERT INTO stock_prices (symbol, name, price, change_percent) VALUES
@@ -59,6 +443,30 @@ INSERT INTO transactions (user_id, symbol, shares, price, transaction_type, tota
     (1, 'SPY', 5, 440.00, 'BUY', 2200.00, NOW() - INTERVAL '2 days');
 -- Total spent: $9,500, remaining cash: $500
 
+-- Journal for testuser: $10,000 opening balance, then the three buys
+INSERT INTO journal_entries (user_id, entry_type, transaction_id, description, created_at) VALUES
+    (1, 'OPENING', NULL, 'opening balance', NOW() - INTERVAL '8 days'),
+    (1, 'TRADE', 1, 'buy 10 AAPL at $170.00', NOW() - INTERVAL '7 days'),
+    (1, 'TRADE', 2, 'buy 2 GOOGL at $2800.00', NOW() - INTERVAL '5 days'),
+    (1, 'TRADE', 3, 'buy 5 SPY at $440.00', NOW() - INTERVAL '2 days');
+
+INSERT INTO postings (entry_id, user_id, account, symbol, quantity, amount) VALUES
+    (1, 1, 'CASH', NULL, 0, 10000.00),
+    (1, 1, 'OPENING_BALANCE', NULL, 0, -10000.00),
+    (2, 1, 'CASH', NULL, 0, -1700.00),
+    (2, 1, 'SECURITIES', 'AAPL', 10, 1700.00),
+    (3, 1, 'CASH', NULL, 0, -5600.00),
+    (3, 1, 'SECURITIES', 'GOOGL', 2, 5600.00),
+    (4, 1, 'CASH', NULL, 0, -2200.00),
+    (4, 1, 'SECURITIES', 'SPY', 5, 2200.00);
+
+-- Cash ledger explaining testuser's balance: $10,000 opening balance less the three buys
+INSERT INTO cash_ledger (user_id, entry_type, debit_account, credit_account, amount, balance_after, transaction_id, journal_entry_id, description, created_at) VALUES
+    (1, 'OPENING', 'CASH', 'OPENING_BALANCE', 10000.00, 10000.00, NULL, 1, 'opening balance', NOW() - INTERVAL '8 days'),
+    (1, 'TRADE', 'SECURITIES', 'CASH', 1700.00, 8300.00, 1, 2, 'buy 10 AAPL at $170.00', NOW() - INTERVAL '7 days'),
+    (1, 'TRADE', 'SECURITIES', 'CASH', 5600.00, 2700.00, 2, 3, 'buy 2 GOOGL at $2800.00', NOW() - INTERVAL '5 days'),
+    (1, 'TRADE', 'SECURITIES', 'CASH', 2200.00, 500.00, 3, 4, 'buy 5 SPY at $440.00', NOW() - INTERVAL '2 days');
+
 -- 4. Add current holdings (must match transaction totals)
 INSERT INTO holdings (user_id, symbol, shares, avg_price) VALUES
     (1, 'AAPL', 10, 170.00),
@@ -13,6 +13,8 @@ import Login from './views/Login.vue'
 import Dashboard from './views/Dashboard.vue'
 import Trade from './views/Trade.vue'
 import TransactionHistory from './views/TransactionHistory.vue'
+import ResetPassword from './views/ResetPassword.vue'
+import VerifyEmail from './views/VerifyEmail.vue'
 
 // Import auth service for route guards
 import authService from './services/authService.js'
@@ -39,6 +41,17 @@ const routes: RouteRecordRaw[] = [
             }
         }
     },
+    {
+        // Emailed links land here with ?token=; without one it asks for the account's email
+        path: '/reset-password',
+        name: 'ResetPassword',
+        component: ResetPassword
+    },
+    {
+        path: '/verify-email',
+        name: 'VerifyEmail',
+        component: VerifyEmail
+    },
     {
         path: '/dashboard',
         name: 'Dashboard',
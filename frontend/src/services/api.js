@@ -42,6 +42,38 @@ const setAuthToken = (token) => {
     }
 }
 
+const setRefreshToken = (token) => {
+    if (token) {
+        localStorage.setItem('finance_refresh_token', token)
+    } else {
+        localStorage.removeItem('finance_refresh_token')
+    }
+}
+
+// Exchange the stored refresh token for a new token pair. Concurrent 401s share one refresh,
+// since each refresh token can only be used once.
+let refreshing = null
+
+const refreshAccessToken = () => {
+    const refreshToken = localStorage.getItem('finance_refresh_token')
+    if (!refreshToken) {
+        return Promise.reject(new Error('No refresh token'))
+    }
+
+    if (!refreshing) {
+        refreshing = axios.post('/api/auth/token/refresh', { refresh_token: refreshToken }, { timeout: 10000 })
+            .then((response) => {
+                setAuthToken(response.data.token)
+                setRefreshToken(response.data.refresh_token)
+                return response.data.token
+            })
+            .finally(() => {
+                refreshing = null
+            })
+    }
+    return refreshing
+}
+
 // Add auth interceptor to all APIs
 const addAuthInterceptor = (apiInstance) => {
     apiInstance.interceptors.request.use(
@@ -65,10 +97,25 @@ const addAuthInterceptor = (apiInstance) => {
     // Add response interceptor for better error handling
     apiInstance.interceptors.response.use(
         (response) => response,
-        (error) => {
+        async (error) => {
+            const request = error.config
+            if (error.response?.status === 401 && request && !request._retried &&
+                localStorage.getItem('finance_refresh_token')) {
+                // The access token expired: refresh it once and replay the request
+                request._retried = true
+                try {
+                    const token = await refreshAccessToken()
+                    request.headers.Authorization = `Bearer ${token}`
+                    return apiInstance(request)
+                } catch (refreshError) {
+                    console.error('Token refresh failed:', refreshError)
+                }
+            }
+
             if (error.response?.status === 401) {
                 // Token expired or invalid
                 console.error('Authentication failed - redirecting to login')
+                localStorage.removeItem('finance_refresh_token')
                 localStorage.removeItem('finance_token')
                 localStorage.removeItem('token')
                 localStorage.removeItem('jwt_token')
@@ -99,4 +146,4 @@ const loadStoredToken = () => {
 }
 
 export default portfolioApi
-export { authApi, portfolioApi, marketApi, setAuthToken, loadStoredToken }
\ No newline at end of file
+export { authApi, portfolioApi, marketApi, setAuthToken, setRefreshToken, refreshAccessToken, loadStoredToken }
\ No newline at end of file
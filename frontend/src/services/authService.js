@@ -1,4 +1,4 @@
-import { authApi, setAuthToken } from './api.js'
+import { authApi, setAuthToken, setRefreshToken } from './api.js'
 
 class AuthService {
     async testConnection() {
@@ -34,6 +34,37 @@ class AuthService {
         }
     }
 
+    // Keep the tokens and user info of a completed login
+    storeSession(data) {
+        if (!data.token) return
+
+        setAuthToken(data.token)
+        localStorage.setItem('finance_token', data.token)
+        setRefreshToken(data.refresh_token)
+
+        // Extract user_id from JWT token and store it
+        try {
+            const payload = JSON.parse(atob(data.token.split('.')[1]))
+            if (payload.user_id) {
+                localStorage.setItem('user_id', String(payload.user_id))
+                console.log('Stored user_id:', payload.user_id)
+            }
+        } catch (error) {
+            console.error('Failed to extract user_id from token:', error)
+        }
+
+        // Store user info if provided
+        if (data.user) {
+            localStorage.setItem('finance_user', JSON.stringify(data.user))
+
+            // Also store user_id from user object if available
+            if (data.user.id && !localStorage.getItem('user_id')) {
+                localStorage.setItem('user_id', String(data.user.id))
+            }
+        }
+    }
+
+    // Returns the login response; with two_factor_required set, finish with verifyTwoFactorLogin
     async login(email, password) {
         try {
             const response = await authApi.post('/login', {
@@ -41,33 +72,7 @@ class AuthService {
                 password
             })
 
-            // Store the token if provided
-            if (response.data.token) {
-                setAuthToken(response.data.token)
-                localStorage.setItem('finance_token', response.data.token)
-
-                // Extract user_id from JWT token and store it
-                try {
-                    const payload = JSON.parse(atob(response.data.token.split('.')[1]))
-                    if (payload.user_id) {
-                        localStorage.setItem('user_id', String(payload.user_id))
-                        console.log('Stored user_id:', payload.user_id)
-                    }
-                } catch (error) {
-                    console.error('Failed to extract user_id from token:', error)
-                }
-
-                // Store user info if provided
-                if (response.data.user) {
-                    localStorage.setItem('finance_user', JSON.stringify(response.data.user))
-
-                    // Also store user_id from user object if available
-                    if (response.data.user.id && !localStorage.getItem('user_id')) {
-                        localStorage.setItem('user_id', String(response.data.user.id))
-                    }
-                }
-            }
-
+            this.storeSession(response.data)
             return response.data
         } catch (error) {
             console.error('Login failed:', error)
@@ -75,6 +80,9 @@ class AuthService {
             // Handle different error types based on your Go backend
             if (error.response?.status === 401) {
                 throw new Error('Invalid email or password')
+            } else if (error.response?.status === 429) {
+                const seconds = parseInt(error.response.headers['retry-after'], 10) || 60
+                throw new Error(`Too many failed attempts - try again in ${Math.ceil(seconds / 60)} minute(s)`)
             } else if (error.response?.status === 400) {
                 throw new Error(error.response.data.error || 'Please check your email and password')
             } else {
@@ -83,8 +91,92 @@ class AuthService {
         }
     }
 
+    async verifyTwoFactorLogin(challengeToken, code) {
+        try {
+            const response = await authApi.post('/login/2fa', {
+                challenge_token: challengeToken,
+                code
+            })
+
+            this.storeSession(response.data)
+            return response.data
+        } catch (error) {
+            console.error('Two-factor login failed:', error)
+            throw new Error(error.response?.data?.error || 'Verification failed - please try again')
+        }
+    }
+
+    // Two-factor enrolment: enroll returns the secret and otpauth URI, activate confirms a code
+    // and returns the recovery codes, disable accepts a code or a recovery code
+    async enrollTwoFactor() {
+        const response = await authApi.post('/2fa/enroll')
+        return response.data
+    }
+
+    async activateTwoFactor(code) {
+        const response = await authApi.post('/2fa/verify', { code })
+        return response.data
+    }
+
+    async disableTwoFactor(code) {
+        const response = await authApi.post('/2fa/disable', { code })
+        return response.data
+    }
+
+    // Password reset and email verification; the tokens come from emailed links
+    async forgotPassword(email) {
+        try {
+            const response = await authApi.post('/password/forgot', { email })
+            return response.data
+        } catch (error) {
+            throw new Error(error.response?.data?.error || 'Could not send reset link - please try again')
+        }
+    }
+
+    async resetPassword(token, password) {
+        try {
+            const response = await authApi.post('/password/reset', { token, password })
+            return response.data
+        } catch (error) {
+            throw new Error(error.response?.data?.error || 'Could not reset password - please try again')
+        }
+    }
+
+    async verifyEmail(token) {
+        try {
+            const response = await authApi.post('/email/verify', { token })
+
+            // Let the stored user trade without signing in again
+            const user = this.getCurrentUser()
+            if (user) {
+                localStorage.setItem('finance_user', JSON.stringify({ ...user, email_verified: true }))
+            }
+            return response.data
+        } catch (error) {
+            throw new Error(error.response?.data?.error || 'Could not verify email - please try again')
+        }
+    }
+
+    async resendVerification() {
+        try {
+            const response = await authApi.post('/email/verify/resend')
+            return response.data
+        } catch (error) {
+            throw new Error(error.response?.data?.error || 'Could not send verification email - please try again')
+        }
+    }
+
     logout() {
+        // Revoke the session on the server; local data is cleared either way
+        const refreshToken = localStorage.getItem('finance_refresh_token')
+        if (refreshToken) {
+            authApi.post('/logout', { refresh_token: refreshToken }).catch((error) => {
+                console.error('Logout request failed:', error)
+            })
+        }
+
         // Clear all stored auth data
+        setRefreshToken(null)
         localStorage.removeItem('finance_token')
         localStorage.removeItem('finance_user')
         localStorage.removeItem('user_id') // Also clear user_id
@@ -100,6 +192,10 @@ class AuthService {
             const currentTime = Date.now() / 1000
 
             if (payload.exp < currentTime) {
+                // An expired access token is renewed on the next request while a refresh token remains
+                if (localStorage.getItem('finance_refresh_token')) {
+                    return true
+                }
                 this.logout() // Clear expired token
                 return false
             }
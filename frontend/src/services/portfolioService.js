@@ -1,5 +1,27 @@
 import { portfolioApi } from './api.js'
 
+// Money fields are sent by the backend as decimal strings ("1234.50")
+const toNumber = (value) => (value === undefined || value === null ? 0 : Number(value))
+
+const normalizeHolding = (holding) => ({
+    ...holding,
+    shares: toNumber(holding.shares),
+    reserved_shares: toNumber(holding.reserved_shares),
+    avg_price: toNumber(holding.avg_price),
+    current_price: toNumber(holding.current_price),
+    total_value: toNumber(holding.total_value),
+    gain_loss: toNumber(holding.gain_loss)
+})
+
+const normalizeTransaction = (transaction) => ({
+    ...transaction,
+    shares: toNumber(transaction.shares),
+    price: toNumber(transaction.price),
+    total_amount: toNumber(transaction.total_amount),
+    fee: toNumber(transaction.fee),
+    realized_gain: transaction.realized_gain == null ? null : toNumber(transaction.realized_gain)
+})
+
 class PortfolioService {
     getUserId() {
         // Always parse back to number, fallback to 1
@@ -22,10 +44,12 @@ class PortfolioService {
 
             // normalize shape
             return {
-                holdings: Array.isArray(payload.holdings) ? payload.holdings : [],
-                total_value: payload.total_value ?? 0,
-                cash: payload.cash ?? 0,
-                total_gain_loss: payload.total_gain_loss ?? 0,
+                holdings: Array.isArray(payload.holdings) ? payload.holdings.map(normalizeHolding) : [],
+                total_value: toNumber(payload.total_value),
+                cash: toNumber(payload.cash),
+                total_gain_loss: toNumber(payload.total_gain_loss),
+                realized_gain_loss: toNumber(payload.realized_gain_loss),
+                unrealized_gain_loss: toNumber(payload.unrealized_gain_loss),
                 total_gain_loss_percent: payload.total_gain_loss_percent ?? 0
             }
         } catch (error) {
@@ -59,9 +83,28 @@ class PortfolioService {
 
             // Token is automatically included via interceptor
             // user_id is extracted from JWT token on backend
+            // Shares may be fractional; send them as a decimal string to keep every digit
+            const response = await portfolioApi.post('/buy', {
+                symbol: symbol.toUpperCase(),
+                shares: String(shares)
+            })
+
+            console.log('Buy response:', response.data)
+            return response.data
+        } catch (error) {
+            console.error('Failed to buy stock:', error)
+            throw error
+        }
+    }
+
+    // Buy a dollar amount's worth of a stock, e.g. buyAmount('NVDA', 100)
+    async buyAmount(symbol, amount) {
+        try {
+            console.log('Buying stock by amount:', { symbol, amount })
+
             const response = await portfolioApi.post('/buy', {
                 symbol: symbol.toUpperCase(),
-                shares: parseInt(shares, 10)
+                amount: Number(amount).toFixed(2)
             })
 
             console.log('Buy response:', response.data)
@@ -80,7 +123,7 @@ class PortfolioService {
             // user_id is extracted from JWT token on backend
             const response = await portfolioApi.post('/sell', {
                 symbol: symbol.toUpperCase(),
-                shares: parseInt(shares, 10)
+                shares: String(shares)
             })
 
             console.log('Sell response:', response.data)
@@ -97,7 +140,8 @@ class PortfolioService {
             // Token is automatically included via interceptor
             const response = await portfolioApi.get(`/transactions/${userId}`)
             // unwrap here too if backend returns {status,message,data}
-            return response.data?.data ?? response.data
+            const transactions = response.data?.data ?? response.data
+            return Array.isArray(transactions) ? transactions.map(normalizeTransaction) : transactions
         } catch (error) {
             console.error('Failed to get transactions history:', error)
             throw error
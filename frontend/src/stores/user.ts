@@ -119,6 +119,11 @@ export const useUserStore = defineStore('user', {
 
                 const response = await authService.login(email, password)
 
+                // Not signed in yet: the caller completes the login with authService.verifyTwoFactorLogin
+                if (response.two_factor_required) {
+                    return response
+                }
+
                 this.user = response.user
                 this.isAuthenticated = true
 
@@ -151,6 +156,7 @@ export const useUserStore = defineStore('user', {
             }
             portfolioService.clearUser()
             localStorage.removeItem('finance_token')
+            localStorage.removeItem('finance_refresh_token')
         },
 
         async loadPortfolio(): Promise<void> {
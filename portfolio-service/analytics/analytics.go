@@ -0,0 +1,198 @@
+// Package analytics computes portfolio performance statistics from valuations and cash flows
+package analytics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultBenchmark is the symbol whose daily price is recorded to compare returns against
+const DefaultBenchmark = "SPY"
+
+// PeriodsPerYear annualizes statistics computed from daily returns. Snapshots are taken every
+// calendar day, weekends and holidays included, so a year has 365 of them.
+const PeriodsPerYear = 365
+
+// ErrNotEnoughData is returned when a window has fewer than two valuations
+var ErrNotEnoughData = errors.New("at least two valuations are needed")
+
+// Point is the portfolio's value at a moment in time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// CashFlow is money moved into (positive) or out of (negative) the portfolio
+type CashFlow struct {
+	Time   time.Time
+	Amount float64
+}
+
+// PeriodReturns returns the return between each pair of consecutive points. Flows between two
+// points are assumed to arrive at the start of the period, so a period's return is
+// value / (previous value + flows) - 1. Periods with nothing invested return zero.
+func PeriodReturns(points []Point, flows []CashFlow) []float64 {
+	flows = sortedFlows(flows)
+
+	returns := make([]float64, 0, len(points))
+	next := 0
+	for i := 1; i < len(points); i++ {
+		// Skip flows up to the previous valuation; they are already in its value
+		for next < len(flows) && !flows[next].Time.After(points[i-1].Time) {
+			next++
+		}
+
+		invested := points[i-1].Value
+		for next < len(flows) && !flows[next].Time.After(points[i].Time) {
+			invested += flows[next].Amount
+			next++
+		}
+
+		r := 0.0
+		if invested > 0 {
+			r = points[i].Value/invested - 1
+		}
+		returns = append(returns, r)
+	}
+	return returns
+}
+
+// TimeWeightedReturn chains period returns, removing the effect of cash flow timing
+func TimeWeightedReturn(returns []float64) float64 {
+	growth := 1.0
+	for _, r := range returns {
+		growth *= 1 + r
+	}
+	return growth - 1
+}
+
+// MaxDrawdown returns the largest peak-to-trough fall of the growth index built from
+// returns, as a positive fraction
+func MaxDrawdown(returns []float64) float64 {
+	index, peak, drawdown := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		index *= 1 + r
+		peak = math.Max(peak, index)
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-index)/peak)
+		}
+	}
+	return drawdown
+}
+
+// Volatility returns the annualized sample standard deviation of daily returns
+func Volatility(returns []float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := average(returns)
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return math.Sqrt(variance) * math.Sqrt(PeriodsPerYear)
+}
+
+// SharpeRatio returns the annualized excess return over riskFreeRate per unit of volatility.
+// ok is false when volatility is zero.
+func SharpeRatio(returns []float64, riskFreeRate float64) (ratio float64, ok bool) {
+	volatility := Volatility(returns)
+	if volatility == 0 {
+		return 0, false
+	}
+	return (average(returns)*PeriodsPerYear - riskFreeRate) / volatility, true
+}
+
+// XIRR returns the annualized rate at which flows (investor's view: negative when paying in)
+// have a net present value of zero
+func XIRR(flows []CashFlow) (float64, error) {
+	flows = sortedFlows(flows)
+	if len(flows) < 2 {
+		return 0, ErrNotEnoughData
+	}
+
+	start := flows[0].Time
+	years := make([]float64, len(flows))
+	hasIn, hasOut := false, false
+	for i, flow := range flows {
+		years[i] = flow.Time.Sub(start).Hours() / 24 / 365
+		hasIn = hasIn || flow.Amount < 0
+		hasOut = hasOut || flow.Amount > 0
+	}
+	if !hasIn || !hasOut {
+		return 0, errors.New("cash flows must include both payments and receipts")
+	}
+
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for i, flow := range flows {
+			total += flow.Amount / math.Pow(1+rate, years[i])
+		}
+		return total
+	}
+
+	// Bracket the root, then bisect; NPV falls as the rate rises for an investment
+	low, high := -0.9999, 1.0
+	for npv(high) > 0 && high < 1e6 {
+		high *= 2
+	}
+	if npv(low)*npv(high) > 0 {
+		return 0, errors.New("no rate solves the cash flows")
+	}
+	for i := 0; i < 200 && high-low > 1e-10; i++ {
+		mid := (low + high) / 2
+		if (npv(mid) > 0) == (npv(low) > 0) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+// MoneyWeightedReturn returns the XIRR of holding the portfolio over points: its first
+// value paid in, flows paid in or taken out, and its last value received
+func MoneyWeightedReturn(points []Point, flows []CashFlow) (float64, error) {
+	if len(points) < 2 {
+		return 0, ErrNotEnoughData
+	}
+	first, last := points[0], points[len(points)-1]
+
+	investor := []CashFlow{{Time: first.Time, Amount: -first.Value}}
+	for _, flow := range flows {
+		if flow.Time.After(first.Time) && !flow.Time.After(last.Time) {
+			investor = append(investor, CashFlow{Time: flow.Time, Amount: -flow.Amount})
+		}
+	}
+	investor = append(investor, CashFlow{Time: last.Time, Amount: last.Value})
+	return XIRR(investor)
+}
+
+// SimpleReturn returns the change between two prices as a fraction
+func SimpleReturn(start float64, end float64) (float64, bool) {
+	if start <= 0 {
+		return 0, false
+	}
+	return end/start - 1, true
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+func sortedFlows(flows []CashFlow) []CashFlow {
+	sorted := append([]CashFlow(nil), flows...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return sorted
+}
@@ -0,0 +1,124 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+const tolerance = 1e-6
+
+func day(n int) time.Time {
+	return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+// samplePoints grows 100 by 10%, takes a 50 deposit, falls 10% to 144 and then rises 20%
+func samplePoints() ([]Point, []CashFlow) {
+	points := []Point{
+		{Time: day(0), Value: 100},
+		{Time: day(1), Value: 110},
+		{Time: day(2), Value: 144},
+		{Time: day(3), Value: 172.8},
+	}
+	flows := []CashFlow{{Time: day(1).Add(12 * time.Hour), Amount: 50}}
+	return points, flows
+}
+
+func TestPeriodReturns(t *testing.T) {
+	points, flows := samplePoints()
+
+	// 110/100 - 1, 144/(110+50) - 1, 172.8/144 - 1
+	want := []float64{0.10, -0.10, 0.20}
+	got := PeriodReturns(points, flows)
+	if len(got) != len(want) {
+		t.Fatalf("got %d returns, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("return %d = %f, want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTimeWeightedReturn(t *testing.T) {
+	points, flows := samplePoints()
+
+	// 1.10 * 0.90 * 1.20 - 1; the deposit does not count as growth
+	want := 0.188
+	if got := TimeWeightedReturn(PeriodReturns(points, flows)); math.Abs(got-want) > tolerance {
+		t.Errorf("TimeWeightedReturn = %f, want %f", got, want)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []float64
+		want    float64
+	}{
+		// The index goes 1.10, 0.99, 1.188: the fall from 1.10 to 0.99 is 10%
+		{"recovered dip", []float64{0.10, -0.10, 0.20}, 0.10},
+		// 1.00 -> 0.80 -> 0.60: 40% below the starting peak
+		{"two falls", []float64{-0.20, -0.25}, 0.40},
+		{"only gains", []float64{0.05, 0.10}, 0},
+		{"no returns", nil, 0},
+	}
+	for _, tt := range tests {
+		if got := MaxDrawdown(tt.returns); math.Abs(got-tt.want) > tolerance {
+			t.Errorf("%s: MaxDrawdown = %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	// Mean 0.2/3; squared deviations (0.1/3)^2 + (0.5/3)^2 + (0.4/3)^2 = 0.42/9, so the
+	// sample variance is 0.42/9/2 = 0.07/3, annualized over 365 daily snapshots
+	want := math.Sqrt(0.07 / 3 * 365)
+	if got := Volatility([]float64{0.10, -0.10, 0.20}); math.Abs(got-want) > tolerance {
+		t.Errorf("Volatility = %f, want %f", got, want)
+	}
+
+	if got := Volatility([]float64{0.01}); got != 0 {
+		t.Errorf("Volatility of one return = %f, want 0", got)
+	}
+	if got := Volatility([]float64{0.01, 0.01, 0.01}); math.Abs(got) > tolerance {
+		t.Errorf("Volatility of constant returns = %f, want 0", got)
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []CashFlow
+		want  float64
+	}{
+		// 1000 becomes 1100 after 365 days
+		{"one year", []CashFlow{{day(0), -1000}, {day(365), 1100}}, 0.10},
+		// 1000 becomes 1210 after two years: 1.1^2
+		{"two years", []CashFlow{{day(0), -1000}, {day(730), 1210}}, 0.10},
+		// -1000 - 1000/1.1 + 2310/1.21 = -1000 - 909.09 + 1909.09 = 0
+		{"two payments", []CashFlow{{day(0), -1000}, {day(365), -1000}, {day(730), 2310}}, 0.10},
+		// 1000 becomes 800 after a year
+		{"loss", []CashFlow{{day(365), 800}, {day(0), -1000}}, -0.20},
+	}
+	for _, tt := range tests {
+		got, err := XIRR(tt.flows)
+		if err != nil {
+			t.Errorf("%s: XIRR failed: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > tolerance {
+			t.Errorf("%s: XIRR = %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestXIRRErrors(t *testing.T) {
+	if _, err := XIRR([]CashFlow{{day(0), -1000}}); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("XIRR of one flow: got %v, want ErrNotEnoughData", err)
+	}
+	if _, err := XIRR([]CashFlow{{day(0), -1000}, {day(365), -100}}); err == nil {
+		t.Error("XIRR without receipts succeeded, want an error")
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minJWKSRefetch limits how often unknown kids or an unreachable auth-service trigger a fetch
+const minJWKSRefetch = 30 * time.Second
+
+// KeySource finds the public key a token was signed with by its kid
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, string, error)
+}
+
+type publicKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// JWKS fetches the public keys auth-service publishes at /.well-known/jwks.json and keeps
+// them for refresh. An unknown kid refetches the set early, so rotated keys are picked up.
+type JWKS struct {
+	url         string
+	refresh     time.Duration
+	client      *http.Client
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetching    chan struct{} // closed when the fetch in progress finishes; nil when none is
+}
+
+// NewJWKS creates a key source for the JWK set at url
+func NewJWKS(url string, refresh time.Duration) *JWKS {
+	return &JWKS{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the public key and JWS algorithm for kid. The set is fetched without holding
+// the lock, so other requests keep verifying with the cached keys meanwhile; requests for a
+// kid that is not cached wait for the fetch in progress.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, string, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > j.refresh
+	leader := false
+	if (stale || !ok) && j.fetching == nil && time.Since(j.attemptedAt) > minJWKSRefetch {
+		j.attemptedAt = time.Now()
+		j.fetching = make(chan struct{})
+		leader = true
+	}
+	fetching := j.fetching
+	j.mu.Unlock()
+
+	if leader {
+		keys, err := j.fetch(ctx)
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+			j.fetchedAt = time.Now()
+		}
+		close(j.fetching)
+		j.fetching = nil
+		j.mu.Unlock()
+
+		if err != nil {
+			if !ok {
+				return nil, "", err
+			}
+			// Keep using the cached key while auth-service is unreachable
+			fmt.Printf("Failed to refresh JWKS: %v\n", err)
+		}
+	} else if !ok && fetching != nil {
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+
+	if !ok {
+		j.mu.Lock()
+		key, ok = j.keys[kid]
+		j.mu.Unlock()
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key.key, key.alg, nil
+}
+
+// fetch downloads and parses the current key set
+func (j *JWKS) fetch(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		switch {
+		case jwk.Kty == "RSA" && jwk.Alg == "RS256":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("invalid RSA key %q in JWKS", jwk.Kid)
+			}
+			keys[jwk.Kid] = publicKey{
+				key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+				alg: jwk.Alg,
+			}
+		case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == "EdDSA":
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid Ed25519 key %q in JWKS", jwk.Kid)
+			}
+			keys[jwk.Kid] = publicKey{key: ed25519.PublicKey(x), alg: jwk.Alg}
+		}
+	}
+
+	return keys, nil
+}
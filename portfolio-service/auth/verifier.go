@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims holds the identity extracted from a verified token
+type Claims struct {
+	UserID    string
+	Username  string
+	ExpiresAt time.Time
+}
+
+// TokenVerifier validates a bearer token and returns the user it belongs to
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// RevocationList reports access tokens revoked by logout or refresh token reuse, by jti
+type RevocationList interface {
+	IsTokenRevoked(jti string) (bool, error)
+}
+
+// LocalVerifier checks RS256 and EdDSA signatures with auth-service's public keys
+type LocalVerifier struct {
+	keys     KeySource
+	revoked  RevocationList
+	issuer   string
+	audience string
+}
+
+// NewLocalVerifier creates a verifier; empty issuer/audience disable those checks and a nil
+// revocation list accepts revoked tokens until they expire
+func NewLocalVerifier(keys KeySource, revoked RevocationList, issuer string, audience string) *LocalVerifier {
+	return &LocalVerifier{
+		keys:     keys,
+		revoked:  revoked,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// Verify parses the token and checks its signature, expiry, issuer, audience and revocation
+func (v *LocalVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, alg, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != alg {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	userID, err := userIDFromClaim(claims["user_id"])
+	if err != nil {
+		return nil, err
+	}
+	username, _ := claims["username"].(string)
+
+	if v.revoked != nil {
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			return nil, fmt.Errorf("jti not found in token")
+		}
+		revoked, err := v.revoked.IsTokenRevoked(jti)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, fmt.Errorf("token has been revoked")
+		}
+	}
+
+	result := &Claims{UserID: userID, Username: username}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	return result, nil
+}
+
+func userIDFromClaim(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("user_id not found in token")
+	case float64:
+		return strconv.Itoa(int(v)), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid user_id type in token")
+	}
+}
+
+// TokenVerifierClient is the part of the auth-service gRPC client used by RemoteVerifier
+type TokenVerifierClient interface {
+	VerifyToken(ctx context.Context, token string) (int32, string, error)
+}
+
+// RemoteVerifier delegates verification to the auth-service VerifyToken RPC
+type RemoteVerifier struct {
+	client TokenVerifierClient
+}
+
+func NewRemoteVerifier(client TokenVerifierClient) *RemoteVerifier {
+	return &RemoteVerifier{client: client}
+}
+
+// Verify asks auth-service whether the token is valid
+func (v *RemoteVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	userID, username, err := v.client.VerifyToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{
+		UserID:   strconv.Itoa(int(userID)),
+		Username: username,
+	}, nil
+}
+
+type cacheEntry struct {
+	claims    *Claims
+	expiresAt time.Time
+}
+
+// CachingVerifier remembers successful verifications for a short time
+type CachingVerifier struct {
+	next    TokenVerifier
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCachingVerifier wraps next; results are kept for ttl or until the token expires
+func NewCachingVerifier(next TokenVerifier, ttl time.Duration) *CachingVerifier {
+	return &CachingVerifier{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Verify returns a cached result when available, otherwise calls the wrapped verifier
+func (v *CachingVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	sum := sha256.Sum256([]byte(tokenString))
+	key := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	v.mu.Lock()
+	entry, ok := v.entries[key]
+	if ok && now.After(entry.expiresAt) {
+		delete(v.entries, key)
+		ok = false
+	}
+	v.mu.Unlock()
+	if ok {
+		return entry.claims, nil
+	}
+
+	claims, err := v.next.Verify(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(v.ttl)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+
+	v.mu.Lock()
+	// Drop stale entries so the cache does not grow without bound
+	for k, e := range v.entries {
+		if now.After(e.expiresAt) {
+			delete(v.entries, k)
+		}
+	}
+	v.entries[key] = cacheEntry{claims: claims, expiresAt: expiresAt}
+	v.mu.Unlock()
+
+	return claims, nil
+}
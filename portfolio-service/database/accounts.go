@@ -0,0 +1,152 @@
+package database
+
+import (
+	"fmt"
+	"portfolio-service/models"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const accountColumns = `id, account_type, cost_basis_method, initial_margin, maintenance_margin, margin_interest_rate, interest_accrued_at, fee_schedule`
+
+func scanAccount(row rowScanner) (*models.Account, error) {
+	var account models.Account
+	err := row.Scan(&account.UserID, &account.AccountType, &account.CostBasisMethod, &account.InitialMargin, &account.MaintenanceMargin,
+		&account.InterestRate, &account.InterestAccruedAt, &account.FeeSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("error getting account: %v", err)
+	}
+	return &account, nil
+}
+
+// GetAccount returns a user's account type and margin settings
+func (db *DB) GetAccount(userID string) (*models.Account, error) {
+	return scanAccount(db.conn.QueryRow(`SELECT `+accountColumns+` FROM users WHERE id = $1`, userID))
+}
+
+// GetMarginAccountIDs returns the IDs of users with margin accounts
+func (db *DB) GetMarginAccountIDs() ([]string, error) {
+	rows, err := db.conn.Query("SELECT id FROM users WHERE account_type = $1 ORDER BY id", models.AccountTypeMargin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query margin accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// IsEmailVerified reports whether a user has confirmed their email address with auth-service
+func (db *DB) IsEmailVerified(userID string) (bool, error) {
+	var verified bool
+	if err := db.conn.QueryRow("SELECT email_verified FROM users WHERE id = $1", userID).Scan(&verified); err != nil {
+		return false, fmt.Errorf("failed to check email verification: %w", err)
+	}
+	return verified, nil
+}
+
+// IsTokenRevoked reports whether auth-service has revoked the access token with this jti
+func (db *DB) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	if err := db.conn.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// GetAccount returns a user's account type and margin settings; call after GetUserCashForUpdate
+func (tx *Tx) GetAccount(userID string) (*models.Account, error) {
+	return scanAccount(tx.tx.QueryRowContext(tx.ctx, `SELECT `+accountColumns+` FROM users WHERE id = $1`, userID))
+}
+
+// UpdateAccount stores a user's account type, cost-basis method and fee schedule. Margin
+// requirements and the interest rate are managed by operators and left unchanged.
+func (tx *Tx) UpdateAccount(account *models.Account) error {
+	query := `
+		UPDATE users
+		SET account_type = $1, cost_basis_method = $2, fee_schedule = $3
+		WHERE id = $4`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, account.AccountType, account.CostBasisMethod, account.FeeSchedule, account.UserID)
+	if err != nil {
+		return fmt.Errorf("error updating account: %v", err)
+	}
+	return nil
+}
+
+// RecordMarginInterest stores interest charged on borrowed cash and moves the accrual date forward
+func (tx *Tx) RecordMarginInterest(userID string, borrowed decimal.Decimal, amount decimal.Decimal, from time.Time, to time.Time) error {
+	if !amount.IsZero() {
+		query := `
+			INSERT INTO margin_interest (user_id, borrowed, amount, period_start, period_end)
+			VALUES ($1, $2, $3, $4, $5)`
+
+		_, err := tx.tx.ExecContext(tx.ctx, query, userID, borrowed, amount, from, to)
+		if err != nil {
+			return fmt.Errorf("error recording margin interest: %v", err)
+		}
+	}
+
+	_, err := tx.tx.ExecContext(tx.ctx, "UPDATE users SET interest_accrued_at = $1 WHERE id = $2", to, userID)
+	if err != nil {
+		return fmt.Errorf("error updating interest accrual date: %v", err)
+	}
+	return nil
+}
+
+// GetAllUserHoldings returns every open long or short position, for margin checks inside a trade
+func (tx *Tx) GetAllUserHoldings(userID string) ([]models.Holding, error) {
+	query := `
+		SELECT symbol, shares, reserved_shares, avg_price
+		FROM holdings
+		WHERE user_id = $1 AND shares <> 0`
+
+	rows, err := tx.tx.QueryContext(tx.ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holdings: %w", err)
+	}
+	defer rows.Close()
+
+	var holdings []models.Holding
+	for rows.Next() {
+		var holding models.Holding
+		err := rows.Scan(&holding.Symbol, &holding.Shares, &holding.ReservedShares, &holding.AvgPrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan holding: %w", err)
+		}
+		holdings = append(holdings, holding)
+	}
+	return holdings, rows.Err()
+}
+
+// OpenMarginCall records a margin call unless the user already has one open
+func (db *DB) OpenMarginCall(userID string, equity decimal.Decimal, requirement decimal.Decimal) (bool, error) {
+	query := `
+		INSERT INTO margin_calls (user_id, equity, requirement)
+		SELECT $1::integer, $2::numeric, $3::numeric
+		WHERE NOT EXISTS (SELECT 1 FROM margin_calls WHERE user_id = $1 AND resolved_at IS NULL)`
+
+	result, err := db.conn.Exec(query, userID, equity, requirement)
+	if err != nil {
+		return false, fmt.Errorf("error opening margin call: %v", err)
+	}
+	opened, _ := result.RowsAffected()
+	return opened == 1, nil
+}
+
+// ResolveMarginCalls closes the user's open margin calls
+func (db *DB) ResolveMarginCalls(userID string) error {
+	_, err := db.conn.Exec("UPDATE margin_calls SET resolved_at = NOW() WHERE user_id = $1 AND resolved_at IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("error resolving margin calls: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const alertColumns = `
+	id, user_id, alert_type, COALESCE(symbol, ''), threshold, channel, COALESCE(target, ''), status,
+	triggered_at, triggered_value, COALESCE(delivery_error, ''), created_at`
+
+// notificationLimit caps how many inbox messages are returned at once
+const notificationLimit = 100
+
+func scanAlerts(rows *sql.Rows) ([]models.Alert, error) {
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var alert models.Alert
+		var triggeredAt sql.NullTime
+		err := rows.Scan(&alert.ID, &alert.UserID, &alert.AlertType, &alert.Symbol, &alert.Threshold, &alert.Channel,
+			&alert.Target, &alert.Status, &triggeredAt, &alert.TriggeredValue, &alert.DeliveryError, &alert.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		if triggeredAt.Valid {
+			alert.TriggeredAt = &triggeredAt.Time
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// CreateAlert stores a new active alert and fills in its ID, status and creation time
+func (db *DB) CreateAlert(alert *models.Alert) error {
+	query := `
+		INSERT INTO alerts (user_id, alert_type, symbol, threshold, channel, target)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''))
+		RETURNING id, status, created_at`
+
+	err := db.conn.QueryRow(query, alert.UserID, alert.AlertType, alert.Symbol, alert.Threshold, alert.Channel, alert.Target).
+		Scan(&alert.ID, &alert.Status, &alert.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating alert: %v", err)
+	}
+	return nil
+}
+
+// GetUserAlerts returns a user's alerts, newest first
+func (db *DB) GetUserAlerts(userID string) ([]models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+// GetActiveAlerts returns every alert still waiting for its condition
+func (db *DB) GetActiveAlerts() ([]models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE status = $1 ORDER BY id`
+
+	rows, err := db.conn.Query(query, models.AlertStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+// DeleteAlert removes one of a user's alerts; returns false if they have no such alert
+func (db *DB) DeleteAlert(userID string, alertID int) (bool, error) {
+	result, err := db.conn.Exec("DELETE FROM alerts WHERE id = $1 AND user_id = $2", alertID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alert: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// TriggerAlert marks an active alert as triggered by value. Returns false when the alert
+// was already triggered or deleted, so each alert notifies at most once.
+func (db *DB) TriggerAlert(alertID int, value decimal.Decimal) (bool, error) {
+	query := `
+		UPDATE alerts SET status = $1, triggered_at = NOW(), triggered_value = $2
+		WHERE id = $3 AND status = $4`
+
+	result, err := db.conn.Exec(query, models.AlertStatusTriggered, value, alertID, models.AlertStatusActive)
+	if err != nil {
+		return false, fmt.Errorf("failed to trigger alert: %w", err)
+	}
+	triggered, err := result.RowsAffected()
+	return triggered > 0, err
+}
+
+// SetAlertDeliveryError records why a triggered alert's notification could not be delivered
+func (db *DB) SetAlertDeliveryError(alertID int, reason string) error {
+	_, err := db.conn.Exec("UPDATE alerts SET delivery_error = $1 WHERE id = $2", reason, alertID)
+	if err != nil {
+		return fmt.Errorf("failed to record alert delivery error: %w", err)
+	}
+	return nil
+}
+
+// GetUserEmail returns the address a user registered with and whether they have confirmed it
+func (db *DB) GetUserEmail(userID string) (string, bool, error) {
+	var email string
+	var verified bool
+	err := db.conn.QueryRow("SELECT email, email_verified FROM users WHERE id = $1", userID).Scan(&email, &verified)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get user email: %w", err)
+	}
+	return email, verified, nil
+}
+
+// CreateNotification adds a message to a user's in-app inbox; alertID 0 means none
+func (db *DB) CreateNotification(userID string, alertID int, subject string, body string) error {
+	query := `
+		INSERT INTO notifications (user_id, alert_id, subject, body)
+		VALUES ($1, NULLIF($2, 0), $3, $4)`
+
+	_, err := db.conn.Exec(query, userID, alertID, subject, body)
+	if err != nil {
+		return fmt.Errorf("error creating notification: %v", err)
+	}
+	return nil
+}
+
+// GetNotifications returns a user's most recent inbox messages, newest first, optionally only unread ones
+func (db *DB) GetNotifications(userID string, unreadOnly bool) ([]models.Notification, error) {
+	query := `
+		SELECT id, alert_id, subject, body, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+
+	rows, err := db.conn.Query(query, userID, unreadOnly, notificationLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		var alertID sql.NullInt64
+		var readAt sql.NullTime
+		err := rows.Scan(&notification.ID, &alertID, &notification.Subject, &notification.Body, &readAt, &notification.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if alertID.Valid {
+			id := int(alertID.Int64)
+			notification.AlertID = &id
+		}
+		if readAt.Valid {
+			notification.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead marks one of a user's inbox messages as read; returns false if they have no such message
+func (db *DB) MarkNotificationRead(userID string, notificationID int) (bool, error) {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2`
+
+	result, err := db.conn.Exec(query, notificationID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	marked, err := result.RowsAffected()
+	return marked > 0, err
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const corporateActionColumns = `
+	id, symbol, action_type, ex_date, cash_amount, ratio_from, ratio_to, COALESCE(new_symbol, ''),
+	status, COALESCE(reason, ''), processed_at, created_at`
+
+func scanCorporateAction(row rowScanner) (*models.CorporateAction, error) {
+	var action models.CorporateAction
+	var processedAt sql.NullTime
+
+	err := row.Scan(&action.ID, &action.Symbol, &action.ActionType, &action.ExDate, &action.CashAmount,
+		&action.RatioFrom, &action.RatioTo, &action.NewSymbol, &action.Status, &action.Reason, &processedAt, &action.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if processedAt.Valid {
+		action.ProcessedAt = &processedAt.Time
+	}
+	return &action, nil
+}
+
+// GetCorporateActions returns every corporate action, latest ex-date first
+func (db *DB) GetCorporateActions() ([]models.CorporateAction, error) {
+	query := `SELECT ` + corporateActionColumns + ` FROM corporate_actions ORDER BY ex_date DESC, id DESC`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []models.CorporateAction{}
+	for rows.Next() {
+		action, err := scanCorporateAction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan corporate action: %w", err)
+		}
+		actions = append(actions, *action)
+	}
+	return actions, rows.Err()
+}
+
+// GetDueCorporateActionIDs returns pending actions whose ex-date is on or before asOf, in the order they take effect
+func (db *DB) GetDueCorporateActionIDs(asOf time.Time) ([]int, error) {
+	query := `SELECT id FROM corporate_actions WHERE status = $1 AND ex_date <= $2 ORDER BY ex_date, id`
+
+	rows, err := db.conn.Query(query, models.CorporateActionPending, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan corporate action id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetCorporateActionForUpdate reads a corporate action and locks its row; returns nil if it does not exist
+func (tx *Tx) GetCorporateActionForUpdate(actionID int) (*models.CorporateAction, error) {
+	query := `SELECT ` + corporateActionColumns + ` FROM corporate_actions WHERE id = $1 FOR UPDATE`
+
+	action, err := scanCorporateAction(tx.tx.QueryRowContext(tx.ctx, query, actionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting corporate action: %v", err)
+	}
+	return action, nil
+}
+
+// FinishCorporateAction marks a corporate action as applied
+func (tx *Tx) FinishCorporateAction(actionID int) error {
+	query := `UPDATE corporate_actions SET status = $1, reason = NULL, processed_at = NOW() WHERE id = $2`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, models.CorporateActionApplied, actionID)
+	if err != nil {
+		return fmt.Errorf("error finishing corporate action: %v", err)
+	}
+	return nil
+}
+
+// FailCorporateAction marks a pending corporate action as failed so it is not retried
+func (db *DB) FailCorporateAction(actionID int, reason string) error {
+	query := `
+		UPDATE corporate_actions
+		SET status = $1, reason = $2, processed_at = NOW()
+		WHERE id = $3 AND status = $4`
+
+	_, err := db.conn.Exec(query, models.CorporateActionFailed, reason, actionID, models.CorporateActionPending)
+	if err != nil {
+		return fmt.Errorf("failed to mark corporate action failed: %w", err)
+	}
+	return nil
+}
+
+// GetHolderIDs returns the users with a long or short position in symbol
+func (tx *Tx) GetHolderIDs(symbol string) ([]string, error) {
+	rows, err := tx.tx.QueryContext(tx.ctx, "SELECT user_id FROM holdings WHERE symbol = $1 AND shares <> 0 ORDER BY user_id", symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan holder id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetOpenOrdersForUpdate locks every order in symbol that is in one of statuses, by ID
+func (tx *Tx) GetOpenOrdersForUpdate(symbol string, statuses ...string) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE symbol = $1 AND status = ANY($2) ORDER BY id FOR UPDATE`
+
+	rows, err := tx.tx.QueryContext(tx.ctx, query, symbol, pq.Array(statuses))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open orders: %w", err)
+	}
+	return scanOrders(rows)
+}
+
+// AdjustOrder stores an open order's symbol, size, prices and reserved shares after a corporate action
+func (tx *Tx) AdjustOrder(order *models.Order) error {
+	query := `
+		UPDATE orders
+		SET symbol = $1, shares = $2, filled_shares = $3, limit_price = $4, stop_price = $5,
+			reserved_shares = $6, updated_at = NOW()
+		WHERE id = $7`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, order.Symbol, order.Shares, order.FilledShares, order.LimitPrice,
+		order.StopPrice, order.ReservedShares, order.ID)
+	if err != nil {
+		return fmt.Errorf("error adjusting order: %v", err)
+	}
+	return nil
+}
+
+// SplitLots multiplies the shares of a user's lots in symbol by ratio and divides their cost per share by it
+func (tx *Tx) SplitLots(userID string, symbol string, ratio decimal.Decimal) error {
+	query := `
+		UPDATE tax_lots
+		SET shares = TRUNC(shares * $3, 6), remaining_shares = TRUNC(remaining_shares * $3, 6),
+			cost_per_share = ROUND(cost_per_share / $3, 4)
+		WHERE user_id = $1 AND symbol = $2`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, userID, symbol, ratio)
+	if err != nil {
+		return fmt.Errorf("error splitting tax lots: %v", err)
+	}
+	return nil
+}
+
+// RenameHolding moves a user's holding and tax lots in symbol to newSymbol. An empty
+// holding row left in newSymbol by an earlier trade is replaced.
+func (tx *Tx) RenameHolding(userID string, symbol string, newSymbol string) error {
+	_, err := tx.tx.ExecContext(tx.ctx, "DELETE FROM holdings WHERE user_id = $1 AND symbol = $2 AND shares = 0 AND reserved_shares = 0",
+		userID, newSymbol)
+	if err != nil {
+		return fmt.Errorf("error clearing holding: %v", err)
+	}
+
+	_, err = tx.tx.ExecContext(tx.ctx, "UPDATE holdings SET symbol = $3, updated_at = NOW() WHERE user_id = $1 AND symbol = $2",
+		userID, symbol, newSymbol)
+	if err != nil {
+		return fmt.Errorf("error renaming holding: %v", err)
+	}
+
+	_, err = tx.tx.ExecContext(tx.ctx, "UPDATE tax_lots SET symbol = $3 WHERE user_id = $1 AND symbol = $2", userID, symbol, newSymbol)
+	if err != nil {
+		return fmt.Errorf("error renaming tax lots: %v", err)
+	}
+	return nil
+}
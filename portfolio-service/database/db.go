@@ -9,6 +9,7 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
 )
 
 type DB struct {
@@ -36,7 +37,11 @@ func Connect() (*DB, error) {
 	}
 
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbName)
+	return ConnectURL(connStr)
+}
 
+// ConnectURL connects to the PostgreSQL database at a postgres:// connection string
+func ConnectURL(connStr string) (*DB, error) {
 	conn, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, err
@@ -58,21 +63,23 @@ func (db *DB) Close() error {
 }
 
 // Get users cash
-func (db *DB) GetUserCash(userID string) (float64, error) {
-	var cash float64
+func (db *DB) GetUserCash(userID string) (decimal.Decimal, error) {
+	var cash decimal.Decimal
 	err := db.conn.QueryRow("SELECT cash FROM users WHERE id = $1", userID).Scan(&cash)
 	if err != nil {
-		return 0, fmt.Errorf("error getting cash from user: %v", err)
+		return decimal.Zero, fmt.Errorf("error getting cash from user: %v", err)
 	}
 	return cash, nil
 }
 
-func (db *DB) UpdateUserCash(userID string, newCash float64) error {
-	_, err := db.conn.Exec("UPDATE users SET cash = $1 WHERE id = $2", newCash, userID)
+// GetUserReservedCash returns the cash held back for the user's open orders
+func (db *DB) GetUserReservedCash(userID string) (decimal.Decimal, error) {
+	var reserved decimal.Decimal
+	err := db.conn.QueryRow("SELECT reserved_cash FROM users WHERE id = $1", userID).Scan(&reserved)
 	if err != nil {
-		return fmt.Errorf("error updating cash to user: %v", err)
+		return decimal.Zero, fmt.Errorf("error getting reserved cash from user: %v", err)
 	}
-	return nil
+	return reserved, nil
 }
 
 func (db *DB) GetUserHoldings(userID string, symbol string) (*models.Holding, error) {
@@ -90,8 +97,7 @@ func (db *DB) GetUserHoldings(userID string, symbol string) (*models.Holding, er
 	return &holding, nil
 }
 
-func (db *DB) UpdateUserHoldings(userID string, symbol string, shares int, avgPrice float64) error {
-	query := `
+const upsertHoldingQuery = `
        INSERT INTO holdings (user_id, symbol, shares, avg_price)
        VALUES ($1, $2, $3, $4)
        ON CONFLICT (user_id, symbol)
@@ -99,28 +105,25 @@ func (db *DB) UpdateUserHoldings(userID string, symbol string, shares int, avgPr
            shares = $3,
            avg_price = $4
 `
-	_, err := db.conn.Exec(query, userID, symbol, shares, avgPrice)
-	if err != nil {
-		return fmt.Errorf("error updating holdings in user: %v", err)
-	}
-	return nil
-}
 
-func (db *DB) CreateTransaction(userID string, symbol string, shares int, price float64, transactionType string, totalAmount float64) error {
-	query := `
-       INSERT INTO transactions (user_id, symbol, shares, price, transaction_type, total_amount, created_at)
-       VALUES ($1, $2, $3, $4, $5, $6, $7)`
+const createTransactionQuery = `
+       INSERT INTO transactions (user_id, symbol, shares, price, transaction_type, total_amount, fee, realized_gain, created_at)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+       RETURNING id`
 
-	_, err := db.conn.Exec(query, userID, symbol, shares, price, transactionType, totalAmount, time.Now())
+func (db *DB) CreateTransaction(userID string, symbol string, shares decimal.Decimal, price decimal.Decimal, transactionType string,
+	totalAmount decimal.Decimal, fee decimal.Decimal, realizedGain decimal.NullDecimal) (int, error) {
+	var id int
+	err := db.conn.QueryRow(createTransactionQuery, userID, symbol, shares, price, transactionType, totalAmount, fee, realizedGain, time.Now()).Scan(&id)
 	if err != nil {
-		return fmt.Errorf("error creating transaction: %v", err)
+		return 0, fmt.Errorf("error creating transaction: %v", err)
 	}
-	return nil
+	return id, nil
 }
 
 func (db *DB) GetUserTransaction(userID string) ([]models.Transaction, error) {
 	query := `
-		SELECT id, user_id, symbol, shares, price, transaction_type, total_amount, created_at
+		SELECT id, user_id, symbol, shares, price, transaction_type, total_amount, fee, realized_gain, created_at
 		FROM transactions
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -136,7 +139,7 @@ func (db *DB) GetUserTransaction(userID string) ([]models.Transaction, error) {
 	for rows.Next() {
 		var tx models.Transaction
 		err := rows.Scan(&tx.ID, &tx.UserID, &tx.Symbol, &tx.Shares, &tx.Price,
-			&tx.TransactionType, &tx.TotalAmount, &tx.Timestamp)
+			&tx.TransactionType, &tx.TotalAmount, &tx.Fee, &tx.RealizedGain, &tx.Timestamp)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %v", err)
 		}
@@ -145,16 +148,131 @@ func (db *DB) GetUserTransaction(userID string) ([]models.Transaction, error) {
 	return transactions, nil
 }
 
+// GetUserTransactionsBetween returns a user's transactions made between from and to, oldest first
+func (db *DB) GetUserTransactionsBetween(userID string, from time.Time, to time.Time) ([]models.Transaction, error) {
+	query := `
+		SELECT id, user_id, symbol, shares, price, transaction_type, total_amount, fee, realized_gain, created_at
+		FROM transactions
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at`
+
+	rows, err := db.conn.Query(query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions from user: %v", err)
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		err := rows.Scan(&tx.ID, &tx.UserID, &tx.Symbol, &tx.Shares, &tx.Price,
+			&tx.TransactionType, &tx.TotalAmount, &tx.Fee, &tx.RealizedGain, &tx.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
 func (db *DB) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return db.conn.BeginTx(ctx, nil)
 }
 
+// Tx is a database transaction exposing the queries a trade needs
+type Tx struct {
+	tx  *sql.Tx
+	ctx context.Context
+}
+
+// WithTx runs fn inside a transaction, committing if fn returns nil and rolling back otherwise
+func (db *DB) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	sqlTx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := fn(&Tx{tx: sqlTx, ctx: ctx}); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetUserCashForUpdate reads the user's cash and the part of it reserved by open orders,
+// and locks the users row until the transaction ends.
+// Every trade takes this lock first, so trades for the same user run one at a time.
+func (tx *Tx) GetUserCashForUpdate(userID string) (decimal.Decimal, decimal.Decimal, error) {
+	var cash, reserved decimal.Decimal
+	err := tx.tx.QueryRowContext(tx.ctx, "SELECT cash, reserved_cash FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&cash, &reserved)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("error getting cash from user: %v", err)
+	}
+	return cash, reserved, nil
+}
+
+// AdjustReservedCash adds delta (which may be negative) to the cash held back for open orders
+func (tx *Tx) AdjustReservedCash(userID string, delta decimal.Decimal) error {
+	_, err := tx.tx.ExecContext(tx.ctx, "UPDATE users SET reserved_cash = reserved_cash + $1 WHERE id = $2", delta, userID)
+	if err != nil {
+		return fmt.Errorf("error updating reserved cash: %v", err)
+	}
+	return nil
+}
+
+// GetUserHoldingForUpdate reads a holding and locks its row; returns nil if the user has none
+func (tx *Tx) GetUserHoldingForUpdate(userID string, symbol string) (*models.Holding, error) {
+	var holding models.Holding
+	query := "SELECT symbol, shares, reserved_shares, avg_price FROM holdings WHERE user_id = $1 AND symbol = $2 FOR UPDATE"
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, userID, symbol).Scan(&holding.Symbol, &holding.Shares, &holding.ReservedShares, &holding.AvgPrice)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting holdings from user: %v", err)
+	}
+
+	return &holding, nil
+}
+
+// AdjustReservedShares adds delta (which may be negative) to the shares held back for open sell orders
+func (tx *Tx) AdjustReservedShares(userID string, symbol string, delta decimal.Decimal) error {
+	_, err := tx.tx.ExecContext(tx.ctx, "UPDATE holdings SET reserved_shares = reserved_shares + $1 WHERE user_id = $2 AND symbol = $3", delta, userID, symbol)
+	if err != nil {
+		return fmt.Errorf("error updating reserved shares: %v", err)
+	}
+	return nil
+}
+
+func (tx *Tx) UpsertHolding(userID string, symbol string, shares decimal.Decimal, avgPrice decimal.Decimal) error {
+	_, err := tx.tx.ExecContext(tx.ctx, upsertHoldingQuery, userID, symbol, shares, avgPrice)
+	if err != nil {
+		return fmt.Errorf("error updating holdings in user: %v", err)
+	}
+	return nil
+}
+
+func (tx *Tx) CreateTransaction(userID string, symbol string, shares decimal.Decimal, price decimal.Decimal, transactionType string,
+	totalAmount decimal.Decimal, fee decimal.Decimal, realizedGain decimal.NullDecimal) (int, error) {
+	var id int
+	err := tx.tx.QueryRowContext(tx.ctx, createTransactionQuery, userID, symbol, shares, price, transactionType, totalAmount, fee, realizedGain, time.Now()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error creating transaction: %v", err)
+	}
+	return id, nil
+}
+
 // GetAllUserHoldings retrieves all stock holdings for a user
 func (db *DB) GetAllUserHoldings(userID string) ([]models.Holding, error) {
 	query := `
-		SELECT symbol, shares, avg_price 
-		FROM holdings 
-		WHERE user_id = $1 AND shares > 0
+		SELECT symbol, shares, reserved_shares, avg_price
+		FROM holdings
+		WHERE user_id = $1 AND shares <> 0
 	`
 
 	rows, err := db.conn.Query(query, userID)
@@ -166,7 +284,7 @@ func (db *DB) GetAllUserHoldings(userID string) ([]models.Holding, error) {
 	var holdings []models.Holding
 	for rows.Next() {
 		var holding models.Holding
-		err := rows.Scan(&holding.Symbol, &holding.Shares, &holding.AvgPrice)
+		err := rows.Scan(&holding.Symbol, &holding.Shares, &holding.ReservedShares, &holding.AvgPrice)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan holding: %w", err)
 		}
@@ -176,25 +294,20 @@ func (db *DB) GetAllUserHoldings(userID string) ([]models.Holding, error) {
 	return holdings, nil
 }
 
-// GetUserHolding gets specific stock holding for user (rename your existing function)
+// GetUserHolding returns the user's holding of symbol, or nil if they hold none
 func (db *DB) GetUserHolding(userID string, symbol string) (*models.Holding, error) {
 	return db.GetUserHoldings(userID, symbol)
 }
 
-// UpsertHolding - rename your existing UpdateUserHoldings
-func (db *DB) UpsertHolding(userID string, symbol string, shares int, avgPrice float64) error {
-	return db.UpdateUserHoldings(userID, symbol, shares, avgPrice)
-}
-
-// GetUserTransactions - rename your existing function
+// GetUserTransactions returns the user's transactions
 func (db *DB) GetUserTransactions(userID string) ([]models.Transaction, error) {
 	return db.GetUserTransaction(userID)
 }
 
 // GetStockPrices retrieves current prices from the database for given symbols
-func (db *DB) GetStockPrices(symbols []string) (map[string]float64, error) {
+func (db *DB) GetStockPrices(symbols []string) (map[string]decimal.Decimal, error) {
 	if len(symbols) == 0 {
-		return make(map[string]float64), nil
+		return make(map[string]decimal.Decimal), nil
 	}
 
 	// Create placeholders for the IN clause
@@ -221,10 +334,10 @@ func (db *DB) GetStockPrices(symbols []string) (map[string]float64, error) {
 	}
 	defer rows.Close()
 
-	prices := make(map[string]float64)
+	prices := make(map[string]decimal.Decimal)
 	for rows.Next() {
 		var symbol string
-		var price float64
+		var price decimal.Decimal
 		err := rows.Scan(&symbol, &price)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stock price: %w", err)
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const feeScheduleColumns = `name, description, user_id IS NOT NULL, selectable, flat_fee, per_share_fee, percent_fee, min_fee, max_fee`
+
+func scanFeeSchedule(row rowScanner) (*models.FeeSchedule, error) {
+	schedule := models.FeeSchedule{Tiers: []models.FeeTier{}}
+	err := row.Scan(&schedule.Name, &schedule.Description, &schedule.Private, &schedule.Selectable, &schedule.FlatFee, &schedule.PerShareFee,
+		&schedule.PercentFee, &schedule.MinFee, &schedule.MaxFee)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+const feeTiersQuery = `
+	SELECT schedule, min_volume, per_share_fee, percent_fee
+	FROM fee_tiers
+	WHERE schedule = ANY($1)
+	ORDER BY schedule, min_volume`
+
+// attachFeeTiers reads fee_tiers rows into the schedules they belong to, lowest volume first
+func attachFeeTiers(rows *sql.Rows, schedules []*models.FeeSchedule) error {
+	defer rows.Close()
+
+	byName := make(map[string]*models.FeeSchedule, len(schedules))
+	for _, schedule := range schedules {
+		byName[schedule.Name] = schedule
+	}
+	for rows.Next() {
+		var name string
+		var tier models.FeeTier
+		if err := rows.Scan(&name, &tier.MinVolume, &tier.PerShareFee, &tier.PercentFee); err != nil {
+			return fmt.Errorf("failed to scan fee tier: %w", err)
+		}
+		if schedule, ok := byName[name]; ok {
+			schedule.Tiers = append(schedule.Tiers, tier)
+		}
+	}
+	return rows.Err()
+}
+
+// GetFeeSchedules returns the commission plans the user can switch to: the selectable public
+// plans and any schedule negotiated for them
+func (db *DB) GetFeeSchedules(userID string) ([]models.FeeSchedule, error) {
+	query := `
+		SELECT ` + feeScheduleColumns + `
+		FROM fee_schedules
+		WHERE selectable AND (user_id IS NULL OR user_id = $1)
+		ORDER BY user_id IS NULL, name`
+
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*models.FeeSchedule
+	var names []string
+	for rows.Next() {
+		schedule, err := scanFeeSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fee schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+		names = append(names, schedule.Name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tierRows, err := db.conn.Query(feeTiersQuery, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee tiers: %w", err)
+	}
+	if err := attachFeeTiers(tierRows, schedules); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.FeeSchedule, len(schedules))
+	for i, schedule := range schedules {
+		result[i] = *schedule
+	}
+	return result, nil
+}
+
+// GetFeeSchedule returns the named schedule with its tiers, or nil if it does not exist
+// or was negotiated for another user
+func (tx *Tx) GetFeeSchedule(userID string, name string) (*models.FeeSchedule, error) {
+	query := `
+		SELECT ` + feeScheduleColumns + `
+		FROM fee_schedules
+		WHERE name = $1 AND (user_id IS NULL OR user_id = $2)`
+
+	schedule, err := scanFeeSchedule(tx.tx.QueryRowContext(tx.ctx, query, name, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting fee schedule: %v", err)
+	}
+
+	rows, err := tx.tx.QueryContext(tx.ctx, feeTiersQuery, pq.Array([]string{name}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee tiers: %w", err)
+	}
+	if err := attachFeeTiers(rows, []*models.FeeSchedule{schedule}); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+const tradeVolumeQuery = `
+	SELECT COALESCE(SUM(total_amount), 0)
+	FROM transactions
+	WHERE user_id = $1 AND transaction_type IN ('BUY', 'SELL') AND created_at >= $2`
+
+// GetTradeVolume returns the value of the user's buys and sells since the given time
+func (db *DB) GetTradeVolume(userID string, since time.Time) (decimal.Decimal, error) {
+	var volume decimal.Decimal
+	if err := db.conn.QueryRow(tradeVolumeQuery, userID, since).Scan(&volume); err != nil {
+		return decimal.Zero, fmt.Errorf("error summing trade volume: %v", err)
+	}
+	return volume, nil
+}
+
+// GetTradeVolume returns the value of the user's buys and sells since the given time;
+// lock the user's row first so concurrent trades see each other's volume
+func (tx *Tx) GetTradeVolume(userID string, since time.Time) (decimal.Decimal, error) {
+	var volume decimal.Decimal
+	if err := tx.tx.QueryRowContext(tx.ctx, tradeVolumeQuery, userID, since).Scan(&volume); err != nil {
+		return decimal.Zero, fmt.Errorf("error summing trade volume: %v", err)
+	}
+	return volume, nil
+}
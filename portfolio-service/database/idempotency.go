@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+	"time"
+)
+
+// expiredIdempotencyKeys matches keys older than the retention period ($1 seconds) and
+// claims still in progress after their lease ($2 seconds), whose request never finished
+const expiredIdempotencyKeys = `
+	(created_at < NOW() - $1::float8 * INTERVAL '1 second'
+		OR (response_status IS NULL AND created_at < NOW() - $2::float8 * INTERVAL '1 second'))`
+
+// ClaimIdempotencyKey reserves key for the user. It returns claimed=true when the
+// caller should run the request, or the stored record when the key was used before.
+// Keys older than ttl, and claims whose request has not finished within lease, are
+// forgotten and can be claimed again.
+func (db *DB) ClaimIdempotencyKey(userID string, key string, requestHash string, ttl time.Duration, lease time.Duration) (*models.IdempotencyRecord, bool, error) {
+	_, err := db.conn.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE user_id = $3 AND idempotency_key = $4 AND`+expiredIdempotencyKeys,
+		ttl.Seconds(), lease.Seconds(), userID, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("error expiring idempotency key: %v", err)
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING`, userID, key, requestHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("error claiming idempotency key: %v", err)
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 1 {
+		return nil, true, nil
+	}
+
+	var record models.IdempotencyRecord
+	var status sql.NullInt64
+	err = db.conn.QueryRow(`
+		SELECT idempotency_key, user_id, request_hash, response_status, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2`, userID, key).Scan(
+		&record.Key, &record.UserID, &record.RequestHash, &status, &record.ResponseBody, &record.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Expired and removed between our insert and select; let the caller retry
+		return nil, false, fmt.Errorf("idempotency key %q changed concurrently, retry the request", key)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting idempotency key: %v", err)
+	}
+	if status.Valid {
+		record.ResponseStatus = int(status.Int64)
+		record.Completed = true
+	}
+	return &record, false, nil
+}
+
+// SaveIdempotentResponse stores the response to replay for a claimed key
+func (db *DB) SaveIdempotentResponse(userID string, key string, status int, body []byte) error {
+	_, err := db.conn.Exec(`
+		UPDATE idempotency_keys
+		SET response_status = $1, response_body = $2
+		WHERE user_id = $3 AND idempotency_key = $4`, status, body, userID, key)
+	if err != nil {
+		return fmt.Errorf("error saving idempotent response: %v", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes every user's keys older than ttl and claims whose request
+// has not finished within lease, returning how many were deleted
+func (db *DB) PurgeIdempotencyKeys(ttl time.Duration, lease time.Duration) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM idempotency_keys WHERE`+expiredIdempotencyKeys, ttl.Seconds(), lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("error purging idempotency keys: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// ReleaseIdempotencyKey forgets a claimed key so the request can be retried
+func (db *DB) ReleaseIdempotencyKey(userID string, key string) error {
+	_, err := db.conn.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %v", err)
+	}
+	return nil
+}
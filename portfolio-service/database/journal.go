@@ -0,0 +1,254 @@
+package database
+
+import (
+	"fmt"
+	"portfolio-service/models"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// PostJournal records a balanced journal entry and its postings, filling in ID and CreatedAt
+func (tx *Tx) PostJournal(entry *models.JournalEntry) error {
+	total := decimal.Zero
+	for _, posting := range entry.Postings {
+		total = total.Add(posting.Amount)
+	}
+	if !total.IsZero() {
+		return fmt.Errorf("journal entry does not balance: postings sum to %s", total.String())
+	}
+
+	query := `
+		INSERT INTO journal_entries (user_id, entry_type, transaction_id, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, entry.UserID, entry.EntryType, entry.TransactionID, entry.Description).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording journal entry: %v", err)
+	}
+
+	for _, posting := range entry.Postings {
+		query := `
+			INSERT INTO postings (entry_id, user_id, account, symbol, quantity, amount)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`
+
+		_, err := tx.tx.ExecContext(tx.ctx, query, entry.ID, entry.UserID, posting.Account, posting.Symbol, posting.Quantity, posting.Amount)
+		if err != nil {
+			return fmt.Errorf("error recording posting: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetJournalEntries returns a user's most recent journal entries with their postings, newest first
+func (db *DB) GetJournalEntries(userID string, limit int) ([]models.JournalEntry, error) {
+	query := `
+		SELECT id, user_id, entry_type, transaction_id, description, created_at
+		FROM journal_entries
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+
+	rows, err := db.conn.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	index := make(map[int]int)
+	var ids []int64
+	for rows.Next() {
+		var entry models.JournalEntry
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryType, &entry.TransactionID, &entry.Description, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		entry.Postings = []models.Posting{}
+		index[entry.ID] = len(entries)
+		ids = append(ids, int64(entry.ID))
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	postingRows, err := db.conn.Query(`
+		SELECT entry_id, account, COALESCE(symbol, ''), quantity, amount
+		FROM postings
+		WHERE entry_id = ANY($1)
+		ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query postings: %w", err)
+	}
+	defer postingRows.Close()
+
+	for postingRows.Next() {
+		var entryID int
+		var posting models.Posting
+		if err := postingRows.Scan(&entryID, &posting.Account, &posting.Symbol, &posting.Quantity, &posting.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		i := index[entryID]
+		entries[i].Postings = append(entries[i].Postings, posting)
+	}
+	return entries, postingRows.Err()
+}
+
+// GetJournalBalances computes a user's cash and security positions from their postings
+func (db *DB) GetJournalBalances(userID string) (decimal.Decimal, []models.JournalPosition, error) {
+	var cash decimal.Decimal
+	err := db.conn.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM postings WHERE user_id = $1 AND account = $2",
+		userID, models.LedgerAccountCash).Scan(&cash)
+	if err != nil {
+		return cash, nil, fmt.Errorf("error summing cash postings: %v", err)
+	}
+
+	query := `
+		SELECT symbol, SUM(quantity), SUM(amount)
+		FROM postings
+		WHERE user_id = $1 AND account = $2
+		GROUP BY symbol
+		HAVING SUM(quantity) <> 0
+		ORDER BY symbol`
+
+	rows, err := db.conn.Query(query, userID, models.LedgerAccountSecurities)
+	if err != nil {
+		return cash, nil, fmt.Errorf("failed to query security postings: %w", err)
+	}
+	defer rows.Close()
+
+	positions := []models.JournalPosition{}
+	for rows.Next() {
+		var position models.JournalPosition
+		if err := rows.Scan(&position.Symbol, &position.Quantity, &position.CostBasis); err != nil {
+			return cash, nil, fmt.Errorf("failed to scan position: %w", err)
+		}
+		positions = append(positions, position)
+	}
+	return cash, positions, rows.Err()
+}
+
+// GetJournalPosition returns a user's share quantity and cost basis in symbol from their postings
+func (tx *Tx) GetJournalPosition(userID string, symbol string) (decimal.Decimal, decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(amount), 0)
+		FROM postings
+		WHERE user_id = $1 AND account = $2 AND symbol = $3`
+
+	var quantity, amount decimal.Decimal
+	err := tx.tx.QueryRowContext(tx.ctx, query, userID, models.LedgerAccountSecurities, symbol).Scan(&quantity, &amount)
+	if err != nil {
+		return quantity, amount, fmt.Errorf("error summing security postings: %v", err)
+	}
+	return quantity, amount, nil
+}
+
+// FindCashMismatches returns users whose users.cash differs from their CASH postings
+func (db *DB) FindCashMismatches() ([]models.ReconciliationIssue, error) {
+	query := `
+		SELECT u.id, COALESCE(p.balance, 0)::text, u.cash::text
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, SUM(amount) AS balance FROM postings WHERE account = $1 GROUP BY user_id
+		) p ON p.user_id = u.id
+		WHERE u.cash <> COALESCE(p.balance, 0)
+		ORDER BY u.id`
+
+	return db.findMismatches("journal cash", query, models.LedgerAccountCash)
+}
+
+// FindLedgerMismatches returns users whose users.cash differs from their cash ledger
+func (db *DB) FindLedgerMismatches() ([]models.ReconciliationIssue, error) {
+	query := `
+		SELECT u.id, COALESCE(l.balance, 0)::text, u.cash::text
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id,
+				SUM(CASE WHEN debit_account = $1 THEN amount WHEN credit_account = $1 THEN -amount ELSE 0 END) AS balance
+			FROM cash_ledger GROUP BY user_id
+		) l ON l.user_id = u.id
+		WHERE u.cash <> COALESCE(l.balance, 0)
+		ORDER BY u.id`
+
+	return db.findMismatches("cash ledger", query, models.LedgerAccountCash)
+}
+
+// FindHoldingMismatches returns holdings whose shares differ from their SECURITIES postings
+func (db *DB) FindHoldingMismatches() ([]models.ReconciliationIssue, error) {
+	query := `
+		SELECT COALESCE(h.user_id, p.user_id), COALESCE(h.symbol, p.symbol),
+			COALESCE(p.quantity, 0)::text, COALESCE(h.shares, 0)::text
+		FROM (SELECT user_id, symbol, shares FROM holdings WHERE shares <> 0) h
+		FULL OUTER JOIN (
+			SELECT user_id, symbol, SUM(quantity) AS quantity FROM postings WHERE account = $1 GROUP BY user_id, symbol
+		) p ON p.user_id = h.user_id AND p.symbol = h.symbol
+		WHERE COALESCE(h.shares, 0) <> COALESCE(p.quantity, 0)
+		ORDER BY 1, 2`
+
+	rows, err := db.conn.Query(query, models.LedgerAccountSecurities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile holdings: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []models.ReconciliationIssue
+	for rows.Next() {
+		issue := models.ReconciliationIssue{Check: "journal shares"}
+		if err := rows.Scan(&issue.UserID, &issue.Subject, &issue.Expected, &issue.Actual); err != nil {
+			return nil, fmt.Errorf("failed to scan holding mismatch: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
+
+// FindUnbalancedEntries returns journal entries whose postings do not sum to zero
+func (db *DB) FindUnbalancedEntries() ([]models.ReconciliationIssue, error) {
+	query := `
+		SELECT e.user_id, 'entry ' || e.id, '0', SUM(p.amount)::text
+		FROM journal_entries e
+		JOIN postings p ON p.entry_id = e.id
+		GROUP BY e.id, e.user_id
+		HAVING SUM(p.amount) <> 0
+		ORDER BY e.id`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check journal balance: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []models.ReconciliationIssue
+	for rows.Next() {
+		issue := models.ReconciliationIssue{Check: "balanced entry"}
+		if err := rows.Scan(&issue.UserID, &issue.Subject, &issue.Expected, &issue.Actual); err != nil {
+			return nil, fmt.Errorf("failed to scan unbalanced entry: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
+
+func (db *DB) findMismatches(check string, query string, args ...interface{}) ([]models.ReconciliationIssue, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile %s: %w", check, err)
+	}
+	defer rows.Close()
+
+	var issues []models.ReconciliationIssue
+	for rows.Next() {
+		issue := models.ReconciliationIssue{Check: check}
+		if err := rows.Scan(&issue.UserID, &issue.Expected, &issue.Actual); err != nil {
+			return nil, fmt.Errorf("failed to scan %s mismatch: %w", check, err)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"portfolio-service/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// PostCash records a ledger entry and applies it to the user's cash balance, filling in the
+// entry's ID, BalanceAfter and CreatedAt. Post its journal entry first and lock the user's row
+// with GetUserCashForUpdate.
+func (tx *Tx) PostCash(entry *models.LedgerEntry) error {
+	change := decimal.Zero
+	switch models.LedgerAccountCash {
+	case entry.DebitAccount:
+		change = entry.Amount
+	case entry.CreditAccount:
+		change = entry.Amount.Neg()
+	}
+
+	err := tx.tx.QueryRowContext(tx.ctx, "UPDATE users SET cash = cash + $1 WHERE id = $2 RETURNING cash",
+		change, entry.UserID).Scan(&entry.BalanceAfter)
+	if err != nil {
+		return fmt.Errorf("error updating cash: %v", err)
+	}
+
+	query := `
+		INSERT INTO cash_ledger (user_id, entry_type, debit_account, credit_account, amount, balance_after, transaction_id, journal_entry_id, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	err = tx.tx.QueryRowContext(tx.ctx, query, entry.UserID, entry.EntryType, entry.DebitAccount, entry.CreditAccount,
+		entry.Amount, entry.BalanceAfter, entry.TransactionID, entry.JournalID, entry.Description).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording ledger entry: %v", err)
+	}
+	return nil
+}
+
+// GetLedgerEntries returns a user's cash ledger, newest first
+func (db *DB) GetLedgerEntries(userID string) ([]models.LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, entry_type, debit_account, credit_account, amount, balance_after, transaction_id, journal_entry_id, description, created_at
+		FROM cash_ledger
+		WHERE user_id = $1
+		ORDER BY id DESC`
+
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cash ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		var entry models.LedgerEntry
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+			&entry.Amount, &entry.BalanceAfter, &entry.TransactionID, &entry.JournalID, &entry.Description, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+const ledgerBalanceQuery = `
+	SELECT COALESCE(SUM(CASE WHEN debit_account = $2 THEN amount WHEN credit_account = $2 THEN -amount ELSE 0 END), 0)
+	FROM cash_ledger
+	WHERE user_id = $1`
+
+// GetLedgerBalance sums a user's cash ledger: debits to CASH add, credits subtract.
+// Lock the user's row first so the sum can be compared with users.cash.
+func (tx *Tx) GetLedgerBalance(userID string) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := tx.tx.QueryRowContext(tx.ctx, ledgerBalanceQuery, userID, models.LedgerAccountCash).Scan(&balance)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error summing cash ledger: %v", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const lotColumns = `id, symbol, transaction_id, shares, remaining_shares, cost_per_share, acquired_at`
+
+func scanLots(rows *sql.Rows) ([]models.TaxLot, error) {
+	defer rows.Close()
+
+	lots := []models.TaxLot{}
+	for rows.Next() {
+		var lot models.TaxLot
+		err := rows.Scan(&lot.ID, &lot.Symbol, &lot.TransactionID, &lot.Shares, &lot.RemainingShares,
+			&lot.CostPerShare, &lot.AcquiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tax lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+// CreateLot opens a tax lot for the shares bought (or sold short) by a transaction
+func (tx *Tx) CreateLot(userID string, symbol string, transactionID int, shares decimal.Decimal, costPerShare decimal.Decimal) error {
+	query := `
+		INSERT INTO tax_lots (user_id, symbol, transaction_id, shares, remaining_shares, cost_per_share)
+		VALUES ($1, $2, $3, $4, $4, $5)`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, userID, symbol, transactionID, shares, costPerShare)
+	if err != nil {
+		return fmt.Errorf("error creating tax lot: %v", err)
+	}
+	return nil
+}
+
+// GetOpenLotsForUpdate locks the user's open lots in a symbol, oldest first unless newestFirst
+func (tx *Tx) GetOpenLotsForUpdate(userID string, symbol string, newestFirst bool) ([]models.TaxLot, error) {
+	order := "acquired_at, id"
+	if newestFirst {
+		order = "acquired_at DESC, id DESC"
+	}
+
+	query := `SELECT ` + lotColumns + ` FROM tax_lots
+		WHERE user_id = $1 AND symbol = $2 AND remaining_shares <> 0
+		ORDER BY ` + order + `
+		FOR UPDATE`
+
+	rows, err := tx.tx.QueryContext(tx.ctx, query, userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax lots: %w", err)
+	}
+	return scanLots(rows)
+}
+
+// RecordLotSales stores the lots closed by a transaction and reduces their remaining shares
+func (tx *Tx) RecordLotSales(transactionID int, sales []models.LotSale) error {
+	for _, sale := range sales {
+		query := `
+			INSERT INTO lot_sales (transaction_id, lot_id, shares, cost_basis, proceeds, realized_gain)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+
+		_, err := tx.tx.ExecContext(tx.ctx, query, transactionID, sale.LotID, sale.Shares, sale.CostBasis, sale.Proceeds, sale.RealizedGain)
+		if err != nil {
+			return fmt.Errorf("error recording lot sale: %v", err)
+		}
+
+		// Short lots hold negative shares, so closing them moves remaining_shares up towards zero
+		query = `
+			UPDATE tax_lots
+			SET remaining_shares = CASE WHEN remaining_shares > 0 THEN remaining_shares - $1 ELSE remaining_shares + $1 END
+			WHERE id = $2`
+
+		_, err = tx.tx.ExecContext(tx.ctx, query, sale.Shares, sale.LotID)
+		if err != nil {
+			return fmt.Errorf("error reducing tax lot: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetUserLots returns a user's open tax lots, optionally for a single symbol
+func (db *DB) GetUserLots(userID string, symbol string) ([]models.TaxLot, error) {
+	query := `SELECT ` + lotColumns + ` FROM tax_lots
+		WHERE user_id = $1 AND remaining_shares <> 0 AND ($2 = '' OR symbol = $2)
+		ORDER BY symbol, acquired_at, id`
+
+	rows, err := db.conn.Query(query, userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax lots: %w", err)
+	}
+	return scanLots(rows)
+}
+
+// GetRealizedGain sums the gains realized by a user's transactions
+func (db *DB) GetRealizedGain(userID string) (decimal.Decimal, error) {
+	var gain decimal.Decimal
+	err := db.conn.QueryRow("SELECT COALESCE(SUM(realized_gain), 0) FROM transactions WHERE user_id = $1", userID).Scan(&gain)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error getting realized gain: %v", err)
+	}
+	return gain, nil
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const orderColumns = `
+	id, user_id, symbol, side, order_type, shares, filled_shares, limit_price, stop_price, time_in_force,
+	status, triggered, reserved_cash, reserved_shares, filled_price, transaction_id,
+	COALESCE(reason, ''), created_at, updated_at, expires_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row rowScanner) (*models.Order, error) {
+	var order models.Order
+	var transactionID sql.NullInt64
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&order.ID, &order.UserID, &order.Symbol, &order.Side, &order.OrderType, &order.Shares,
+		&order.FilledShares, &order.LimitPrice, &order.StopPrice, &order.TimeInForce, &order.Status, &order.Triggered,
+		&order.ReservedCash, &order.ReservedShares, &order.FilledPrice, &transactionID,
+		&order.Reason, &order.CreatedAt, &order.UpdatedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if transactionID.Valid {
+		id := int(transactionID.Int64)
+		order.TransactionID = &id
+	}
+	if expiresAt.Valid {
+		order.ExpiresAt = &expiresAt.Time
+	}
+	return &order, nil
+}
+
+func scanOrders(rows *sql.Rows) ([]models.Order, error) {
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+// CreateOrder inserts a new pending order and fills in its ID and timestamps
+func (tx *Tx) CreateOrder(order *models.Order) error {
+	query := `
+		INSERT INTO orders (user_id, symbol, side, order_type, shares, limit_price, stop_price, time_in_force,
+			status, reserved_cash, reserved_shares, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, order.UserID, order.Symbol, order.Side, order.OrderType, order.Shares,
+		order.LimitPrice, order.StopPrice, order.TimeInForce, order.Status, order.ReservedCash, order.ReservedShares,
+		order.ExpiresAt).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating order: %v", err)
+	}
+	return nil
+}
+
+// GetOrderForUpdate reads an order and locks its row; returns nil if it does not exist
+func (tx *Tx) GetOrderForUpdate(orderID int) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 FOR UPDATE`
+
+	order, err := scanOrder(tx.tx.QueryRowContext(tx.ctx, query, orderID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting order: %v", err)
+	}
+	return order, nil
+}
+
+// MarkOrderTriggered records that a stop-limit order's stop price has been reached
+func (tx *Tx) MarkOrderTriggered(orderID int) error {
+	_, err := tx.tx.ExecContext(tx.ctx, "UPDATE orders SET triggered = TRUE, updated_at = NOW() WHERE id = $1", orderID)
+	if err != nil {
+		return fmt.Errorf("error triggering order: %v", err)
+	}
+	return nil
+}
+
+// FillOrder stores an order's status, filled shares, average fill price, trigger and
+// remaining reservation after a fill by the given transaction
+func (tx *Tx) FillOrder(order *models.Order, transactionID int) error {
+	query := `
+		UPDATE orders
+		SET status = $1, filled_shares = $2, filled_price = $3, triggered = $4, transaction_id = $5,
+			reserved_cash = $6, reserved_shares = $7, updated_at = NOW()
+		WHERE id = $8`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, order.Status, order.FilledShares, order.FilledPrice, order.Triggered,
+		transactionID, order.ReservedCash, order.ReservedShares, order.ID)
+	if err != nil {
+		return fmt.Errorf("error filling order: %v", err)
+	}
+	return nil
+}
+
+// CloseOrder moves an order to a final status without a fill and clears its reservation
+func (tx *Tx) CloseOrder(orderID int, status string, reason string) error {
+	query := `
+		UPDATE orders
+		SET status = $1, reason = NULLIF($2, ''), reserved_cash = 0, reserved_shares = 0, updated_at = NOW()
+		WHERE id = $3`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, status, reason, orderID)
+	if err != nil {
+		return fmt.Errorf("error closing order: %v", err)
+	}
+	return nil
+}
+
+// AmendOrder stores new shares, prices, trigger, time in force and reservation for a pending order
+func (tx *Tx) AmendOrder(order *models.Order) error {
+	query := `
+		UPDATE orders
+		SET shares = $1, limit_price = $2, stop_price = $3, triggered = $4, time_in_force = $5, expires_at = $6,
+			reserved_cash = $7, reserved_shares = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at`
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, order.Shares, order.LimitPrice, order.StopPrice, order.Triggered,
+		order.TimeInForce, order.ExpiresAt, order.ReservedCash, order.ReservedShares, order.ID).Scan(&order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error amending order: %v", err)
+	}
+	return nil
+}
+
+// RecordOrderEvent appends an entry to the order's audit trail
+func (tx *Tx) RecordOrderEvent(orderID int, fromStatus string, toStatus string, reason string) error {
+	query := `
+		INSERT INTO order_events (order_id, from_status, to_status, reason)
+		VALUES ($1, NULLIF($2, ''), $3, $4)`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, orderID, fromStatus, toStatus, reason)
+	if err != nil {
+		return fmt.Errorf("error recording order event: %v", err)
+	}
+	return nil
+}
+
+// GetOrderEvents returns an order's audit trail, oldest first
+func (db *DB) GetOrderEvents(orderID int) ([]models.OrderEvent, error) {
+	query := `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, reason, created_at
+		FROM order_events
+		WHERE order_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := db.conn.Query(query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.OrderEvent{}
+	for rows.Next() {
+		var event models.OrderEvent
+		err := rows.Scan(&event.ID, &event.OrderID, &event.FromStatus, &event.ToStatus, &event.Reason, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetUserOrders returns a user's orders, newest first
+func (db *DB) GetUserOrders(userID string) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	return scanOrders(rows)
+}
+
+// GetUserOrder returns one of a user's orders; returns nil if it does not exist
+func (db *DB) GetUserOrder(userID string, orderID int) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 AND user_id = $2`
+
+	order, err := scanOrder(db.conn.QueryRow(query, orderID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+// GetOrdersByStatus returns all orders in the given statuses, oldest first
+func (db *DB) GetOrdersByStatus(statuses ...string) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE status = ANY($1) ORDER BY created_at`
+
+	rows, err := db.conn.Query(query, pq.Array(statuses))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open orders: %w", err)
+	}
+	return scanOrders(rows)
+}
+
+// GetExpiredOrderIDs returns open orders whose time-in-force has run out
+func (db *DB) GetExpiredOrderIDs(now time.Time, statuses ...string) ([]int, error) {
+	query := `SELECT id FROM orders WHERE status = ANY($1) AND expires_at IS NOT NULL AND expires_at <= $2`
+
+	rows, err := db.conn.Query(query, pq.Array(statuses), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan order id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RecordDailyPrice stores a symbol's closing price for a date, replacing any earlier one
+func (db *DB) RecordDailyPrice(symbol string, date time.Time, price decimal.Decimal) error {
+	query := `
+		INSERT INTO price_history (symbol, price_date, price)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (symbol, price_date) DO UPDATE SET price = EXCLUDED.price`
+
+	_, err := db.conn.Exec(query, symbol, date, price)
+	if err != nil {
+		return fmt.Errorf("error recording price history: %v", err)
+	}
+	return nil
+}
+
+// GetPriceRange returns a symbol's first and last recorded prices between from and to
+// (inclusive dates). Both are invalid when no prices were recorded.
+func (db *DB) GetPriceRange(symbol string, from time.Time, to time.Time) (decimal.NullDecimal, decimal.NullDecimal, error) {
+	query := `
+		SELECT
+			(SELECT price FROM price_history WHERE symbol = $1 AND price_date BETWEEN $2 AND $3 ORDER BY price_date LIMIT 1),
+			(SELECT price FROM price_history WHERE symbol = $1 AND price_date BETWEEN $2 AND $3 ORDER BY price_date DESC LIMIT 1)`
+
+	var first, last decimal.NullDecimal
+	err := db.conn.QueryRow(query, symbol, from, to).Scan(&first, &last)
+	if err != nil {
+		return first, last, fmt.Errorf("error getting price history: %v", err)
+	}
+	return first, last, nil
+}
+
+// SplitStockPrice divides a symbol's fallback price by a split's ratio
+func (tx *Tx) SplitStockPrice(symbol string, ratio decimal.Decimal) error {
+	_, err := tx.tx.ExecContext(tx.ctx, "UPDATE stock_prices SET price = ROUND(price / $2, 2), updated_at = NOW() WHERE symbol = $1", symbol, ratio)
+	if err != nil {
+		return fmt.Errorf("error splitting stock price: %v", err)
+	}
+	return nil
+}
+
+// RenameStockPrice moves a symbol's fallback price to newSymbol unless newSymbol already has one
+func (tx *Tx) RenameStockPrice(symbol string, newSymbol string) error {
+	query := `
+		UPDATE stock_prices SET symbol = $2, updated_at = NOW()
+		WHERE symbol = $1 AND NOT EXISTS (SELECT 1 FROM stock_prices WHERE symbol = $2)`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, symbol, newSymbol)
+	if err != nil {
+		return fmt.Errorf("error renaming stock price: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// SaveSnapshot stores a portfolio snapshot, replacing any earlier one for the same user and date
+func (tx *Tx) SaveSnapshot(snapshot *models.PortfolioSnapshot) error {
+	query := `
+		INSERT INTO portfolio_snapshots (user_id, snapshot_date, total_value, cash, holdings_value)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, snapshot_date)
+		DO UPDATE SET total_value = EXCLUDED.total_value, cash = EXCLUDED.cash,
+			holdings_value = EXCLUDED.holdings_value, created_at = NOW()
+		RETURNING id, created_at`
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, snapshot.UserID, snapshot.Date, snapshot.TotalValue, snapshot.Cash,
+		snapshot.HoldingsValue).Scan(&snapshot.ID, &snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving snapshot: %v", err)
+	}
+
+	_, err = tx.tx.ExecContext(tx.ctx, "DELETE FROM snapshot_holdings WHERE snapshot_id = $1", snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("error clearing snapshot holdings: %v", err)
+	}
+
+	for _, holding := range snapshot.Holdings {
+		query := `
+			INSERT INTO snapshot_holdings (snapshot_id, symbol, shares, price, value)
+			VALUES ($1, $2, $3, $4, $5)`
+
+		_, err = tx.tx.ExecContext(tx.ctx, query, snapshot.ID, holding.Symbol, holding.Shares, holding.Price, holding.Value)
+		if err != nil {
+			return fmt.Errorf("error saving snapshot holding: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetSnapshots returns a user's snapshots between from and to (inclusive dates), keeping the
+// last snapshot of each interval ("day", "week" or "month"), oldest first
+func (db *DB) GetSnapshots(userID string, from time.Time, to time.Time, interval string) ([]models.PortfolioSnapshot, error) {
+	query := `
+		SELECT id, user_id, snapshot_date, total_value, cash, holdings_value, created_at
+		FROM (
+			SELECT DISTINCT ON (date_trunc($4::text, snapshot_date::timestamp)) *
+			FROM portfolio_snapshots
+			WHERE user_id = $1 AND snapshot_date BETWEEN $2 AND $3
+			ORDER BY date_trunc($4::text, snapshot_date::timestamp), snapshot_date DESC
+		) latest
+		ORDER BY snapshot_date`
+
+	rows, err := db.conn.Query(query, userID, from, to, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.PortfolioSnapshot{}
+	index := make(map[int]int)
+	var ids []int64
+	for rows.Next() {
+		var snapshot models.PortfolioSnapshot
+		err := rows.Scan(&snapshot.ID, &snapshot.UserID, &snapshot.Date, &snapshot.TotalValue, &snapshot.Cash,
+			&snapshot.HoldingsValue, &snapshot.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshot.Holdings = []models.SnapshotHolding{}
+		index[snapshot.ID] = len(snapshots)
+		ids = append(ids, int64(snapshot.ID))
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	holdingRows, err := db.conn.Query(`
+		SELECT snapshot_id, symbol, shares, price, value
+		FROM snapshot_holdings
+		WHERE snapshot_id = ANY($1)
+		ORDER BY snapshot_id, symbol`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot holdings: %w", err)
+	}
+	defer holdingRows.Close()
+
+	for holdingRows.Next() {
+		var snapshotID int
+		var holding models.SnapshotHolding
+		err := holdingRows.Scan(&snapshotID, &holding.Symbol, &holding.Shares, &holding.Price, &holding.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot holding: %w", err)
+		}
+		i := index[snapshotID]
+		snapshots[i].Holdings = append(snapshots[i].Holdings, holding)
+	}
+	return snapshots, holdingRows.Err()
+}
+
+// GetLastSnapshotValue returns the total value of a user's latest snapshot dated before
+// date; invalid when they have none
+func (db *DB) GetLastSnapshotValue(userID string, date time.Time) (decimal.NullDecimal, error) {
+	query := `
+		SELECT total_value FROM portfolio_snapshots
+		WHERE user_id = $1 AND snapshot_date < $2
+		ORDER BY snapshot_date DESC
+		LIMIT 1`
+
+	var value decimal.NullDecimal
+	err := db.conn.QueryRow(query, userID, date).Scan(&value)
+	if err == sql.ErrNoRows {
+		return value, nil
+	}
+	if err != nil {
+		return value, fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+	return value, nil
+}
+
+// GetUserIDs returns the IDs of all users, for jobs that run across every portfolio
+func (db *DB) GetUserIDs() ([]string, error) {
+	rows, err := db.conn.Query("SELECT id FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
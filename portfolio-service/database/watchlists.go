@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"portfolio-service/models"
+
+	"github.com/lib/pq"
+)
+
+const watchlistColumns = `id, name, created_at`
+
+func scanWatchlist(row rowScanner) (*models.Watchlist, error) {
+	watchlist := models.Watchlist{Symbols: []string{}}
+	if err := row.Scan(&watchlist.ID, &watchlist.Name, &watchlist.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+const watchlistSymbolsQuery = `
+	SELECT watchlist_id, symbol
+	FROM watchlist_symbols
+	WHERE watchlist_id = ANY($1)
+	ORDER BY watchlist_id, added_at, symbol`
+
+// attachWatchlistSymbols reads watchlist_symbols rows into the watchlists they belong to, in the order added
+func attachWatchlistSymbols(rows *sql.Rows, watchlists []*models.Watchlist) error {
+	defer rows.Close()
+
+	byID := make(map[int]*models.Watchlist, len(watchlists))
+	for _, watchlist := range watchlists {
+		byID[watchlist.ID] = watchlist
+	}
+	for rows.Next() {
+		var id int
+		var symbol string
+		if err := rows.Scan(&id, &symbol); err != nil {
+			return fmt.Errorf("failed to scan watchlist symbol: %w", err)
+		}
+		if watchlist, ok := byID[id]; ok {
+			watchlist.Symbols = append(watchlist.Symbols, symbol)
+		}
+	}
+	return rows.Err()
+}
+
+// GetUserWatchlists returns a user's watchlists with their symbols, by name
+func (db *DB) GetUserWatchlists(userID string) ([]models.Watchlist, error) {
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists WHERE user_id = $1 ORDER BY name`
+
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	var watchlists []*models.Watchlist
+	var ids []int64
+	for rows.Next() {
+		watchlist, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		watchlists = append(watchlists, watchlist)
+		ids = append(ids, int64(watchlist.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	symbolRows, err := db.conn.Query(watchlistSymbolsQuery, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist symbols: %w", err)
+	}
+	if err := attachWatchlistSymbols(symbolRows, watchlists); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Watchlist, len(watchlists))
+	for i, watchlist := range watchlists {
+		result[i] = *watchlist
+	}
+	return result, nil
+}
+
+// GetWatchlist returns one of a user's watchlists with its symbols, or nil if they have no such watchlist
+func (db *DB) GetWatchlist(userID string, watchlistID int) (*models.Watchlist, error) {
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists WHERE id = $1 AND user_id = $2`
+
+	watchlist, err := scanWatchlist(db.conn.QueryRow(query, watchlistID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	rows, err := db.conn.Query(watchlistSymbolsQuery, pq.Array([]int64{int64(watchlistID)}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist symbols: %w", err)
+	}
+	if err := attachWatchlistSymbols(rows, []*models.Watchlist{watchlist}); err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// GetWatchlistForUpdate reads one of a user's watchlists with its symbols and locks its row;
+// returns nil if they have no such watchlist
+func (tx *Tx) GetWatchlistForUpdate(userID string, watchlistID int) (*models.Watchlist, error) {
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists WHERE id = $1 AND user_id = $2 FOR UPDATE`
+
+	watchlist, err := scanWatchlist(tx.tx.QueryRowContext(tx.ctx, query, watchlistID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting watchlist: %v", err)
+	}
+
+	rows, err := tx.tx.QueryContext(tx.ctx, watchlistSymbolsQuery, pq.Array([]int64{int64(watchlistID)}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist symbols: %w", err)
+	}
+	if err := attachWatchlistSymbols(rows, []*models.Watchlist{watchlist}); err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// CreateWatchlist stores a new, empty watchlist and fills in its ID and creation time.
+// Returns false when the user already has a watchlist with that name.
+func (tx *Tx) CreateWatchlist(userID string, watchlist *models.Watchlist) (bool, error) {
+	query := `
+		INSERT INTO watchlists (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, created_at`
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, userID, watchlist.Name).Scan(&watchlist.ID, &watchlist.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error creating watchlist: %v", err)
+	}
+	return true, nil
+}
+
+// AddWatchlistSymbol adds symbol to a watchlist; adding a symbol already there does nothing
+func (tx *Tx) AddWatchlistSymbol(watchlistID int, symbol string) error {
+	query := `
+		INSERT INTO watchlist_symbols (watchlist_id, symbol)
+		VALUES ($1, $2)
+		ON CONFLICT (watchlist_id, symbol) DO NOTHING`
+
+	_, err := tx.tx.ExecContext(tx.ctx, query, watchlistID, symbol)
+	if err != nil {
+		return fmt.Errorf("error adding watchlist symbol: %v", err)
+	}
+	return nil
+}
+
+// RenameWatchlist renames a watchlist the caller has locked. Returns false when the user
+// already has another watchlist with that name.
+func (tx *Tx) RenameWatchlist(userID string, watchlistID int, name string) (bool, error) {
+	query := `
+		UPDATE watchlists SET name = $3
+		WHERE id = $2 AND user_id = $1
+			AND NOT EXISTS (SELECT 1 FROM watchlists WHERE user_id = $1 AND name = $3 AND id <> $2)`
+
+	result, err := tx.tx.ExecContext(tx.ctx, query, userID, watchlistID, name)
+	if err != nil {
+		return false, fmt.Errorf("error renaming watchlist: %v", err)
+	}
+	renamed, err := result.RowsAffected()
+	return renamed > 0, err
+}
+
+// DeleteWatchlist removes one of a user's watchlists and its symbols; returns false if they have no such watchlist
+func (db *DB) DeleteWatchlist(userID string, watchlistID int) (bool, error) {
+	result, err := db.conn.Exec("DELETE FROM watchlists WHERE id = $1 AND user_id = $2", watchlistID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// RemoveWatchlistSymbol removes symbol from one of a user's watchlists; returns false if it was not there
+func (db *DB) RemoveWatchlistSymbol(userID string, watchlistID int, symbol string) (bool, error) {
+	query := `
+		DELETE FROM watchlist_symbols ws
+		USING watchlists w
+		WHERE w.id = ws.watchlist_id AND w.id = $1 AND w.user_id = $2 AND ws.symbol = $3`
+
+	result, err := db.conn.Exec(query, watchlistID, userID, symbol)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove watchlist symbol: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
@@ -0,0 +1,100 @@
+// Package events is an in-process publish/subscribe bus that carries per-user
+// portfolio updates from the services to streaming clients
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypePortfolio   = "portfolio"   // Data is a *models.Portfolio revaluation
+	TypeOrder       = "order"       // Data is the *models.Order after a change of status or terms
+	TypeTransaction = "transaction" // Data is the *models.TradeResult of an executed trade
+)
+
+// Event is one update for a single user
+type Event struct {
+	Type   string      `json:"type"`
+	UserID string      `json:"-"`
+	Data   interface{} `json:"data"`
+	Time   time.Time   `json:"time"`
+}
+
+// Bus fans events out to the subscribers of the user they belong to. Publishing never
+// blocks: a subscriber whose buffer is full misses the event.
+type Bus struct {
+	mu          sync.Mutex
+	buffer      int
+	closed      bool
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewBus creates a bus whose subscribers buffer up to buffer events each
+func NewBus(buffer int) *Bus {
+	return &Bus{
+		buffer:      buffer,
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving userID's events and a function that ends the
+// subscription. The channel is closed when the subscription ends or the bus closes.
+func (b *Bus) Subscribe(userID string) (<-chan Event, func()) {
+	ch := make(chan Event, b.buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[userID][ch]; !ok {
+			return
+		}
+		delete(b.subscribers[userID], ch)
+		if len(b.subscribers[userID]) == 0 {
+			delete(b.subscribers, userID)
+		}
+		close(ch)
+	}
+}
+
+// Publish delivers event to every current subscriber of event.UserID
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription and makes later ones end immediately, so that open
+// streams finish during shutdown
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for userID, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(b.subscribers, userID)
+	}
+}
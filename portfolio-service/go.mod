@@ -6,10 +6,11 @@ require (
 	github.com/FUNfarik/finance_microservices/proto/go v0.0.0-00010101000000-000000000000
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/lib/pq v1.10.9
+	github.com/shopspring/decimal v1.4.0
 	google.golang.org/grpc v1.75.0
 )
 
-require google.golang.org/protobuf v1.36.8 // indirect
+require google.golang.org/protobuf v1.36.8
 
 require (
 	golang.org/x/net v0.41.0 // indirect
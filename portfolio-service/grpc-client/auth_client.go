@@ -0,0 +1,55 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/FUNfarik/finance_microservices/proto/go/auth"
+)
+
+type AuthClient struct {
+	conn   *grpc.ClientConn
+	client pb.AuthServiceClient
+}
+
+// ConnectAuth establishes gRPC connection to Auth Service
+func ConnectAuth() (*AuthClient, error) {
+	grpcAddr := os.Getenv("AUTH_GRPC_URL")
+	if grpcAddr == "" {
+		grpcAddr = "localhost:8006" // fallback for local development
+	}
+
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
+	}
+
+	fmt.Printf("Connected to Auth Service via gRPC at %s\n", grpcAddr)
+	return &AuthClient{
+		conn:   conn,
+		client: pb.NewAuthServiceClient(conn),
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *AuthClient) Close() error {
+	return c.conn.Close()
+}
+
+// VerifyToken asks Auth Service to validate a JWT and returns the user it belongs to
+func (c *AuthClient) VerifyToken(ctx context.Context, token string) (int32, string, error) {
+	response, err := c.client.VerifyToken(ctx, &pb.VerifyTokenRequest{Token: token})
+	if err != nil {
+		return 0, "", fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	if !response.Valid {
+		return 0, "", fmt.Errorf("invalid token: %s", response.ErrorMessage)
+	}
+
+	return response.UserId, response.Username, nil
+}
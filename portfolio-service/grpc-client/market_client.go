@@ -2,6 +2,7 @@ package grpcclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,6 +12,9 @@ import (
 	pb "github.com/FUNfarik/finance_microservices/proto/go/market"
 )
 
+// ErrServiceError is returned when the market data service answers but cannot quote a symbol
+var ErrServiceError = errors.New("service error")
+
 type MarketClient struct {
 	conn   *grpc.ClientConn
 	client pb.MarketDataServiceClient
@@ -55,7 +59,7 @@ func (c *MarketClient) GetStockPrice(ctx context.Context, symbol string) (float6
 	}
 
 	if !response.Success {
-		return 0, "", fmt.Errorf("service error: %s", response.ErrorMessage)
+		return 0, "", fmt.Errorf("%w: %s", ErrServiceError, response.ErrorMessage)
 	}
 
 	fmt.Printf("Retrieved price for %s (%s): $%.2f\n", symbol, response.Name, response.CurrentPrice)
@@ -88,8 +92,12 @@ func (c *MarketClient) GetMultipleStockPrices(ctx context.Context, symbols []str
 	return prices, nil
 }
 
-// ValidateSymbol checks if a stock symbol exists
+// ValidateSymbol checks if a stock symbol exists. It only returns an error when the
+// market data service cannot be reached, so callers can tell that apart from a bad symbol.
 func (c *MarketClient) ValidateSymbol(ctx context.Context, symbol string) (bool, error) {
 	_, _, err := c.GetStockPrice(ctx, symbol)
-	return err == nil, nil
+	if errors.Is(err, ErrServiceError) {
+		return false, nil
+	}
+	return err == nil, err
 }
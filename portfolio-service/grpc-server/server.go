@@ -0,0 +1,380 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"portfolio-service/auth"
+	"portfolio-service/events"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"portfolio-service/services"
+
+	pb "github.com/FUNfarik/finance_microservices/proto/go/portfolio"
+)
+
+const dateLayout = "2006-01-02"
+
+// PortfolioServer exposes services.PortfolioService over gRPC
+type PortfolioServer struct {
+	pb.UnimplementedPortfolioServiceServer
+	portfolioService *services.PortfolioService
+	tokenVerifier    auth.TokenVerifier
+}
+
+// NewPortfolioServer creates a new gRPC server instance
+func NewPortfolioServer(portfolioService *services.PortfolioService, tokenVerifier auth.TokenVerifier) *PortfolioServer {
+	return &PortfolioServer{
+		portfolioService: portfolioService,
+		tokenVerifier:    tokenVerifier,
+	}
+}
+
+// Addr returns the address the gRPC server listens on
+func Addr() string {
+	port := os.Getenv("PORTFOLIO_GRPC_PORT")
+	if port == "" {
+		port = "8007"
+	}
+	return ":" + port
+}
+
+// Start listens on Addr and serves PortfolioService in the background
+func Start(portfolioServer *PortfolioServer) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", Addr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", Addr(), err)
+	}
+
+	server := grpc.NewServer()
+	pb.RegisterPortfolioServiceServer(server, portfolioServer)
+
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			fmt.Printf("gRPC server stopped: %v\n", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// userIDFromToken verifies the token carried in every request
+func (s *PortfolioServer) userIDFromToken(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("token is required")
+	}
+	claims, err := s.tokenVerifier.Verify(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("authentication failed: %v", err)
+	}
+	return claims.UserID, nil
+}
+
+// requestShares returns a request's fractional quantity when set, otherwise its whole shares.
+// Like over HTTP, the quantity must be positive with at most money.SharePlaces decimals.
+func requestShares(shares int32, quantity float64) (decimal.Decimal, error) {
+	if quantity == 0 {
+		return decimal.NewFromInt(int64(shares)), nil
+	}
+	if math.IsNaN(quantity) || math.IsInf(quantity, 0) {
+		return decimal.Zero, fmt.Errorf("%w: %v", services.ErrInvalidShares, quantity)
+	}
+	parsed := decimal.NewFromFloat(quantity)
+	if !money.ValidShares(parsed) {
+		return decimal.Zero, fmt.Errorf("%w: %s", services.ErrInvalidShares, parsed.String())
+	}
+	return parsed, nil
+}
+
+// requestAmount returns a request's dollar amount, which must be finite; the service
+// checks that it is positive and in whole cents
+func requestAmount(amount float64) (decimal.Decimal, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return decimal.Zero, services.ErrInvalidAmount
+	}
+	return decimal.NewFromFloat(amount), nil
+}
+
+// BuyStock processes a stock purchase
+func (s *PortfolioServer) BuyStock(ctx context.Context, req *pb.BuyStockRequest) (*pb.BuyStockResponse, error) {
+	userID, err := s.userIDFromToken(ctx, req.Token)
+	if err != nil {
+		return &pb.BuyStockResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	response := &pb.BuyStockResponse{}
+	err = s.idempotent(ctx, userID, req.IdempotencyKey, "BuyStock", req, response, func() (proto.Message, error) {
+		var result *models.TradeResult
+		var err error
+		if req.Amount != 0 {
+			var amount decimal.Decimal
+			if amount, err = requestAmount(req.Amount); err == nil {
+				result, err = s.portfolioService.BuyStockAmount(ctx, userID, req.Symbol, amount)
+			}
+		} else {
+			var shares decimal.Decimal
+			if shares, err = requestShares(req.Shares, req.Quantity); err == nil {
+				result, err = s.portfolioService.BuyStock(ctx, userID, req.Symbol, shares)
+			}
+		}
+		if err != nil {
+			return &pb.BuyStockResponse{Success: false, ErrorMessage: err.Error()}, err
+		}
+
+		return &pb.BuyStockResponse{
+			Success:       true,
+			TransactionId: strconv.Itoa(result.TransactionID),
+			TotalCost:     result.TotalAmount.Add(result.Fee).InexactFloat64(),
+			RemainingCash: result.RemainingCash.InexactFloat64(),
+			Fee:           result.Fee.InexactFloat64(),
+			Quantity:      result.Shares.InexactFloat64(),
+		}, nil
+	})
+	if err != nil {
+		return &pb.BuyStockResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return response, nil
+}
+
+// SellStock processes a stock sale
+func (s *PortfolioServer) SellStock(ctx context.Context, req *pb.SellStockRequest) (*pb.SellStockResponse, error) {
+	userID, err := s.userIDFromToken(ctx, req.Token)
+	if err != nil {
+		return &pb.SellStockResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	response := &pb.SellStockResponse{}
+	err = s.idempotent(ctx, userID, req.IdempotencyKey, "SellStock", req, response, func() (proto.Message, error) {
+		lotIDs := make([]int, len(req.LotIds))
+		for i, id := range req.LotIds {
+			lotIDs[i] = int(id)
+		}
+
+		shares, err := requestShares(req.Shares, req.Quantity)
+		if err != nil {
+			return &pb.SellStockResponse{Success: false, ErrorMessage: err.Error()}, err
+		}
+		result, err := s.portfolioService.SellStock(ctx, userID, req.Symbol, shares, lotIDs)
+		if err != nil {
+			return &pb.SellStockResponse{Success: false, ErrorMessage: err.Error()}, err
+		}
+
+		return &pb.SellStockResponse{
+			Success:       true,
+			TransactionId: strconv.Itoa(result.TransactionID),
+			TotalReceived: result.TotalAmount.Sub(result.Fee).InexactFloat64(),
+			RemainingCash: result.RemainingCash.InexactFloat64(),
+			RealizedGain:  result.RealizedGain.Decimal.InexactFloat64(),
+			Fee:           result.Fee.InexactFloat64(),
+		}, nil
+	})
+	if err != nil {
+		return &pb.SellStockResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return response, nil
+}
+
+// idempotent runs fn once per idempotency key and decodes the original or replayed
+// response into response. Without a key fn simply runs. fn returns its response and the
+// error behind a failed one; only successes and rejected trades are stored, so a failure
+// a retry may get past releases the key like a 5xx response does over HTTP.
+func (s *PortfolioServer) idempotent(ctx context.Context, userID string, key string, method string,
+	req proto.Message, response proto.Message, fn func() (proto.Message, error)) error {
+	if key == "" {
+		message, _ := fn()
+		proto.Merge(response, message)
+		return nil
+	}
+
+	// Identify the request by method and fields, leaving out the token
+	request := proto.Clone(req)
+	request.ProtoReflect().Clear(request.ProtoReflect().Descriptor().Fields().ByName("token"))
+	requestBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	requestBytes = append([]byte("grpc "+method+"\n"), requestBytes...)
+
+	_, body, _, err := s.portfolioService.Idempotent(ctx, userID, key, requestBytes, func() (int, []byte) {
+		message, tradeErr := fn()
+		encoded, err := proto.Marshal(message)
+		if err != nil {
+			return http.StatusInternalServerError, nil
+		}
+		if tradeErr != nil && !services.IsRejection(tradeErr) {
+			return http.StatusServiceUnavailable, encoded
+		}
+		return http.StatusOK, encoded
+	})
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return fmt.Errorf("failed to encode response")
+	}
+	return proto.Unmarshal(body, response)
+}
+
+// GetPortfolio retrieves a user's portfolio with current prices
+func (s *PortfolioServer) GetPortfolio(ctx context.Context, req *pb.GetPortfolioRequest) (*pb.GetPortfolioResponse, error) {
+	userID, err := s.userIDFromToken(ctx, req.Token)
+	if err != nil {
+		return &pb.GetPortfolioResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	portfolio, err := s.portfolioService.GetPortfolio(ctx, userID)
+	if err != nil {
+		return &pb.GetPortfolioResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return portfolioResponse(portfolio), nil
+}
+
+// portfolioResponse converts a valued portfolio to its gRPC form
+func portfolioResponse(portfolio *models.Portfolio) *pb.GetPortfolioResponse {
+	holdings := make([]*pb.Holding, len(portfolio.Holdings))
+	for i, holding := range portfolio.Holdings {
+		holdings[i] = &pb.Holding{
+			Symbol:       holding.Symbol,
+			Shares:       int32(holding.Shares.IntPart()),
+			Quantity:     holding.Shares.InexactFloat64(),
+			AvgPrice:     holding.AvgPrice.InexactFloat64(),
+			CurrentPrice: holding.CurrentPrice.InexactFloat64(),
+			TotalValue:   holding.TotalValue.InexactFloat64(),
+			GainLoss:     holding.GainLoss.InexactFloat64(),
+		}
+	}
+
+	return &pb.GetPortfolioResponse{
+		Success:       true,
+		Holdings:      holdings,
+		TotalValue:    portfolio.TotalValue.InexactFloat64(),
+		CashBalance:   portfolio.Cash.InexactFloat64(),
+		TotalGainLoss: portfolio.UnrealizedGainLoss.InexactFloat64(),
+
+		RealizedGainLoss:   portfolio.RealizedGainLoss.InexactFloat64(),
+		UnrealizedGainLoss: portfolio.UnrealizedGainLoss.InexactFloat64(),
+	}
+}
+
+// GetPerformance returns return and risk statistics for the user's holdings
+func (s *PortfolioServer) GetPerformance(ctx context.Context, req *pb.GetPerformanceRequest) (*pb.GetPerformanceResponse, error) {
+	userID, err := s.userIDFromToken(ctx, req.Token)
+	if err != nil {
+		return &pb.GetPerformanceResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.To != "" {
+		if to, err = time.Parse(dateLayout, req.To); err != nil {
+			return &pb.GetPerformanceResponse{Success: false, ErrorMessage: "invalid to date, expected YYYY-MM-DD"}, nil
+		}
+	}
+	from := to.AddDate(-1, 0, 0)
+	if req.From != "" {
+		if from, err = time.Parse(dateLayout, req.From); err != nil {
+			return &pb.GetPerformanceResponse{Success: false, ErrorMessage: "invalid from date, expected YYYY-MM-DD"}, nil
+		}
+	}
+
+	performance, err := s.portfolioService.GetPerformance(ctx, userID, from, to)
+	if err != nil {
+		return &pb.GetPerformanceResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	return &pb.GetPerformanceResponse{
+		Success:             true,
+		From:                performance.From.Format(dateLayout),
+		To:                  performance.To.Format(dateLayout),
+		StartValue:          performance.StartValue.InexactFloat64(),
+		EndValue:            performance.EndValue.InexactFloat64(),
+		NetCashFlow:         performance.NetCashFlow.InexactFloat64(),
+		TimeWeightedReturn:  performance.TimeWeightedReturn,
+		MoneyWeightedReturn: performance.MoneyWeightedReturn,
+		MaxDrawdown:         performance.MaxDrawdown,
+		Volatility:          performance.Volatility,
+		SharpeRatio:         performance.SharpeRatio,
+		Benchmark:           performance.Benchmark,
+		BenchmarkReturn:     performance.BenchmarkReturn,
+		ExcessReturn:        performance.ExcessReturn,
+	}, nil
+}
+
+// StreamUpdates streams the user's portfolio revaluations, order changes and executed trades
+// until the client disconnects
+func (s *PortfolioServer) StreamUpdates(req *pb.StreamUpdatesRequest, stream grpc.ServerStreamingServer[pb.PortfolioUpdate]) error {
+	userID, err := s.userIDFromToken(stream.Context(), req.Token)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return s.portfolioService.StreamUpdates(stream.Context(), userID, func(event events.Event) error {
+		update := &pb.PortfolioUpdate{
+			Type:      event.Type,
+			Timestamp: event.Time.Format(time.RFC3339),
+		}
+		switch data := event.Data.(type) {
+		case *models.Portfolio:
+			update.Update = &pb.PortfolioUpdate_Portfolio{Portfolio: portfolioResponse(data)}
+		case *models.Order:
+			update.Update = &pb.PortfolioUpdate_Order{Order: orderUpdate(data)}
+		case *models.TradeResult:
+			update.Update = &pb.PortfolioUpdate_Transaction{Transaction: transactionUpdate(data)}
+		default:
+			return nil
+		}
+		return stream.Send(update)
+	})
+}
+
+// optionalFloat converts a nullable decimal to an optional proto double
+func optionalFloat(value decimal.NullDecimal) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	f := value.Decimal.InexactFloat64()
+	return &f
+}
+
+// orderUpdate converts an order to its gRPC form
+func orderUpdate(order *models.Order) *pb.OrderUpdate {
+	return &pb.OrderUpdate{
+		OrderId:        int32(order.ID),
+		Symbol:         order.Symbol,
+		Side:           order.Side,
+		OrderType:      order.OrderType,
+		Status:         order.Status,
+		Quantity:       order.Shares.InexactFloat64(),
+		FilledQuantity: order.FilledShares.InexactFloat64(),
+		LimitPrice:     optionalFloat(order.LimitPrice),
+		StopPrice:      optionalFloat(order.StopPrice),
+		FilledPrice:    optionalFloat(order.FilledPrice),
+		Reason:         order.Reason,
+	}
+}
+
+// transactionUpdate converts a completed trade to its gRPC form
+func transactionUpdate(result *models.TradeResult) *pb.TransactionUpdate {
+	return &pb.TransactionUpdate{
+		TransactionId:   strconv.Itoa(result.TransactionID),
+		TransactionType: result.TransactionType,
+		Symbol:          result.Symbol,
+		Quantity:        result.Shares.InexactFloat64(),
+		Price:           result.Price.InexactFloat64(),
+		TotalAmount:     result.TotalAmount.InexactFloat64(),
+		Fee:             result.Fee.InexactFloat64(),
+		RemainingCash:   result.RemainingCash.InexactFloat64(),
+		RealizedGain:    optionalFloat(result.RealizedGain),
+	}
+}
@@ -0,0 +1,38 @@
+package grpcserver
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"portfolio-service/services"
+)
+
+func TestRequestShares(t *testing.T) {
+	tests := []struct {
+		shares   int32
+		quantity float64
+		want     string
+	}{
+		{3, 0, "3"},
+		{0, 0.25, "0.25"},
+		{3, 1.5, "1.5"}, // quantity wins over whole shares
+		{0, 0.000001, "0.000001"},
+	}
+	for _, tt := range tests {
+		got, err := requestShares(tt.shares, tt.quantity)
+		if err != nil {
+			t.Errorf("requestShares(%d, %v) failed: %v", tt.shares, tt.quantity, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("requestShares(%d, %v) = %s, want %s", tt.shares, tt.quantity, got, tt.want)
+		}
+	}
+
+	for _, quantity := range []float64{0.0000001, 1.2345678, -1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := requestShares(0, quantity); !errors.Is(err, services.ErrInvalidShares) {
+			t.Errorf("requestShares(0, %v) = %v, want ErrInvalidShares", quantity, err)
+		}
+	}
+}
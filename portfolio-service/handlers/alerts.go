@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-service/models"
+	"portfolio-service/services"
+	"strconv"
+	"strings"
+)
+
+// AlertsHandler lists (GET) or creates (POST) the user's price and portfolio alerts:
+// POST /alerts {"alert_type": "PRICE_ABOVE", "symbol": "AAPL", "threshold": "250", "channel": "WEBHOOK", "target": "https://..."}
+func (h *Handlers) AlertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.Method == "GET" {
+		alerts, err := h.portfolioService.GetAlerts(r.Context(), userID)
+		if err != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Failed to get alerts: %v", err),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.APIResponse{
+			Status:  "success",
+			Message: "Alerts retrieved successfully",
+			Data:    alerts,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var alertReq models.AlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&alertReq); err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid JSON request",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	alert, err := h.portfolioService.CreateAlert(r.Context(), userID, alertReq)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to create alert: %v", err),
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Alert created successfully",
+		Data:    alert,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// AlertHandler deletes one of the user's alerts: DELETE /alerts/{id}
+func (h *Handlers) AlertHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	alertID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/alerts/"))
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid alert ID",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if err := h.portfolioService.DeleteAlert(r.Context(), userID, alertID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrAlertNotFound) {
+			status = http.StatusNotFound
+		}
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to delete alert: %v", err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Alert deleted successfully",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// NotificationsHandler lists the user's in-app inbox, newest first (?unread=true for unread only)
+func (h *Handlers) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	notifications, err := h.portfolioService.GetNotifications(r.Context(), userID, unreadOnly)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get notifications: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Notifications retrieved successfully",
+		Data:    notifications,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// NotificationHandler marks an inbox message as read: POST /notifications/{id}/read
+func (h *Handlers) NotificationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/notifications/")
+	idPart, ok := strings.CutSuffix(path, "/read")
+	notificationID, err := strconv.Atoi(idPart)
+	if !ok || err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid notification path, expected /notifications/{id}/read",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if err := h.portfolioService.MarkNotificationRead(r.Context(), userID, notificationID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			status = http.StatusNotFound
+		}
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to mark notification read: %v", err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Notification marked as read",
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-service/models"
+	"portfolio-service/services"
+)
+
+// DepositHandler adds money to the user's cash balance: POST /cash/deposit {"amount": "100.00"}
+func (h *Handlers) DepositHandler(w http.ResponseWriter, r *http.Request) {
+	h.cashMovement(w, r, "Deposit", h.portfolioService.Deposit)
+}
+
+// WithdrawHandler takes money out of the user's cash balance: POST /cash/withdraw {"amount": "100.00"}
+func (h *Handlers) WithdrawHandler(w http.ResponseWriter, r *http.Request) {
+	h.cashMovement(w, r, "Withdrawal", h.portfolioService.Withdraw)
+}
+
+func (h *Handlers) cashMovement(w http.ResponseWriter, r *http.Request, name string,
+	move func(ctx context.Context, userID string, req models.CashRequest) (*models.LedgerEntry, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var cashReq models.CashRequest
+	if err := json.NewDecoder(r.Body).Decode(&cashReq); err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid JSON request",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	entry, err := move(r.Context(), userID, cashReq)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAmount) || errors.Is(err, services.ErrInsufficientFunds) {
+			status = http.StatusBadRequest
+		}
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("%s failed: %v", name, err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s completed successfully", name),
+		Data:    entry,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// CashLedgerHandler returns the user's cash ledger and whether it agrees with their balance
+func (h *Handlers) CashLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	ledger, err := h.portfolioService.GetCashLedger(r.Context(), userID)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get cash ledger: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Cash ledger retrieved successfully",
+		Data:    ledger,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// JournalHandler returns the user's balances computed from journal postings and their recent entries
+func (h *Handlers) JournalHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	journal, err := h.portfolioService.GetJournal(r.Context(), userID)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get journal: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Journal retrieved successfully",
+		Data:    journal,
+	}
+	json.NewEncoder(w).Encode(response)
+}
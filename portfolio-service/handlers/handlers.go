@@ -3,28 +3,30 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"portfolio-service/auth"
 	"portfolio-service/models"
 	"portfolio-service/services"
 	"strconv"
 	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type Handlers struct {
 	portfolioService *services.PortfolioService
+	tokenVerifier    auth.TokenVerifier
 }
 
 // NewHandlers creates a new handlers instance
-func NewHandlers(portfolioService *services.PortfolioService) *Handlers {
+func NewHandlers(portfolioService *services.PortfolioService, tokenVerifier auth.TokenVerifier) *Handlers {
 	return &Handlers{
 		portfolioService: portfolioService,
+		tokenVerifier:    tokenVerifier,
 	}
 }
 
-// extractUserIDFromToken extracts user ID from JWT token
+// extractUserIDFromToken verifies the bearer token and returns its user ID
 func (h *Handlers) extractUserIDFromToken(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -36,33 +38,12 @@ func (h *Handlers) extractUserIDFromToken(r *http.Request) (string, error) {
 		return "", fmt.Errorf("bearer token missing")
 	}
 
-	// Parse token without verification (since we trust the auth service)
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	claims, err := h.tokenVerifier.Verify(r.Context(), tokenString)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse token: %v", err)
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", fmt.Errorf("invalid token claims")
-	}
-
-	userID, ok := claims["user_id"]
-	if !ok {
-		return "", fmt.Errorf("user_id not found in token")
+		return "", err
 	}
 
-	// Convert to string
-	switch v := userID.(type) {
-	case float64:
-		return strconv.Itoa(int(v)), nil
-	case int:
-		return strconv.Itoa(v), nil
-	case string:
-		return v, nil
-	default:
-		return "", fmt.Errorf("invalid user_id type in token")
-	}
+	return claims.UserID, nil
 }
 
 // HealthHandler returns service health status
@@ -119,7 +100,20 @@ func (h *Handlers) GetPortfolioHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("✅ Portfolio sent for user %s\n", userID)
 }
 
-// BuyStockHandler processes stock purchases
+// tradeErrorStatus is the HTTP status for a failed trade: 403 until the account's email is
+// confirmed, 400 for other rejected trades and 500 for failures a retry may get past, which
+// also lets WithIdempotency release the key
+func tradeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return http.StatusForbidden
+	case services.IsRejection(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // BuyStockHandler processes stock purchases
 func (h *Handlers) BuyStockHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -153,29 +147,38 @@ func (h *Handlers) BuyStockHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Process buy order using userID from JWT token
-	err = h.portfolioService.BuyStock(context.Background(), userID, buyReq.Symbol, buyReq.Shares)
+	// Process buy order using userID from JWT token; an amount buys that many dollars' worth
+	var result *models.TradeResult
+	if buyReq.Amount.Valid {
+		result, err = h.portfolioService.BuyStockAmount(context.Background(), userID, buyReq.Symbol, buyReq.Amount.Decimal)
+	} else {
+		result, err = h.portfolioService.BuyStock(context.Background(), userID, buyReq.Symbol, buyReq.Shares)
+	}
 	if err != nil {
 		response := models.APIResponse{
 			Status: "error",
 			Error:  fmt.Sprintf("Failed to buy stock: %v", err),
 		}
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(tradeErrorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
 	response := models.APIResponse{
 		Status:  "success",
-		Message: fmt.Sprintf("Successfully bought %d shares of %s", buyReq.Shares, buyReq.Symbol),
+		Message: fmt.Sprintf("Successfully bought %s shares of %s", result.Shares.String(), buyReq.Symbol),
 		Data: map[string]interface{}{
-			"symbol": buyReq.Symbol,
-			"shares": buyReq.Shares,
-			"action": "BUY",
+			"symbol":         buyReq.Symbol,
+			"shares":         result.Shares,
+			"action":         "BUY",
+			"transaction_id": result.TransactionID,
+			"total_amount":   result.TotalAmount,
+			"fee":            result.Fee,
+			"remaining_cash": result.RemainingCash,
 		},
 	}
 	json.NewEncoder(w).Encode(response)
-	fmt.Printf("Buy order: User %s bought %d shares of %s\n", userID, buyReq.Shares, buyReq.Symbol)
+	fmt.Printf("Buy order: User %s bought %s shares of %s\n", userID, result.Shares.String(), buyReq.Symbol)
 }
 
 // SellStockHandler processes stock sales
@@ -212,28 +215,32 @@ func (h *Handlers) SellStockHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process sell order using userID from JWT token
-	err = h.portfolioService.SellStock(context.Background(), userID, sellReq.Symbol, sellReq.Shares)
+	result, err := h.portfolioService.SellStock(context.Background(), userID, sellReq.Symbol, sellReq.Shares, sellReq.LotIDs)
 	if err != nil {
 		response := models.APIResponse{
 			Status: "error",
 			Error:  fmt.Sprintf("Failed to sell stock: %v", err),
 		}
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(tradeErrorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
 	response := models.APIResponse{
 		Status:  "success",
-		Message: fmt.Sprintf("Successfully sold %d shares of %s", sellReq.Shares, sellReq.Symbol),
+		Message: fmt.Sprintf("Successfully sold %s shares of %s", sellReq.Shares.String(), sellReq.Symbol),
 		Data: map[string]interface{}{
-			"symbol": sellReq.Symbol,
-			"shares": sellReq.Shares,
-			"action": "SELL",
+			"symbol":         sellReq.Symbol,
+			"shares":         sellReq.Shares,
+			"action":         "SELL",
+			"transaction_id": result.TransactionID,
+			"total_amount":   result.TotalAmount,
+			"fee":            result.Fee,
+			"remaining_cash": result.RemainingCash,
 		},
 	}
 	json.NewEncoder(w).Encode(response)
-	fmt.Printf("💸 Sell order: User %s sold %d shares of %s\n", userID, sellReq.Shares, sellReq.Symbol)
+	fmt.Printf("💸 Sell order: User %s sold %s shares of %s\n", userID, sellReq.Shares.String(), sellReq.Symbol)
 }
 
 // GetTransactionsHandler retrieves transaction history
@@ -272,3 +279,336 @@ func (h *Handlers) GetTransactionsHandler(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 	fmt.Printf("Transaction history requested for user %s\n", userID)
 }
+
+// OrdersHandler lists the user's orders (GET) or places a new order (POST)
+func (h *Handlers) OrdersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.Method == "GET" {
+		orders, err := h.portfolioService.GetOrders(r.Context(), userID)
+		if err != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Failed to get orders: %v", err),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.APIResponse{
+			Status:  "success",
+			Message: "Orders retrieved successfully",
+			Data:    orders,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var orderReq models.OrderRequest
+	err = json.NewDecoder(r.Body).Decode(&orderReq)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid JSON request",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	order, err := h.portfolioService.PlaceOrder(r.Context(), userID, orderReq)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to place order: %v", err),
+		}
+		w.WriteHeader(tradeErrorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s %s order placed for %s shares of %s", order.OrderType, order.Side, order.Shares.String(), order.Symbol),
+		Data:    order,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+	fmt.Printf("Order placed: User %s order %d\n", userID, order.ID)
+}
+
+// OrderHandler retrieves (GET), amends (PATCH) or cancels (DELETE) a single order at /orders/{id}
+func (h *Handlers) OrderHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "PATCH" && r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	orderID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/orders/"))
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid order ID",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var order *models.Order
+	var message string
+	switch r.Method {
+	case "GET":
+		order, err = h.portfolioService.GetOrder(r.Context(), userID, orderID)
+		if err == nil && order == nil {
+			err = services.ErrOrderNotFound
+		}
+		message = "Order retrieved successfully"
+	case "DELETE":
+		order, err = h.portfolioService.CancelOrder(r.Context(), userID, orderID)
+		message = "Order cancelled successfully"
+	case "PATCH":
+		var amendReq models.AmendOrderRequest
+		if decodeErr := json.NewDecoder(r.Body).Decode(&amendReq); decodeErr != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  "Invalid JSON request",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		order, err = h.portfolioService.AmendOrder(r.Context(), userID, orderID, amendReq)
+		message = "Order amended successfully"
+	}
+
+	if err != nil {
+		status := tradeErrorStatus(err)
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrInvalidOrderTransition):
+			status = http.StatusConflict
+		}
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Order request failed: %v", err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    order,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// AccountHandler shows (GET) or updates (PUT) the user's account type, margin settings and fee schedule
+func (h *Handlers) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var account *models.Account
+	var message string
+	switch r.Method {
+	case "GET":
+		account, err = h.portfolioService.GetAccount(r.Context(), userID)
+		message = "Account retrieved successfully"
+	case "PUT":
+		var accountReq models.AccountRequest
+		if decodeErr := json.NewDecoder(r.Body).Decode(&accountReq); decodeErr != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  "Invalid JSON request",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		account, err = h.portfolioService.UpdateAccount(r.Context(), userID, accountReq)
+		message = "Account updated successfully"
+	}
+
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Account request failed: %v", err),
+		}
+		w.WriteHeader(tradeErrorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    account,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// FeesHandler shows the user's fee schedule, this month's trade volume and the available plans;
+// switch plans with PUT /account {"fee_schedule": "..."}
+func (h *Handlers) FeesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	fees, err := h.portfolioService.GetFees(r.Context(), userID)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get fees: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Fees retrieved successfully",
+		Data:    fees,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// CorporateActionsHandler lists scheduled and processed dividends, splits and ticker renames
+func (h *Handlers) CorporateActionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := h.extractUserIDFromToken(r); err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	actions, err := h.portfolioService.GetCorporateActions(r.Context())
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get corporate actions: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Corporate actions retrieved successfully",
+		Data:    actions,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// LotsHandler lists the user's open tax lots, optionally filtered by ?symbol=
+func (h *Handlers) LotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	lots, err := h.portfolioService.GetLots(r.Context(), userID, r.URL.Query().Get("symbol"))
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get tax lots: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Tax lots retrieved successfully",
+		Data:    lots,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-service/analytics"
+	"portfolio-service/models"
+	"portfolio-service/services"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDate reads an optional YYYY-MM-DD query parameter, returning fallback when it is absent
+func parseDate(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", name, value)
+	}
+	return date, nil
+}
+
+// historyErrorStatus is the HTTP status for a failed history or performance query: 400 when
+// the query cannot be answered as asked, 500 when the snapshots could not be read
+func historyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidInterval), errors.Is(err, services.ErrInvalidDateRange),
+		errors.Is(err, analytics.ErrNotEnoughData):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// PortfolioHistoryHandler returns the user's portfolio value over time:
+// GET /portfolio/history?from=YYYY-MM-DD&to=YYYY-MM-DD&interval=day|week|month
+// The range defaults to the last 30 days and the interval to day.
+func (h *Handlers) PortfolioHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to, err := parseDate(r, "to", today)
+	var from time.Time
+	if err == nil {
+		from, err = parseDate(r, "from", to.AddDate(0, 0, -30))
+	}
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = models.IntervalDay
+	}
+
+	var history []models.PortfolioSnapshot
+	status := http.StatusBadRequest
+	if err == nil {
+		history, err = h.portfolioService.GetHistory(r.Context(), userID, from, to, interval)
+		status = historyErrorStatus(err)
+	}
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get portfolio history: %v", err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Portfolio history retrieved successfully",
+		Data:    history,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// SnapshotHandler takes today's snapshot of the user's portfolio on demand
+func (h *Handlers) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	snapshot, err := h.portfolioService.TakeSnapshot(r.Context(), userID)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to take snapshot: %v", err),
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Snapshot taken successfully",
+		Data:    snapshot,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// PerformanceHandler returns return and risk statistics for the user's holdings:
+// GET /portfolio/performance?from=YYYY-MM-DD&to=YYYY-MM-DD (defaults to the last year)
+func (h *Handlers) PerformanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to, err := parseDate(r, "to", today)
+	var from time.Time
+	if err == nil {
+		from, err = parseDate(r, "from", to.AddDate(-1, 0, 0))
+	}
+
+	var performance *models.Performance
+	status := http.StatusBadRequest
+	if err == nil {
+		performance, err = h.portfolioService.GetPerformance(r.Context(), userID, from, to)
+		status = historyErrorStatus(err)
+	}
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to get performance: %v", err),
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Performance retrieved successfully",
+		Data:    performance,
+	}
+	json.NewEncoder(w).Encode(response)
+}
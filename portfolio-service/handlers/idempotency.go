@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"portfolio-service/models"
+	"portfolio-service/services"
+)
+
+// responseBuffer captures a handler's response so it can be stored and replayed
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// WithIdempotency honours the Idempotency-Key header: the first request with a key
+// runs next and its response is stored; repeats with the same body get the stored
+// response, and repeats with a different body are rejected.
+func (h *Handlers) WithIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method != "POST" {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		userID, err := h.extractUserIDFromToken(r)
+		if err != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Authentication failed: %v", err),
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  "Could not read request body",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// The request is identified by method, path and body; the token is left out so
+		// a retry after refreshing it still matches
+		request := append([]byte(r.Method+" "+r.URL.Path+"\n"), body...)
+
+		status, responseBody, replayed, err := h.portfolioService.Idempotent(r.Context(), userID, key, request, func() (int, []byte) {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			buffer := &responseBuffer{header: w.Header()}
+			next(buffer, r)
+			if buffer.status == 0 {
+				buffer.status = http.StatusOK
+			}
+			return buffer.status, buffer.body.Bytes()
+		})
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, services.ErrIdempotencyKeyReused):
+				status = http.StatusUnprocessableEntity
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				status = http.StatusConflict
+			}
+			response := models.APIResponse{
+				Status: "error",
+				Error:  err.Error(),
+			}
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+		}
+		w.WriteHeader(status)
+		w.Write(responseBody)
+	}
+}
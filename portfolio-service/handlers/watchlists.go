@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-service/models"
+	"portfolio-service/services"
+	"strconv"
+	"strings"
+)
+
+// WatchlistsHandler lists (GET) or creates (POST) the user's watchlists:
+// POST /watchlists {"name": "Tech", "symbols": ["AAPL", "MSFT"]}
+func (h *Handlers) WatchlistsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.Method == "GET" {
+		watchlists, err := h.portfolioService.GetWatchlists(r.Context(), userID)
+		if err != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Failed to get watchlists: %v", err),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.APIResponse{
+			Status:  "success",
+			Message: "Watchlists retrieved successfully",
+			Data:    watchlists,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var watchlistReq models.WatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&watchlistReq); err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid JSON request",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	watchlist, err := h.portfolioService.CreateWatchlist(r.Context(), userID, watchlistReq)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Failed to create watchlist: %v", err),
+		}
+		w.WriteHeader(watchlistErrorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: "Watchlist created successfully",
+		Data:    watchlist,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// WatchlistHandler serves a single watchlist:
+//   - GET /watchlists/{id} returns it with live quotes
+//   - PUT /watchlists/{id} {"name": "..."} renames it
+//   - DELETE /watchlists/{id} deletes it
+//   - POST /watchlists/{id}/symbols {"symbol": "AAPL"} adds a symbol
+//   - DELETE /watchlists/{id}/symbols/{symbol} removes one
+func (h *Handlers) WatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/watchlists/"), "/")
+	var route string
+	switch {
+	case len(parts) == 1 && (r.Method == "GET" || r.Method == "PUT" || r.Method == "DELETE"):
+		route = r.Method
+	case len(parts) == 2 && parts[1] == "symbols" && r.Method == "POST":
+		route = "ADD"
+	case len(parts) == 3 && parts[1] == "symbols" && parts[2] != "" && r.Method == "DELETE":
+		route = "REMOVE"
+	case len(parts) <= 3:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Authentication failed: %v", err),
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	watchlistID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  "Invalid watchlist ID",
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var watchlist *models.Watchlist
+	var message string
+	switch route {
+	case "GET":
+		watchlist, err = h.portfolioService.GetWatchlist(r.Context(), userID, watchlistID)
+		message = "Watchlist retrieved successfully"
+	case "DELETE":
+		err = h.portfolioService.DeleteWatchlist(r.Context(), userID, watchlistID)
+		message = "Watchlist deleted successfully"
+	case "REMOVE":
+		err = h.portfolioService.RemoveWatchlistSymbol(r.Context(), userID, watchlistID, parts[2])
+		message = fmt.Sprintf("%s removed from watchlist", strings.ToUpper(parts[2]))
+	case "PUT":
+		var watchlistReq models.WatchlistRequest
+		if decodeErr := json.NewDecoder(r.Body).Decode(&watchlistReq); decodeErr != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  "Invalid JSON request",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		watchlist, err = h.portfolioService.RenameWatchlist(r.Context(), userID, watchlistID, watchlistReq)
+		message = "Watchlist renamed successfully"
+	case "ADD":
+		var symbolReq models.WatchlistSymbolRequest
+		if decodeErr := json.NewDecoder(r.Body).Decode(&symbolReq); decodeErr != nil {
+			response := models.APIResponse{
+				Status: "error",
+				Error:  "Invalid JSON request",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		watchlist, err = h.portfolioService.AddWatchlistSymbol(r.Context(), userID, watchlistID, symbolReq.Symbol)
+		message = "Symbol added to watchlist"
+	}
+
+	if err != nil {
+		response := models.APIResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Watchlist request failed: %v", err),
+		}
+		w.WriteHeader(watchlistErrorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.APIResponse{
+		Status:  "success",
+		Message: message,
+	}
+	if watchlist != nil {
+		response.Data = watchlist
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// watchlistErrorStatus maps watchlist errors to HTTP statuses; anything else is a bad request
+func watchlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrWatchlistNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWatchlistExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
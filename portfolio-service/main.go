@@ -11,9 +11,12 @@ import (
 	"syscall"
 	"time"
 
+	"portfolio-service/auth"
 	"portfolio-service/database"
 	"portfolio-service/grpc-client"
+	"portfolio-service/grpc-server"
 	"portfolio-service/handlers"
+	"portfolio-service/notify"
 	"portfolio-service/services"
 )
 
@@ -41,8 +44,8 @@ func enableCORS(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Idempotency-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
@@ -56,6 +59,139 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// newTokenVerifier verifies tokens locally against the public keys auth-service publishes
+// at JWKS_URL, checking every request against the revoked_tokens table so logouts take
+// effect at once. TOKEN_VERIFIER=grpc asks the auth-service VerifyToken RPC instead; its
+// answers are cached for a minute, so revocations can take that long to apply.
+func newTokenVerifier(db *database.DB) (auth.TokenVerifier, func(), error) {
+	mode := os.Getenv("TOKEN_VERIFIER")
+	if mode == "" {
+		mode = "local"
+	}
+
+	var verifier auth.TokenVerifier
+	closeFn := func() {}
+
+	switch mode {
+	case "local":
+		jwksURL := os.Getenv("JWKS_URL")
+		if jwksURL == "" {
+			jwksURL = "http://localhost:8001/.well-known/jwks.json"
+		}
+		verifier = auth.NewLocalVerifier(auth.NewJWKS(jwksURL, 5*time.Minute), db, tokenIssuer(), tokenAudience())
+	case "grpc":
+		authClient, err := grpcclient.ConnectAuth()
+		if err != nil {
+			return nil, nil, err
+		}
+		verifier = auth.NewCachingVerifier(auth.NewRemoteVerifier(authClient), time.Minute)
+		closeFn = func() { authClient.Close() }
+	default:
+		return nil, nil, fmt.Errorf("unknown TOKEN_VERIFIER %q", mode)
+	}
+
+	fmt.Printf("Verifying tokens with %s verifier\n", mode)
+	return verifier, closeFn, nil
+}
+
+// tokenIssuer reads JWT_ISSUER, the "iss" auth-service puts in access tokens, defaulting to "auth-service"
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "auth-service"
+}
+
+// tokenAudience reads JWT_AUDIENCE, the "aud" auth-service puts in access tokens, defaulting to "finance-api"
+func tokenAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "finance-api"
+}
+
+// orderPollInterval reads ORDER_POLL_INTERVAL (e.g. "5s"), defaulting to 5 seconds
+func orderPollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("ORDER_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 5 * time.Second
+	}
+	return interval
+}
+
+// snapshotTime reads SNAPSHOT_TIME ("HH:MM" in UTC), defaulting to 21:30 after the US market close
+func snapshotTime() time.Duration {
+	at, err := time.Parse("15:04", os.Getenv("SNAPSHOT_TIME"))
+	if err != nil {
+		return 21*time.Hour + 30*time.Minute
+	}
+	return time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+}
+
+// corporateActionInterval reads CORPORATE_ACTION_INTERVAL (e.g. "1h"), defaulting to one hour
+func corporateActionInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("CORPORATE_ACTION_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Hour
+	}
+	return interval
+}
+
+// alertPollInterval reads ALERT_POLL_INTERVAL (e.g. "30s"), defaulting to 30 seconds
+func alertPollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("ALERT_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * time.Second
+	}
+	return interval
+}
+
+// marginCheckInterval reads MARGIN_CHECK_INTERVAL (e.g. "1h"), defaulting to one hour
+func marginCheckInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("MARGIN_CHECK_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Hour
+	}
+	return interval
+}
+
+// newNotifier delivers alerts to webhooks, the in-app inbox and email. Email goes through
+// SMTP_ADDR when it is set and is printed to the log otherwise.
+func newNotifier(db *database.DB) notify.Notifier {
+	var email notify.Notifier = notify.LogNotifier{}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			from = "alerts@localhost"
+		}
+		email = notify.NewSMTPNotifier(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	}
+
+	return notify.Dispatcher{
+		notify.ChannelInApp:   notify.NewInboxNotifier(db),
+		notify.ChannelWebhook: notify.NewWebhookNotifier(10 * time.Second),
+		notify.ChannelEmail:   email,
+	}
+}
+
+// reconcile checks balances against the journal and exits non-zero when any disagree
+func reconcile(portfolioService *services.PortfolioService) {
+	issues, err := portfolioService.Reconcile(context.Background())
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+	if len(issues) == 0 {
+		fmt.Println("Reconciliation passed: balances agree with the journal")
+		return
+	}
+
+	fmt.Printf("Reconciliation found %d mismatch(es):\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("- user %s %s %s: expected %s, actual %s\n", issue.UserID, issue.Check, issue.Subject, issue.Expected, issue.Actual)
+	}
+	os.Exit(1)
+}
+
 func main() {
 	// Connect to database
 	db, err := database.Connect()
@@ -74,8 +210,29 @@ func main() {
 	// Create portfolio service
 	portfolioService := services.NewPortfolioService(db, marketClient)
 
+	// "portfolio-service reconcile" checks the books and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		reconcile(portfolioService)
+		return
+	}
+
+	// "portfolio-service corporate-actions" applies due corporate actions once and exits
+	if len(os.Args) > 1 && os.Args[1] == "corporate-actions" {
+		if err := portfolioService.ProcessCorporateActions(context.Background()); err != nil {
+			log.Fatalf("Corporate action processing failed: %v", err)
+		}
+		return
+	}
+
+	// Choose how JWTs are verified
+	tokenVerifier, closeVerifier, err := newTokenVerifier(db)
+	if err != nil {
+		log.Fatalf("Failed to set up token verification: %v", err)
+	}
+	defer closeVerifier()
+
 	// Create handlers
-	h := handlers.NewHandlers(portfolioService)
+	h := handlers.NewHandlers(portfolioService, tokenVerifier)
 
 	// Setup HTTP routes with CORS enabled for all endpoints
 	mux := http.NewServeMux()
@@ -87,9 +244,29 @@ func main() {
 	})
 
 	mux.HandleFunc("/portfolio/", h.GetPortfolioHandler)
-	mux.HandleFunc("/buy", h.BuyStockHandler)
-	mux.HandleFunc("/sell", h.SellStockHandler)
+	mux.HandleFunc("/buy", h.WithIdempotency(h.BuyStockHandler))
+	mux.HandleFunc("/sell", h.WithIdempotency(h.SellStockHandler))
 	mux.HandleFunc("/transactions/", h.GetTransactionsHandler)
+	mux.HandleFunc("/orders", h.OrdersHandler)
+	mux.HandleFunc("/orders/", h.OrderHandler)
+	mux.HandleFunc("/account", h.AccountHandler)
+	mux.HandleFunc("/lots", h.LotsHandler)
+	mux.HandleFunc("/fees", h.FeesHandler)
+	mux.HandleFunc("/portfolio/history", h.PortfolioHistoryHandler)
+	mux.HandleFunc("/portfolio/snapshots", h.SnapshotHandler)
+	mux.HandleFunc("/portfolio/performance", h.PerformanceHandler)
+	mux.HandleFunc("/portfolio/stream", h.StreamHandler)
+	mux.HandleFunc("/cash/deposit", h.WithIdempotency(h.DepositHandler))
+	mux.HandleFunc("/cash/withdraw", h.WithIdempotency(h.WithdrawHandler))
+	mux.HandleFunc("/cash/ledger", h.CashLedgerHandler)
+	mux.HandleFunc("/journal", h.JournalHandler)
+	mux.HandleFunc("/corporate-actions", h.CorporateActionsHandler)
+	mux.HandleFunc("/alerts", h.AlertsHandler)
+	mux.HandleFunc("/alerts/", h.AlertHandler)
+	mux.HandleFunc("/notifications", h.NotificationsHandler)
+	mux.HandleFunc("/notifications/", h.NotificationHandler)
+	mux.HandleFunc("/watchlists", h.WatchlistsHandler)
+	mux.HandleFunc("/watchlists/", h.WatchlistHandler)
 
 	// Create HTTP server with CORS middleware applied to all routes
 	server := &http.Server{
@@ -107,18 +284,66 @@ func main() {
 		fmt.Println("- POST /buy")
 		fmt.Println("- POST /sell")
 		fmt.Println("- GET  /transactions/ (requires JWT token)")
+		fmt.Println("- GET  /orders, POST /orders (requires JWT token)")
+		fmt.Println("- GET/PATCH/DELETE /orders/{id} (requires JWT token)")
+		fmt.Println("- GET/PUT /account (requires JWT token)")
+		fmt.Println("- GET  /lots (requires JWT token)")
+		fmt.Println("- GET  /fees (requires JWT token)")
+		fmt.Println("- GET  /portfolio/history?from=&to=&interval= (requires JWT token)")
+		fmt.Println("- POST /portfolio/snapshots (requires JWT token)")
+		fmt.Println("- GET  /portfolio/performance?from=&to= (requires JWT token)")
+		fmt.Println("- GET  /portfolio/stream (Server-Sent Events, requires JWT token or ?token=)")
+		fmt.Println("- POST /cash/deposit, POST /cash/withdraw (requires JWT token)")
+		fmt.Println("- GET  /cash/ledger (requires JWT token)")
+		fmt.Println("- GET  /journal (requires JWT token)")
+		fmt.Println("- GET  /corporate-actions (requires JWT token)")
+		fmt.Println("- GET  /alerts, POST /alerts, DELETE /alerts/{id} (requires JWT token)")
+		fmt.Println("- GET  /notifications, POST /notifications/{id}/read (requires JWT token)")
+		fmt.Println("- GET  /watchlists, POST /watchlists (requires JWT token)")
+		fmt.Println("- GET/PUT/DELETE /watchlists/{id}, POST /watchlists/{id}/symbols, DELETE /watchlists/{id}/symbols/{symbol} (requires JWT token)")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
+	// Start gRPC server next to the HTTP server
+	grpcServer, err := grpcserver.Start(grpcserver.NewPortfolioServer(portfolioService, tokenVerifier))
+	if err != nil {
+		log.Fatalf("gRPC server failed to start: %v", err)
+	}
+	fmt.Printf("gRPC PortfolioService running on %s\n", grpcserver.Addr())
+
+	// Start the background order matching engine and other jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go services.NewOrderMatcher(portfolioService, orderPollInterval()).Run(jobsCtx)
+
+	// Take daily portfolio snapshots for the history endpoint
+	go services.NewSnapshotScheduler(portfolioService, snapshotTime()).Run(jobsCtx)
+
+	// Pay dividends and apply splits and renames once their ex-date arrives
+	go services.NewCorporateActionProcessor(portfolioService, corporateActionInterval()).Run(jobsCtx)
+
+	// Check price and portfolio alerts and send their notifications
+	go services.NewAlertEvaluator(portfolioService, newNotifier(db), alertPollInterval()).Run(jobsCtx)
+
+	// Charge margin interest and record margin calls
+	go services.NewMarginMonitor(portfolioService, marginCheckInterval()).Run(jobsCtx)
+
+	// Forget idempotency keys past their retention and claims whose request never finished
+	go services.NewIdempotencyPurger(portfolioService, time.Hour).Run(jobsCtx)
+
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	fmt.Println("\nShutting down Portfolio Service...")
+	stopJobs()
+
+	// End open update streams so they do not hold up the shutdown
+	portfolioService.CloseStreams()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -128,5 +353,18 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop accepting RPCs and wait for in-flight ones, bounded by the same timeout
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Printf("gRPC server forced to shutdown: %v", ctx.Err())
+		grpcServer.Stop()
+	}
+
 	fmt.Println("Portfolio Service stopped")
 }
@@ -1,49 +1,514 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 // Portfolio represents a user's complete portfolio
 type Portfolio struct {
-	UserID     string       `json:"user_id"`
-	TotalValue float64   `json:"total_value"`
-	Cash       float64   `json:"cash"`
-	Holdings   []Holding `json:"holdings"`
+	UserID       string          `json:"user_id"`
+	TotalValue   decimal.Decimal `json:"total_value"`
+	Cash         decimal.Decimal `json:"cash"`
+	ReservedCash decimal.Decimal `json:"reserved_cash"` // held back for open buy orders
+	Holdings     []Holding       `json:"holdings"`
+	Margin       *MarginSummary  `json:"margin,omitempty"` // only for margin accounts
+
+	RealizedGainLoss   decimal.Decimal `json:"realized_gain_loss"`   // gains realized by past sales
+	UnrealizedGainLoss decimal.Decimal `json:"unrealized_gain_loss"` // sum of GainLoss over holdings
+}
+
+// Account types
+const (
+	AccountTypeCash   = "CASH"
+	AccountTypeMargin = "MARGIN"
+)
+
+// Cost-basis methods used to pick the tax lots a sale closes
+const (
+	CostBasisFIFO     = "FIFO"
+	CostBasisLIFO     = "LIFO"
+	CostBasisSpecific = "SPECIFIC" // lots chosen per sale, FIFO when none are given
+	CostBasisAverage  = "AVERAGE"
+)
+
+// Account holds a user's account type, cost-basis method and margin settings
+type Account struct {
+	UserID            string          `json:"user_id"`
+	AccountType       string          `json:"account_type"`
+	CostBasisMethod   string          `json:"cost_basis_method"`
+	InitialMargin     decimal.Decimal `json:"initial_margin"`     // equity required to open positions, as a fraction of their value
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"` // equity below this fraction triggers a margin call
+	InterestRate      decimal.Decimal `json:"interest_rate"`      // annual rate charged on borrowed cash
+	InterestAccruedAt time.Time       `json:"interest_accrued_at"`
+	FeeSchedule       string          `json:"fee_schedule"` // commission plan charged on trades
+}
+
+// AccountRequest represents a change to a user's account type, cost-basis method or fee
+// schedule. Margin requirements are not user settings; operators set them on the account.
+type AccountRequest struct {
+	AccountType     string `json:"account_type"`
+	CostBasisMethod string `json:"cost_basis_method"`
+	FeeSchedule     string `json:"fee_schedule"`
+}
+
+// DefaultFeeSchedule is the commission plan accounts start on
+const DefaultFeeSchedule = "standard"
+
+// FeeSchedule is a commission plan. A trade's fee is FlatFee plus PerShareFee per share plus
+// PercentFee of the trade value, clamped to MinFee and MaxFee. When the plan has tiers, the
+// highest tier reached by the user's trade value so far this month sets the per-share and
+// percentage rates instead.
+type FeeSchedule struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Private     bool                `json:"private"`    // negotiated for a single user rather than a public plan
+	Selectable  bool                `json:"selectable"` // users may switch to it themselves; otherwise only operators assign it
+	FlatFee     decimal.Decimal     `json:"flat_fee"`
+	PerShareFee decimal.Decimal     `json:"per_share_fee"`
+	PercentFee  decimal.Decimal     `json:"percent_fee"` // fraction of trade value, e.g. 0.001 for 0.1%
+	MinFee      decimal.Decimal     `json:"min_fee"`
+	MaxFee      decimal.NullDecimal `json:"max_fee"`
+	Tiers       []FeeTier           `json:"tiers"`
 }
 
-// Holding represents a stock position in user's portfolio
+// FeeTier sets a schedule's rates once the user's monthly trade value reaches MinVolume
+type FeeTier struct {
+	MinVolume   decimal.Decimal `json:"min_volume"`
+	PerShareFee decimal.Decimal `json:"per_share_fee"`
+	PercentFee  decimal.Decimal `json:"percent_fee"`
+}
+
+// FeeSummary describes the user's commission plan and the plans they can switch to
+type FeeSummary struct {
+	FeeSchedule   string          `json:"fee_schedule"`
+	MonthlyVolume decimal.Decimal `json:"monthly_volume"` // trade value since the start of the month, for tiers
+	Schedules     []FeeSchedule   `json:"schedules"`
+}
+
+// MarginSummary describes a margin account's equity and requirements
+type MarginSummary struct {
+	Equity                 decimal.Decimal `json:"equity"`               // cash plus long value minus short value
+	GrossPositionValue     decimal.Decimal `json:"gross_position_value"` // long value plus absolute short value
+	Borrowed               decimal.Decimal `json:"borrowed"`             // negative cash balance
+	InitialRequirement     decimal.Decimal `json:"initial_requirement"`
+	MaintenanceRequirement decimal.Decimal `json:"maintenance_requirement"`
+	BuyingPower            decimal.Decimal `json:"buying_power"`
+	MarginCall             bool            `json:"margin_call"`
+}
+
+// Holding represents a stock position in user's portfolio; Shares is negative for short positions
 type Holding struct {
-	Symbol       string  `json:"symbol"`
-	Shares       int     `json:"shares"`
-	AvgPrice     float64 `json:"avg_price"`
-	CurrentPrice float64 `json:"current_price"`
-	TotalValue   float64 `json:"total_value"`
-	GainLoss     float64 `json:"gain_loss"`
+	Symbol         string          `json:"symbol"`
+	Shares         decimal.Decimal `json:"shares"`
+	ReservedShares decimal.Decimal `json:"reserved_shares"` // held back for open sell orders
+	AvgPrice       decimal.Decimal `json:"avg_price"`
+	CurrentPrice   decimal.Decimal `json:"current_price"`
+	TotalValue     decimal.Decimal `json:"total_value"`
+	GainLoss       decimal.Decimal `json:"gain_loss"`
 }
 
-// Transaction represents a buy/sell transaction
+// Transaction represents a buy/sell transaction, or a dividend, split or rename applied to a holding
 type Transaction struct {
-	ID              int       `json:"id"`
-	UserID          string    `json:"user_id"`
-	Symbol          string    `json:"symbol"`
-	Shares          int       `json:"shares"`
-	Price           float64   `json:"price"`
-	TransactionType string    `json:"transaction_type"` // "BUY" or "SELL"
-	TotalAmount     float64   `json:"total_amount"`
-	Timestamp       time.Time `json:"timestamp"`
+	ID              int                 `json:"id"`
+	UserID          string              `json:"user_id"`
+	Symbol          string              `json:"symbol"`
+	Shares          decimal.Decimal     `json:"shares"`
+	Price           decimal.Decimal     `json:"price"`
+	TransactionType string              `json:"transaction_type"` // "BUY", "SELL" or a corporate action type
+	TotalAmount     decimal.Decimal     `json:"total_amount"`     // shares * price, before the fee
+	Fee             decimal.Decimal     `json:"fee"`              // commission paid on top of a buy or out of a sale
+	RealizedGain    decimal.NullDecimal `json:"realized_gain"`    // set on trades that close lots
+	Timestamp       time.Time           `json:"timestamp"`
 }
 
-// BuyRequest represents a stock purchase request
+// BuyRequest represents a stock purchase request for a number of shares or, with Amount
+// instead of Shares, a dollar amount's worth of shares
 type BuyRequest struct {
-	Symbol string `json:"symbol"`
-	Shares int    `json:"shares"`
-	UserID string    `json:"user_id"`
+	Symbol string              `json:"symbol"`
+	Shares decimal.Decimal     `json:"shares"`
+	Amount decimal.NullDecimal `json:"amount"`
+	UserID string              `json:"user_id"`
 }
 
 // SellRequest represents a stock sale request
 type SellRequest struct {
+	Symbol string          `json:"symbol"`
+	Shares decimal.Decimal `json:"shares"`
+	UserID string          `json:"user_id"`
+	LotIDs []int           `json:"lot_ids"` // optional, for the SPECIFIC cost-basis method
+}
+
+// TradeResult describes a completed buy or sell
+type TradeResult struct {
+	TransactionID   int                 `json:"transaction_id"`
+	TransactionType string              `json:"transaction_type"` // "BUY" or "SELL"
+	Symbol          string              `json:"symbol"`
+	Shares          decimal.Decimal     `json:"shares"`
+	Price           decimal.Decimal     `json:"price"`
+	TotalAmount     decimal.Decimal     `json:"total_amount"` // shares * price, before the fee
+	Fee             decimal.Decimal     `json:"fee"`
+	RemainingCash   decimal.Decimal     `json:"remaining_cash"`
+	RealizedGain    decimal.NullDecimal `json:"realized_gain"`
+}
+
+// TaxLot is a block of shares opened by one trade; Shares and RemainingShares are
+// negative for short lots
+type TaxLot struct {
+	ID              int             `json:"id"`
+	Symbol          string          `json:"symbol"`
+	TransactionID   int             `json:"transaction_id"`
+	Shares          decimal.Decimal `json:"shares"`
+	RemainingShares decimal.Decimal `json:"remaining_shares"`
+	CostPerShare    decimal.Decimal `json:"cost_per_share"`
+	AcquiredAt      time.Time       `json:"acquired_at"`
+}
+
+// LotSale records the part of a tax lot closed by a transaction
+type LotSale struct {
+	LotID        int             `json:"lot_id"`
+	Shares       decimal.Decimal `json:"shares"`
+	CostBasis    decimal.Decimal `json:"cost_basis"`
+	Proceeds     decimal.Decimal `json:"proceeds"`
+	RealizedGain decimal.Decimal `json:"realized_gain"`
+}
+
+// Order sides, types, time-in-force values and statuses
+const (
+	OrderSideBuy  = "BUY"
+	OrderSideSell = "SELL"
+
+	OrderTypeLimit     = "LIMIT"
+	OrderTypeStop      = "STOP"
+	OrderTypeStopLimit = "STOP_LIMIT"
+
+	TimeInForceDay = "DAY"
+	TimeInForceGTC = "GTC"
+
+	OrderStatusPending         = "PENDING"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCancelled       = "CANCELLED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusExpired         = "EXPIRED"
+)
+
+// Order represents a resting limit, stop or stop-limit order
+type Order struct {
+	ID             int                 `json:"id"`
+	UserID         string              `json:"user_id"`
+	Symbol         string              `json:"symbol"`
+	Side           string              `json:"side"`
+	OrderType      string              `json:"order_type"`
+	Shares         decimal.Decimal     `json:"shares"`
+	FilledShares   decimal.Decimal     `json:"filled_shares"`
+	LimitPrice     decimal.NullDecimal `json:"limit_price"`
+	StopPrice      decimal.NullDecimal `json:"stop_price"`
+	TimeInForce    string              `json:"time_in_force"`
+	Status         string              `json:"status"`
+	Triggered      bool                `json:"triggered"` // stop price reached
+	ReservedCash   decimal.Decimal     `json:"reserved_cash"`
+	ReservedShares decimal.Decimal     `json:"reserved_shares"`
+	FilledPrice    decimal.NullDecimal `json:"filled_price"` // average over all fills
+	TransactionID  *int                `json:"transaction_id"`
+	Reason         string              `json:"reason,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	ExpiresAt      *time.Time          `json:"expires_at"`
+	Events         []OrderEvent        `json:"events,omitempty"`
+}
+
+// OrderEvent is one entry in an order's audit trail
+type OrderEvent struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FromStatus string    `json:"from_status"` // empty when the order was created
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OrderRequest represents a request to place a new order
+type OrderRequest struct {
+	Symbol      string              `json:"symbol"`
+	Side        string              `json:"side"`
+	OrderType   string              `json:"order_type"`
+	Shares      decimal.Decimal     `json:"shares"`
+	LimitPrice  decimal.NullDecimal `json:"limit_price"`
+	StopPrice   decimal.NullDecimal `json:"stop_price"`
+	TimeInForce string              `json:"time_in_force"`
+}
+
+// AmendOrderRequest represents a change to a pending order; omitted fields are left unchanged
+type AmendOrderRequest struct {
+	Shares      decimal.NullDecimal `json:"shares"`
+	LimitPrice  decimal.NullDecimal `json:"limit_price"`
+	StopPrice   decimal.NullDecimal `json:"stop_price"`
+	TimeInForce string              `json:"time_in_force"`
+}
+
+// IdempotencyRecord is a stored response for a request sent with an idempotency key
+type IdempotencyRecord struct {
+	Key            string
+	UserID         string
+	RequestHash    string
+	Completed      bool // false while the original request is still running
+	ResponseStatus int
+	ResponseBody   []byte
+	CreatedAt      time.Time
+}
+
+// Cash ledger entry types
+const (
+	LedgerOpening    = "OPENING"
+	LedgerDeposit    = "DEPOSIT"
+	LedgerWithdrawal = "WITHDRAWAL"
+	LedgerTrade      = "TRADE"
+	LedgerFee        = "FEE"
+	LedgerDividend   = "DIVIDEND"
+	LedgerInterest   = "INTEREST"
+)
+
+// Journal-only entry types for corporate actions that move shares but no cash
+const (
+	JournalSplit  = "SPLIT"
+	JournalRename = "RENAME"
+)
+
+// Ledger accounts; every entry moves money between the user's CASH account and one of the others
+const (
+	LedgerAccountCash           = "CASH"
+	LedgerAccountOpeningBalance = "OPENING_BALANCE"
+	LedgerAccountExternal       = "EXTERNAL" // the user's bank
+	LedgerAccountSecurities     = "SECURITIES"
+	LedgerAccountFees           = "FEES"
+	LedgerAccountDividends      = "DIVIDENDS"
+	LedgerAccountInterest       = "INTEREST"
+	LedgerAccountRealizedGains  = "REALIZED_GAINS" // journal only; credited with gains when positions close
+)
+
+// LedgerEntry is one double-entry cash movement: Amount is debited to DebitAccount and
+// credited to CreditAccount
+type LedgerEntry struct {
+	ID            int             `json:"id"`
+	UserID        string          `json:"user_id"`
+	EntryType     string          `json:"entry_type"`
+	DebitAccount  string          `json:"debit_account"`
+	CreditAccount string          `json:"credit_account"`
+	Amount        decimal.Decimal `json:"amount"`
+	BalanceAfter  decimal.Decimal `json:"balance_after"` // user's cash after the entry
+	TransactionID *int            `json:"transaction_id,omitempty"`
+	JournalID     int             `json:"journal_id"` // journal entry holding the full postings
+	Description   string          `json:"description"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// JournalEntry is a balanced set of postings: the amounts of its postings sum to zero
+type JournalEntry struct {
+	ID            int       `json:"id"`
+	UserID        string    `json:"user_id"`
+	EntryType     string    `json:"entry_type"` // a cash ledger or journal-only entry type
+	TransactionID *int      `json:"transaction_id,omitempty"`
+	Description   string    `json:"description"`
+	Postings      []Posting `json:"postings"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Posting moves Amount into (positive, a debit) or out of (negative, a credit) an account.
+// Postings to SECURITIES name a Symbol and carry the change in shares as Quantity.
+type Posting struct {
+	Account  string          `json:"account"`
+	Symbol   string          `json:"symbol,omitempty"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// JournalPosition is a security balance computed from postings
+type JournalPosition struct {
+	Symbol    string          `json:"symbol"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+}
+
+// Journal is a user's balances computed from postings, with the entries behind them
+type Journal struct {
+	Cash      decimal.Decimal   `json:"cash"`
+	Positions []JournalPosition `json:"positions"`
+	Entries   []JournalEntry    `json:"entries"`
+}
+
+// ReconciliationIssue is a stored balance that disagrees with the journal or cash ledger
+type ReconciliationIssue struct {
+	UserID   string `json:"user_id"`
+	Check    string `json:"check"`
+	Subject  string `json:"subject,omitempty"` // symbol or journal entry the check is about
+	Expected string `json:"expected"`          // from the journal or ledger
+	Actual   string `json:"actual"`            // stored in users or holdings
+}
+
+// CashRequest represents a deposit or withdrawal
+type CashRequest struct {
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+}
+
+// CashLedger explains a user's cash balance: Balance is users.cash and LedgerBalance the
+// sum of the ledger, which must agree
+type CashLedger struct {
+	Balance       decimal.Decimal `json:"balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+	Reconciled    bool            `json:"reconciled"`
+	Entries       []LedgerEntry   `json:"entries"`
+}
+
+// Snapshot history intervals
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// PortfolioSnapshot records a portfolio's value at the end of a day
+type PortfolioSnapshot struct {
+	ID            int               `json:"id"`
+	UserID        string            `json:"user_id"`
+	Date          time.Time         `json:"date"`
+	TotalValue    decimal.Decimal   `json:"total_value"`
+	Cash          decimal.Decimal   `json:"cash"`
+	HoldingsValue decimal.Decimal   `json:"holdings_value"`
+	Holdings      []SnapshotHolding `json:"holdings"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// SnapshotHolding is one position's valuation within a snapshot
+type SnapshotHolding struct {
+	Symbol string          `json:"symbol"`
+	Shares decimal.Decimal `json:"shares"`
+	Price  decimal.Decimal `json:"price"`
+	Value  decimal.Decimal `json:"value"`
+}
+
+// Performance describes a portfolio's returns and risk over a window. Returns are fractions
+// (0.05 is 5%); optional figures are null when the data cannot support them.
+type Performance struct {
+	From                time.Time       `json:"from"`
+	To                  time.Time       `json:"to"`
+	StartValue          decimal.Decimal `json:"start_value"`
+	EndValue            decimal.Decimal `json:"end_value"`
+	NetCashFlow         decimal.Decimal `json:"net_cash_flow"` // money invested minus money taken out
+	TimeWeightedReturn  float64         `json:"time_weighted_return"`
+	MoneyWeightedReturn *float64        `json:"money_weighted_return"` // annualized XIRR
+	MaxDrawdown         float64         `json:"max_drawdown"`
+	Volatility          float64         `json:"volatility"` // annualized
+	SharpeRatio         *float64        `json:"sharpe_ratio"`
+	Benchmark           string          `json:"benchmark"`
+	BenchmarkReturn     *float64        `json:"benchmark_return"`
+	ExcessReturn        *float64        `json:"excess_return"` // time-weighted return minus benchmark return
+}
+
+// Corporate action types, which are also the transaction types they record, and statuses
+const (
+	CorporateActionDividend = "DIVIDEND"
+	CorporateActionSplit    = "SPLIT"
+	CorporateActionRename   = "RENAME"
+
+	CorporateActionPending = "PENDING"
+	CorporateActionApplied = "APPLIED"
+	CorporateActionFailed  = "FAILED"
+)
+
+// CorporateAction is a dividend, split or ticker rename applied to every holder of Symbol
+// once its ex-date arrives. A split of RatioTo new shares for every RatioFrom old ones
+// is a reverse split when RatioTo is smaller.
+type CorporateAction struct {
+	ID          int                 `json:"id"`
+	Symbol      string              `json:"symbol"`
+	ActionType  string              `json:"action_type"`
+	ExDate      time.Time           `json:"ex_date"`
+	CashAmount  decimal.NullDecimal `json:"cash_amount"` // dividend per share
+	RatioFrom   decimal.NullDecimal `json:"ratio_from"`
+	RatioTo     decimal.NullDecimal `json:"ratio_to"`
+	NewSymbol   string              `json:"new_symbol,omitempty"`
+	Status      string              `json:"status"`
+	Reason      string              `json:"reason,omitempty"`
+	ProcessedAt *time.Time          `json:"processed_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// Alert types and statuses. Price alerts compare Symbol's price with Threshold; portfolio
+// alerts compare the day's change in total value, in percent, with Threshold.
+const (
+	AlertPriceAbove    = "PRICE_ABOVE"
+	AlertPriceBelow    = "PRICE_BELOW"
+	AlertPortfolioUp   = "PORTFOLIO_UP"
+	AlertPortfolioDown = "PORTFOLIO_DOWN"
+
+	AlertStatusActive    = "ACTIVE"
+	AlertStatusTriggered = "TRIGGERED"
+)
+
+// Alert notifies a user once through Channel when its condition is met
+type Alert struct {
+	ID             int                 `json:"id"`
+	UserID         string              `json:"user_id"`
+	AlertType      string              `json:"alert_type"`
+	Symbol         string              `json:"symbol,omitempty"`
+	Threshold      decimal.Decimal     `json:"threshold"`
+	Channel        string              `json:"channel"`          // INAPP, WEBHOOK or EMAIL
+	Target         string              `json:"target,omitempty"` // webhook URL; email alerts go to the user's confirmed address
+	Status         string              `json:"status"`
+	TriggeredAt    *time.Time          `json:"triggered_at,omitempty"`
+	TriggeredValue decimal.NullDecimal `json:"triggered_value"` // price or percent change that met the condition
+	DeliveryError  string              `json:"delivery_error,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+// AlertRequest creates an alert; Channel defaults to the in-app inbox. Email alerts always
+// go to the user's confirmed address, so their Target may only repeat it.
+type AlertRequest struct {
+	AlertType string          `json:"alert_type"`
+	Symbol    string          `json:"symbol"`
+	Threshold decimal.Decimal `json:"threshold"`
+	Channel   string          `json:"channel"`
+	Target    string          `json:"target"`
+}
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID        int        `json:"id"`
+	AlertID   *int       `json:"alert_id,omitempty"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Watchlist is a named list of symbols a user follows. Quotes is only filled in when a
+// single watchlist is fetched.
+type Watchlist struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	Symbols   []string         `json:"symbols"`
+	Quotes    []WatchlistQuote `json:"quotes,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// WatchlistQuote is a watched symbol's live price; Price is null when the market has none
+type WatchlistQuote struct {
+	Symbol string              `json:"symbol"`
+	Price  decimal.NullDecimal `json:"price"`
+}
+
+// WatchlistRequest creates or renames a watchlist; Symbols seeds a new one
+type WatchlistRequest struct {
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols"`
+}
+
+// WatchlistSymbolRequest adds a symbol to a watchlist
+type WatchlistSymbolRequest struct {
 	Symbol string `json:"symbol"`
-	Shares int    `json:"shares"`
-	UserID string `json:"user_id"`
 }
 
 // APIResponse represents standard API response format
@@ -56,8 +521,8 @@ type APIResponse struct {
 
 // StockPrice represents current stock price data
 type StockPrice struct {
-	Symbol        string  `json:"symbol"`
-	Name          string  `json:"name"`
-	Price         float64 `json:"price"`
-	ChangePercent float64 `json:"change_percent"`
+	Symbol        string          `json:"symbol"`
+	Name          string          `json:"name"`
+	Price         decimal.Decimal `json:"price"`
+	ChangePercent float64         `json:"change_percent"`
 }
@@ -0,0 +1,68 @@
+// Package money defines how cash amounts and prices are rounded.
+//
+// Amounts are carried as decimal.Decimal end to end and encoded in JSON as
+// strings, so nothing passes through float64 except the market price feed
+// and the double fields of the gRPC contract.
+package money
+
+import "github.com/shopspring/decimal"
+
+const (
+	// CashPlaces is the precision of cash balances, trade prices and totals (DECIMAL(15,2))
+	CashPlaces = 2
+	// AvgPricePlaces is the precision of a holding's average cost (DECIMAL(15,4))
+	AvgPricePlaces = 4
+	// SharePlaces is the precision of share quantities, which may be fractional (DECIMAL(18,6))
+	SharePlaces = 6
+)
+
+// Zero is a zero amount
+var Zero = decimal.Zero
+
+// FromPrice converts a market price received as float64 to a decimal rounded to whole cents
+func FromPrice(price float64) decimal.Decimal {
+	return decimal.NewFromFloat(price).Round(CashPlaces)
+}
+
+// FromPrices converts a map of market prices with FromPrice
+func FromPrices(prices map[string]float64) map[string]decimal.Decimal {
+	result := make(map[string]decimal.Decimal, len(prices))
+	for symbol, price := range prices {
+		result[symbol] = FromPrice(price)
+	}
+	return result
+}
+
+// RoundCash rounds half away from zero to whole cents
+func RoundCash(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(CashPlaces)
+}
+
+// RoundShares truncates a share quantity to SharePlaces, so a quantity derived from a
+// dollar amount never costs more than that amount
+func RoundShares(shares decimal.Decimal) decimal.Decimal {
+	return shares.Truncate(SharePlaces)
+}
+
+// ValidShares reports whether shares is a positive quantity of at most SharePlaces decimals
+func ValidShares(shares decimal.Decimal) bool {
+	return shares.IsPositive() && RoundShares(shares).Equal(shares)
+}
+
+// Total returns price * shares rounded to whole cents. It is exact for whole shares, whose
+// price is already in whole cents; fractional shares round half away from zero.
+func Total(price decimal.Decimal, shares decimal.Decimal) decimal.Decimal {
+	return RoundCash(price.Mul(shares))
+}
+
+// AverageCost returns the new average price after adding shares bought for cost to a
+// position of heldShares at heldAvg. The result is rounded half away from zero to
+// AvgPricePlaces so repeated buys do not accumulate rounding drift at cent precision.
+func AverageCost(heldShares decimal.Decimal, heldAvg decimal.Decimal, shares decimal.Decimal, cost decimal.Decimal) decimal.Decimal {
+	totalShares := heldShares.Add(shares)
+	if totalShares.IsZero() {
+		return Zero
+	}
+	basis := heldAvg.Mul(heldShares).Add(cost)
+	return basis.DivRound(totalShares, AvgPricePlaces)
+}
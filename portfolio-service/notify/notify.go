@@ -0,0 +1,216 @@
+// Package notify delivers alert notifications by webhook, email or the in-app inbox
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Delivery channels an alert can notify through
+const (
+	ChannelInApp   = "INAPP"
+	ChannelWebhook = "WEBHOOK"
+	ChannelEmail   = "EMAIL"
+)
+
+// Message is one notification for a user. Target is the webhook URL or email address.
+type Message struct {
+	UserID  string    `json:"user_id"`
+	AlertID int       `json:"alert_id"`
+	Channel string    `json:"-"`
+	Target  string    `json:"-"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Notifier delivers a message
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// Dispatcher routes each message to the notifier for its channel
+type Dispatcher map[string]Notifier
+
+// Notify delivers message through the notifier registered for its channel
+func (d Dispatcher) Notify(ctx context.Context, message Message) error {
+	notifier, ok := d[message.Channel]
+	if !ok {
+		return fmt.Errorf("no notifier for channel %q", message.Channel)
+	}
+	return notifier.Notify(ctx, message)
+}
+
+// ErrPrivateAddress is returned for webhook targets that are not on the public internet
+var ErrPrivateAddress = errors.New("webhook target must be a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP does not treat as private
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is a publicly routable unicast address, ruling out loopback,
+// private, link-local (including cloud metadata at 169.254.169.254) and unspecified addresses
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// CheckWebhookTarget validates a webhook URL when an alert is created: it must be http or
+// https and its host must resolve only to public addresses. Delivery checks the address it
+// actually connects to again, since DNS answers can change in between.
+func CheckWebhookTarget(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook alerts need an http or https target URL")
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateAddress, u.Hostname(), addr.IP)
+		}
+	}
+	return nil
+}
+
+// dialPublicOnly runs just before each webhook connection and refuses non-public addresses,
+// so a host that resolved to a public address at creation cannot later point inside the network
+func dialPublicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs messages as JSON to the alert's URL
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier whose requests give up after timeout. It only
+// connects to public addresses, ignores proxy settings and does not follow redirects, so
+// that alert targets cannot reach services inside the network.
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+	return &WebhookNotifier{client: &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		// A redirect is answered as the webhook's response, which Notify reports as a failure
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Notify posts the message and fails on any non-2xx response
+func (n *WebhookNotifier) Notify(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, message.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails messages through an SMTP server
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier creates a notifier sending from the from address through addr
+// ("host:port"); an empty username sends without authentication
+func NewSMTPNotifier(addr string, from string, username string, password string) *SMTPNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPNotifier{addr: addr, from: from, auth: auth}
+}
+
+// Notify sends the message as a plain-text email to its target address
+func (n *SMTPNotifier) Notify(ctx context.Context, message Message) error {
+	if message.Target == "" {
+		return fmt.Errorf("no email address for user %s", message.UserID)
+	}
+
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", n.from)
+	fmt.Fprintf(&email, "To: %s\r\n", message.Target)
+	fmt.Fprintf(&email, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&email, "Date: %s\r\n", message.SentAt.Format(time.RFC1123Z))
+	email.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	email.WriteString(message.Body)
+	email.WriteString("\r\n")
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{message.Target}, email.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// LogNotifier prints messages instead of sending them, standing in for SMTP in development
+type LogNotifier struct{}
+
+// Notify prints the message
+func (LogNotifier) Notify(ctx context.Context, message Message) error {
+	fmt.Printf("Notification to %s (%s): %s - %s\n", message.Target, message.Channel, message.Subject, message.Body)
+	return nil
+}
+
+// InboxStore saves in-app notifications
+type InboxStore interface {
+	CreateNotification(userID string, alertID int, subject string, body string) error
+}
+
+// InboxNotifier stores messages in the user's in-app inbox
+type InboxNotifier struct {
+	store InboxStore
+}
+
+// NewInboxNotifier creates a notifier writing to store
+func NewInboxNotifier(store InboxStore) *InboxNotifier {
+	return &InboxNotifier{store: store}
+}
+
+// Notify adds the message to the user's inbox
+func (n *InboxNotifier) Notify(ctx context.Context, message Message) error {
+	return n.store.CreateNotification(message.UserID, message.AlertID, message.Subject, message.Body)
+}
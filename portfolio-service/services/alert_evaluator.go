@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"portfolio-service/notify"
+	"time"
+)
+
+// AlertEvaluator periodically checks active alerts and sends their notifications
+type AlertEvaluator struct {
+	portfolioService *PortfolioService
+	notifier         notify.Notifier
+	interval         time.Duration
+}
+
+// NewAlertEvaluator creates an evaluator that checks prices every interval and notifies through notifier
+func NewAlertEvaluator(portfolioService *PortfolioService, notifier notify.Notifier, interval time.Duration) *AlertEvaluator {
+	return &AlertEvaluator{
+		portfolioService: portfolioService,
+		notifier:         notifier,
+		interval:         interval,
+	}
+}
+
+// Run evaluates alerts until ctx is cancelled
+func (e *AlertEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Alert evaluator running every %s\n", e.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Alert evaluator stopped")
+			return
+		case <-ticker.C:
+			if err := e.portfolioService.EvaluateAlerts(ctx, e.notifier); err != nil && ctx.Err() == nil {
+				fmt.Printf("Alert evaluation failed: %v\n", err)
+			}
+		}
+	}
+}
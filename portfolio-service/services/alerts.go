@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"portfolio-service/notify"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrAlertNotFound is returned when an alert does not exist or belongs to another user
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrNotificationNotFound is returned when an inbox message does not exist or belongs to another user
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// CreateAlert validates and stores a new price or portfolio alert
+func (s *PortfolioService) CreateAlert(ctx context.Context, userID string, req models.AlertRequest) (*models.Alert, error) {
+	alert := &models.Alert{
+		UserID:    userID,
+		AlertType: strings.ToUpper(req.AlertType),
+		Symbol:    strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Threshold: req.Threshold,
+		Channel:   strings.ToUpper(req.Channel),
+		Target:    strings.TrimSpace(req.Target),
+	}
+	if !alert.Threshold.IsPositive() {
+		return nil, fmt.Errorf("threshold must be positive")
+	}
+
+	switch alert.AlertType {
+	case models.AlertPriceAbove, models.AlertPriceBelow:
+		symbol, err := s.validSymbol(ctx, alert.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		alert.Symbol = symbol
+	case models.AlertPortfolioUp, models.AlertPortfolioDown:
+		alert.Symbol = ""
+	default:
+		return nil, fmt.Errorf("invalid alert_type: %q", req.AlertType)
+	}
+
+	switch alert.Channel {
+	case "":
+		alert.Channel = notify.ChannelInApp
+		alert.Target = ""
+	case notify.ChannelInApp:
+		alert.Target = ""
+	case notify.ChannelWebhook:
+		if err := notify.CheckWebhookTarget(ctx, alert.Target); err != nil {
+			return nil, err
+		}
+	case notify.ChannelEmail:
+		// Email alerts only go to the user's own confirmed address, looked up when they fire
+		email, verified, err := s.db.GetUserEmail(userID)
+		if err != nil {
+			return nil, err
+		}
+		if alert.Target != "" {
+			address, err := mail.ParseAddress(alert.Target)
+			if err != nil {
+				return nil, fmt.Errorf("invalid email target: %q", alert.Target)
+			}
+			if !strings.EqualFold(address.Address, email) {
+				return nil, fmt.Errorf("email alerts can only be sent to your account's address")
+			}
+		}
+		if !verified {
+			return nil, fmt.Errorf("confirm your email address before creating email alerts")
+		}
+		alert.Target = ""
+	default:
+		return nil, fmt.Errorf("invalid channel: %q (use INAPP, WEBHOOK or EMAIL)", req.Channel)
+	}
+
+	if err := s.db.CreateAlert(alert); err != nil {
+		return nil, err
+	}
+	fmt.Printf("User %s created %s alert %d\n", userID, alert.AlertType, alert.ID)
+	return alert, nil
+}
+
+// GetAlerts returns the user's alerts, newest first
+func (s *PortfolioService) GetAlerts(ctx context.Context, userID string) ([]models.Alert, error) {
+	return s.db.GetUserAlerts(userID)
+}
+
+// DeleteAlert removes one of the user's alerts
+func (s *PortfolioService) DeleteAlert(ctx context.Context, userID string, alertID int) error {
+	deleted, err := s.db.DeleteAlert(userID, alertID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAlertNotFound
+	}
+	return nil
+}
+
+// GetNotifications returns the user's in-app inbox, optionally only unread messages
+func (s *PortfolioService) GetNotifications(ctx context.Context, userID string, unreadOnly bool) ([]models.Notification, error) {
+	return s.db.GetNotifications(userID, unreadOnly)
+}
+
+// MarkNotificationRead marks one of the user's inbox messages as read
+func (s *PortfolioService) MarkNotificationRead(ctx context.Context, userID string, notificationID int) error {
+	marked, err := s.db.MarkNotificationRead(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// portfolioChange is a portfolio's current value against its last snapshot
+type portfolioChange struct {
+	value    decimal.Decimal
+	previous decimal.Decimal
+	percent  decimal.Decimal
+}
+
+// EvaluateAlerts checks every active alert against current market prices and notifies the
+// owners of those whose condition is met. Each alert triggers once.
+func (s *PortfolioService) EvaluateAlerts(ctx context.Context, notifier notify.Notifier) error {
+	alerts, err := s.db.GetActiveAlerts()
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+
+	// Price every alert's symbol and every holding behind a portfolio alert in one batch
+	holdings := make(map[string][]models.Holding)
+	seen := make(map[string]bool)
+	var symbols []string
+	addSymbol := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, alert := range alerts {
+		if alert.Symbol != "" {
+			addSymbol(alert.Symbol)
+			continue
+		}
+		if _, ok := holdings[alert.UserID]; ok {
+			continue
+		}
+		userHoldings, err := s.db.GetAllUserHoldings(alert.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user holdings: %w", err)
+		}
+		holdings[alert.UserID] = userHoldings
+		for _, holding := range userHoldings {
+			addSymbol(holding.Symbol)
+		}
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	if len(symbols) > 0 {
+		marketPrices, err := s.marketClient.GetMultipleStockPrices(ctx, symbols)
+		if err != nil {
+			return fmt.Errorf("failed to get prices from market service: %w", err)
+		}
+		prices = money.FromPrices(marketPrices)
+	}
+
+	changes := make(map[string]*portfolioChange)
+	today := snapshotDate(time.Now())
+	for _, alert := range alerts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var value decimal.Decimal
+		var subject, body string
+		switch alert.AlertType {
+		case models.AlertPriceAbove, models.AlertPriceBelow:
+			price, ok := prices[alert.Symbol]
+			if !ok || !price.IsPositive() {
+				continue
+			}
+			above := alert.AlertType == models.AlertPriceAbove
+			if (above && price.LessThan(alert.Threshold)) || (!above && price.GreaterThan(alert.Threshold)) {
+				continue
+			}
+			direction := "below"
+			if above {
+				direction = "above"
+			}
+			value = price
+			subject = fmt.Sprintf("%s is %s $%s", alert.Symbol, direction, alert.Threshold.StringFixed(money.CashPlaces))
+			body = fmt.Sprintf("%s is trading at $%s, at or %s your alert price of $%s.", alert.Symbol,
+				price.StringFixed(money.CashPlaces), direction, alert.Threshold.StringFixed(money.CashPlaces))
+
+		case models.AlertPortfolioUp, models.AlertPortfolioDown:
+			change, ok := changes[alert.UserID]
+			if !ok {
+				change, err = s.portfolioChange(alert.UserID, holdings[alert.UserID], prices, today)
+				if err != nil {
+					fmt.Printf("Failed to value portfolio of user %s for alerts: %v\n", alert.UserID, err)
+				}
+				changes[alert.UserID] = change
+			}
+			if change == nil {
+				continue
+			}
+			up := alert.AlertType == models.AlertPortfolioUp
+			if (up && change.percent.LessThan(alert.Threshold)) || (!up && change.percent.GreaterThan(alert.Threshold.Neg())) {
+				continue
+			}
+			direction := "down"
+			if up {
+				direction = "up"
+			}
+			value = change.percent
+			subject = fmt.Sprintf("Your portfolio is %s %s%% today", direction, change.percent.Abs().StringFixed(2))
+			body = fmt.Sprintf("Your portfolio is worth $%s, %s %s%% from $%s at the last snapshot, past your %s%% alert.",
+				change.value.StringFixed(money.CashPlaces), direction, change.percent.Abs().StringFixed(2),
+				change.previous.StringFixed(money.CashPlaces), alert.Threshold.String())
+
+		default:
+			continue
+		}
+
+		if err := s.triggerAlert(ctx, notifier, alert, value, subject, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// portfolioChange values a user's portfolio at prices (falling back to average prices) and
+// compares it with their last snapshot before today. Returns nil without a snapshot to compare with.
+func (s *PortfolioService) portfolioChange(userID string, holdings []models.Holding, prices map[string]decimal.Decimal,
+	today time.Time) (*portfolioChange, error) {
+	previous, err := s.db.GetLastSnapshotValue(userID, today)
+	if err != nil || !previous.Valid || !previous.Decimal.IsPositive() {
+		return nil, err
+	}
+
+	value, err := s.db.GetUserCash(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, holding := range holdings {
+		price, ok := prices[holding.Symbol]
+		if !ok {
+			price = holding.AvgPrice
+		}
+		value = value.Add(money.Total(price, holding.Shares))
+	}
+
+	return &portfolioChange{
+		value:    value,
+		previous: previous.Decimal,
+		percent:  value.Sub(previous.Decimal).Mul(hundred).DivRound(previous.Decimal, 2),
+	}, nil
+}
+
+// triggerAlert claims an alert and delivers its notification. A failed delivery is
+// recorded on the alert rather than retried.
+func (s *PortfolioService) triggerAlert(ctx context.Context, notifier notify.Notifier, alert models.Alert,
+	value decimal.Decimal, subject string, body string) error {
+	claimed, err := s.db.TriggerAlert(alert.ID, value)
+	if err != nil || !claimed {
+		return err
+	}
+
+	message := notify.Message{
+		UserID:  alert.UserID,
+		AlertID: alert.ID,
+		Channel: alert.Channel,
+		Target:  alert.Target,
+		Subject: subject,
+		Body:    body,
+		SentAt:  time.Now().UTC(),
+	}
+	if message.Channel == notify.ChannelEmail {
+		email, verified, err := s.db.GetUserEmail(alert.UserID)
+		if err != nil {
+			return s.db.SetAlertDeliveryError(alert.ID, err.Error())
+		}
+		if !verified {
+			return s.db.SetAlertDeliveryError(alert.ID, "email address is not confirmed")
+		}
+		message.Target = email
+	}
+
+	if err := notifier.Notify(ctx, message); err != nil {
+		fmt.Printf("Failed to deliver alert %d to user %s: %v\n", alert.ID, alert.UserID, err)
+		return s.db.SetAlertDeliveryError(alert.ID, err.Error())
+	}
+	fmt.Printf("Alert %d triggered for user %s: %s\n", alert.ID, alert.UserID, subject)
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidAmount is returned for deposits and withdrawals that are not a positive amount of cents
+var ErrInvalidAmount = errors.New("amount must be positive with at most 2 decimal places")
+
+// ledgerCounterAccounts is the account on the other side of the user's CASH account for each entry type
+var ledgerCounterAccounts = map[string]string{
+	models.LedgerOpening:    models.LedgerAccountOpeningBalance,
+	models.LedgerDeposit:    models.LedgerAccountExternal,
+	models.LedgerWithdrawal: models.LedgerAccountExternal,
+	models.LedgerTrade:      models.LedgerAccountSecurities,
+	models.LedgerFee:        models.LedgerAccountFees,
+	models.LedgerDividend:   models.LedgerAccountDividends,
+	models.LedgerInterest:   models.LedgerAccountInterest,
+}
+
+// postCash moves change (positive into the user's cash, negative out of it) through the
+// journal and cash ledger and returns the ledger entry, whose BalanceAfter is the new balance.
+// postings are the journal's other side of the movement; by default the entry type's counter
+// account takes the opposite amount. The user's row must already be locked.
+func postCash(tx *database.Tx, userID string, entryType string, change decimal.Decimal, transactionID *int,
+	description string, postings ...models.Posting) (*models.LedgerEntry, error) {
+	counterAccount := ledgerCounterAccounts[entryType]
+	if len(postings) == 0 {
+		postings = []models.Posting{{Account: counterAccount, Amount: change.Neg()}}
+	}
+
+	journal := &models.JournalEntry{
+		UserID:        userID,
+		EntryType:     entryType,
+		TransactionID: transactionID,
+		Description:   description,
+		Postings:      append([]models.Posting{{Account: models.LedgerAccountCash, Amount: change}}, postings...),
+	}
+	if err := tx.PostJournal(journal); err != nil {
+		return nil, err
+	}
+
+	entry := &models.LedgerEntry{
+		UserID:        userID,
+		EntryType:     entryType,
+		DebitAccount:  models.LedgerAccountCash,
+		CreditAccount: counterAccount,
+		Amount:        change.Abs(),
+		TransactionID: transactionID,
+		JournalID:     journal.ID,
+		Description:   description,
+	}
+	if change.IsNegative() {
+		entry.DebitAccount, entry.CreditAccount = entry.CreditAccount, entry.DebitAccount
+	}
+
+	if err := tx.PostCash(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func validateCashAmount(amount decimal.Decimal) error {
+	if !amount.IsPositive() || !money.RoundCash(amount).Equal(amount) {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// Deposit adds money from the user's bank to their cash balance
+func (s *PortfolioService) Deposit(ctx context.Context, userID string, req models.CashRequest) (*models.LedgerEntry, error) {
+	if err := validateCashAmount(req.Amount); err != nil {
+		return nil, err
+	}
+	description := req.Description
+	if description == "" {
+		description = "deposit"
+	}
+
+	var entry *models.LedgerEntry
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		// Interest on cash borrowed until now is owed before the deposit repays it
+		if _, _, err := settleInterest(tx, userID); err != nil {
+			return err
+		}
+		var err error
+		entry, err = postCash(tx, userID, models.LedgerDeposit, req.Amount, nil, description)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("User %s deposited $%s\n", userID, req.Amount.StringFixed(money.CashPlaces))
+	return entry, nil
+}
+
+// Withdraw sends money from the user's cash balance to their bank. Cash reserved for open
+// orders and borrowed cash cannot be withdrawn.
+func (s *PortfolioService) Withdraw(ctx context.Context, userID string, req models.CashRequest) (*models.LedgerEntry, error) {
+	if err := validateCashAmount(req.Amount); err != nil {
+		return nil, err
+	}
+	description := req.Description
+	if description == "" {
+		description = "withdrawal"
+	}
+
+	var entry *models.LedgerEntry
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		cash, reserved, err := settleInterest(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		available := cash.Sub(reserved)
+		if available.LessThan(req.Amount) {
+			return fmt.Errorf("%w: have $%s available, withdrawing $%s", ErrInsufficientFunds,
+				decimal.Max(available, money.Zero).StringFixed(money.CashPlaces), req.Amount.StringFixed(money.CashPlaces))
+		}
+
+		entry, err = postCash(tx, userID, models.LedgerWithdrawal, req.Amount.Neg(), nil, description)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("User %s withdrew $%s\n", userID, req.Amount.StringFixed(money.CashPlaces))
+	return entry, nil
+}
+
+// GetCashLedger returns the user's ledger entries and checks their sum against the cash balance
+func (s *PortfolioService) GetCashLedger(ctx context.Context, userID string) (*models.CashLedger, error) {
+	// Read both balances under the user's lock so an in-flight trade cannot skew the comparison
+	var balance, ledgerBalance decimal.Decimal
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		if balance, _, err = tx.GetUserCashForUpdate(userID); err != nil {
+			return fmt.Errorf("failed to get user cash: %w", err)
+		}
+		ledgerBalance, err = tx.GetLedgerBalance(userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.db.GetLedgerEntries(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := &models.CashLedger{
+		Balance:       balance,
+		LedgerBalance: ledgerBalance,
+		Reconciled:    balance.Equal(ledgerBalance),
+		Entries:       entries,
+	}
+	if !ledger.Reconciled {
+		fmt.Printf("Cash ledger mismatch for user %s: balance $%s, ledger $%s\n", userID,
+			balance.StringFixed(money.CashPlaces), ledgerBalance.StringFixed(money.CashPlaces))
+	}
+	return ledger, nil
+}
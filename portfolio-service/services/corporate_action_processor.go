@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CorporateActionProcessor periodically applies corporate actions whose ex-date has arrived
+type CorporateActionProcessor struct {
+	portfolioService *PortfolioService
+	interval         time.Duration
+}
+
+// NewCorporateActionProcessor creates a processor that checks for due actions every interval
+func NewCorporateActionProcessor(portfolioService *PortfolioService, interval time.Duration) *CorporateActionProcessor {
+	return &CorporateActionProcessor{
+		portfolioService: portfolioService,
+		interval:         interval,
+	}
+}
+
+// Run processes due actions at startup and then every interval until ctx is cancelled
+func (p *CorporateActionProcessor) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Corporate action processor running every %s\n", p.interval)
+	for {
+		if err := p.portfolioService.ProcessCorporateActions(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Corporate action processing failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Corporate action processor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
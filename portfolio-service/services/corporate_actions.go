@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProcessCorporateActions applies every pending corporate action whose ex-date has arrived.
+// An action that cannot be applied is marked FAILED with the reason and the rest still run.
+func (s *PortfolioService) ProcessCorporateActions(ctx context.Context) error {
+	ids, err := s.db.GetDueCorporateActionIDs(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := s.applyCorporateAction(ctx, id)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("Corporate action %d failed: %v\n", id, err)
+		if err := s.db.FailCorporateAction(id, err.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCorporateAction applies one action to every holder and open order in a single
+// transaction. Open orders are locked before users, as lockOrder does, and users before
+// their holdings, as trades do.
+func (s *PortfolioService) applyCorporateAction(ctx context.Context, actionID int) error {
+	var action *models.CorporateAction
+	holders := 0
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		action, err = tx.GetCorporateActionForUpdate(actionID)
+		if err != nil || action == nil || action.Status != models.CorporateActionPending {
+			return err
+		}
+
+		switch action.ActionType {
+		case models.CorporateActionDividend:
+			holders, err = applyDividend(tx, action)
+		case models.CorporateActionSplit:
+			holders, err = applySplit(tx, action)
+		case models.CorporateActionRename:
+			holders, err = applyRename(tx, action)
+		default:
+			err = fmt.Errorf("unknown corporate action type %q", action.ActionType)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.FinishCorporateAction(action.ID)
+	})
+	if err != nil || action == nil || action.Status != models.CorporateActionPending {
+		return err
+	}
+
+	fmt.Printf("Applied %s to %d holder(s)\n", describeCorporateAction(action), holders)
+	return nil
+}
+
+// describeCorporateAction names an action for logs and journal descriptions
+func describeCorporateAction(action *models.CorporateAction) string {
+	switch action.ActionType {
+	case models.CorporateActionDividend:
+		return fmt.Sprintf("$%s per share dividend on %s", action.CashAmount.Decimal.String(), action.Symbol)
+	case models.CorporateActionSplit:
+		return fmt.Sprintf("%s-for-%s split of %s", action.RatioTo.Decimal.String(), action.RatioFrom.Decimal.String(), action.Symbol)
+	case models.CorporateActionRename:
+		return fmt.Sprintf("rename of %s to %s", action.Symbol, action.NewSymbol)
+	}
+	return fmt.Sprintf("%s of %s", strings.ToLower(action.ActionType), action.Symbol)
+}
+
+// lockHolding locks a holder's row and then their holding in symbol. Returns nil when
+// the position was closed after the holders were listed.
+func lockHolding(tx *database.Tx, userID string, symbol string) (*models.Holding, error) {
+	if _, _, err := tx.GetUserCashForUpdate(userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	holding, err := tx.GetUserHoldingForUpdate(userID, symbol)
+	if err != nil || holding == nil || holding.Shares.IsZero() {
+		return nil, err
+	}
+	return holding, nil
+}
+
+// applyDividend credits each holder the dividend on their shares; short positions pay it
+func applyDividend(tx *database.Tx, action *models.CorporateAction) (int, error) {
+	if !action.CashAmount.Valid || !action.CashAmount.Decimal.IsPositive() {
+		return 0, fmt.Errorf("dividend needs a positive cash_amount")
+	}
+	perShare := action.CashAmount.Decimal
+
+	userIDs, err := tx.GetHolderIDs(action.Symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, userID := range userIDs {
+		holding, err := lockHolding(tx, userID, action.Symbol)
+		if err != nil {
+			return paid, err
+		}
+		if holding == nil {
+			continue
+		}
+		amount := money.RoundCash(holding.Shares.Mul(perShare))
+		if amount.IsZero() {
+			continue
+		}
+
+		transactionID, err := tx.CreateTransaction(userID, action.Symbol, holding.Shares, perShare,
+			models.CorporateActionDividend, amount, money.Zero, decimal.NullDecimal{})
+		if err != nil {
+			return paid, err
+		}
+		description := fmt.Sprintf("dividend of $%s per share on %s %s", perShare.String(), holding.Shares.String(), action.Symbol)
+		if _, err := postCash(tx, userID, models.LedgerDividend, amount, &transactionID, description); err != nil {
+			return paid, err
+		}
+		paid++
+	}
+	return paid, nil
+}
+
+// applySplit multiplies shares by RatioTo/RatioFrom and divides prices by it for every open
+// order, holding and tax lot in the symbol. Cost basis is unchanged, so the journal only
+// records the change in quantity.
+func applySplit(tx *database.Tx, action *models.CorporateAction) (int, error) {
+	if !action.RatioFrom.Valid || !action.RatioTo.Valid || !action.RatioFrom.Decimal.IsPositive() ||
+		!action.RatioTo.Decimal.IsPositive() || action.RatioFrom.Decimal.Equal(action.RatioTo.Decimal) {
+		return 0, fmt.Errorf("split needs positive, different ratio_from and ratio_to")
+	}
+	ratio := action.RatioTo.Decimal.Div(action.RatioFrom.Decimal)
+	description := describeCorporateAction(action)
+
+	orders, err := tx.GetOpenOrdersForUpdate(action.Symbol, openOrderStatuses...)
+	if err != nil {
+		return 0, err
+	}
+	for i := range orders {
+		if err := splitOrder(tx, &orders[i], ratio, description); err != nil {
+			return 0, err
+		}
+	}
+
+	userIDs, err := tx.GetHolderIDs(action.Symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	adjusted := 0
+	for _, userID := range userIDs {
+		holding, err := lockHolding(tx, userID, action.Symbol)
+		if err != nil {
+			return adjusted, err
+		}
+		if holding == nil {
+			continue
+		}
+
+		newShares := money.RoundShares(holding.Shares.Mul(ratio))
+		newAvg := holding.AvgPrice.DivRound(ratio, money.AvgPricePlaces)
+		if err := tx.UpsertHolding(userID, action.Symbol, newShares, newAvg); err != nil {
+			return adjusted, err
+		}
+		if err := tx.SplitLots(userID, action.Symbol, ratio); err != nil {
+			return adjusted, err
+		}
+
+		delta := newShares.Sub(holding.Shares)
+		transactionID, err := tx.CreateTransaction(userID, action.Symbol, delta, money.Zero,
+			models.CorporateActionSplit, money.Zero, money.Zero, decimal.NullDecimal{})
+		if err != nil {
+			return adjusted, err
+		}
+		err = tx.PostJournal(&models.JournalEntry{
+			UserID:        userID,
+			EntryType:     models.JournalSplit,
+			TransactionID: &transactionID,
+			Description:   fmt.Sprintf("%s: %s shares became %s", description, holding.Shares.String(), newShares.String()),
+			Postings: []models.Posting{
+				{Account: models.LedgerAccountSecurities, Symbol: action.Symbol, Quantity: delta, Amount: money.Zero},
+			},
+		})
+		if err != nil {
+			return adjusted, err
+		}
+		adjusted++
+	}
+
+	return adjusted, tx.SplitStockPrice(action.Symbol, ratio)
+}
+
+// splitOrder rescales an open order and the shares it reserves. An order whose unfilled
+// part rounds away to nothing is cancelled.
+func splitOrder(tx *database.Tx, order *models.Order, ratio decimal.Decimal, description string) error {
+	if _, _, err := tx.GetUserCashForUpdate(order.UserID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	shares := money.RoundShares(order.Shares.Mul(ratio))
+	filled := money.RoundShares(order.FilledShares.Mul(ratio))
+	if shares.LessThanOrEqual(filled) {
+		reason := fmt.Sprintf("cancelled by %s", description)
+		if err := transitionOrder(tx, order, models.OrderStatusCancelled, reason); err != nil {
+			return err
+		}
+		if err := releaseReservation(tx, order); err != nil {
+			return err
+		}
+		return tx.CloseOrder(order.ID, models.OrderStatusCancelled, reason)
+	}
+
+	reserved := money.RoundShares(order.ReservedShares.Mul(ratio))
+	if change := reserved.Sub(order.ReservedShares); !change.IsZero() {
+		if err := tx.AdjustReservedShares(order.UserID, order.Symbol, change); err != nil {
+			return err
+		}
+	}
+
+	reason := fmt.Sprintf("%s: %s shares became %s", description, order.Shares.String(), shares.String())
+	order.Shares, order.FilledShares, order.ReservedShares = shares, filled, reserved
+	if order.LimitPrice.Valid {
+		order.LimitPrice.Decimal = order.LimitPrice.Decimal.DivRound(ratio, money.CashPlaces)
+	}
+	if order.StopPrice.Valid {
+		order.StopPrice.Decimal = order.StopPrice.Decimal.DivRound(ratio, money.CashPlaces)
+	}
+	if err := tx.AdjustOrder(order); err != nil {
+		return err
+	}
+	return tx.RecordOrderEvent(order.ID, order.Status, order.Status, reason)
+}
+
+// applyRename moves every holding, tax lot and open order in the symbol to NewSymbol.
+// The journal moves each position's quantity and cost basis across with it.
+func applyRename(tx *database.Tx, action *models.CorporateAction) (int, error) {
+	newSymbol := strings.ToUpper(strings.TrimSpace(action.NewSymbol))
+	if newSymbol == "" || newSymbol == action.Symbol {
+		return 0, fmt.Errorf("rename needs a new_symbol different from %s", action.Symbol)
+	}
+	action.NewSymbol = newSymbol
+	description := describeCorporateAction(action)
+
+	orders, err := tx.GetOpenOrdersForUpdate(action.Symbol, openOrderStatuses...)
+	if err != nil {
+		return 0, err
+	}
+	for i := range orders {
+		order := &orders[i]
+		if _, _, err := tx.GetUserCashForUpdate(order.UserID); err != nil {
+			return 0, fmt.Errorf("failed to lock user: %w", err)
+		}
+		order.Symbol = newSymbol
+		if err := tx.AdjustOrder(order); err != nil {
+			return 0, err
+		}
+		if err := tx.RecordOrderEvent(order.ID, order.Status, order.Status, description); err != nil {
+			return 0, err
+		}
+	}
+
+	userIDs, err := tx.GetHolderIDs(action.Symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	renamed := 0
+	for _, userID := range userIDs {
+		holding, err := lockHolding(tx, userID, action.Symbol)
+		if err != nil {
+			return renamed, err
+		}
+		if holding == nil {
+			continue
+		}
+		existing, err := tx.GetUserHoldingForUpdate(userID, newSymbol)
+		if err != nil {
+			return renamed, err
+		}
+		if existing != nil && !(existing.Shares.IsZero() && existing.ReservedShares.IsZero()) {
+			return renamed, fmt.Errorf("user %s already holds %s", userID, newSymbol)
+		}
+
+		if err := tx.RenameHolding(userID, action.Symbol, newSymbol); err != nil {
+			return renamed, err
+		}
+
+		quantity, costBasis, err := tx.GetJournalPosition(userID, action.Symbol)
+		if err != nil {
+			return renamed, err
+		}
+		transactionID, err := tx.CreateTransaction(userID, newSymbol, holding.Shares, holding.AvgPrice,
+			models.CorporateActionRename, money.Zero, money.Zero, decimal.NullDecimal{})
+		if err != nil {
+			return renamed, err
+		}
+		err = tx.PostJournal(&models.JournalEntry{
+			UserID:        userID,
+			EntryType:     models.JournalRename,
+			TransactionID: &transactionID,
+			Description:   fmt.Sprintf("%s: %s shares", description, holding.Shares.String()),
+			Postings: []models.Posting{
+				{Account: models.LedgerAccountSecurities, Symbol: action.Symbol, Quantity: quantity.Neg(), Amount: costBasis.Neg()},
+				{Account: models.LedgerAccountSecurities, Symbol: newSymbol, Quantity: quantity, Amount: costBasis},
+			},
+		})
+		if err != nil {
+			return renamed, err
+		}
+		renamed++
+	}
+
+	return renamed, tx.RenameStockPrice(action.Symbol, newSymbol)
+}
+
+// GetCorporateActions lists scheduled and processed corporate actions
+func (s *PortfolioService) GetCorporateActions(ctx context.Context) ([]models.CorporateAction, error) {
+	return s.db.GetCorporateActions()
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// monthStart returns the start of t's calendar month in UTC, when tiered volume resets
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// calculateFee returns the commission on trading shares worth value under schedule, for a
+// user who has already traded monthlyVolume this month
+func calculateFee(schedule *models.FeeSchedule, shares decimal.Decimal, value decimal.Decimal, monthlyVolume decimal.Decimal) decimal.Decimal {
+	perShare, percent := schedule.PerShareFee, schedule.PercentFee
+	for _, tier := range schedule.Tiers {
+		if monthlyVolume.GreaterThanOrEqual(tier.MinVolume) {
+			perShare, percent = tier.PerShareFee, tier.PercentFee
+		}
+	}
+
+	fee := schedule.FlatFee.
+		Add(perShare.Mul(shares)).
+		Add(value.Mul(percent))
+	fee = decimal.Max(fee, schedule.MinFee)
+	if schedule.MaxFee.Valid {
+		fee = decimal.Min(fee, schedule.MaxFee.Decimal)
+	}
+	return money.RoundCash(fee)
+}
+
+// tradeFee returns the commission the account pays for trading shares worth value.
+// The user's row must already be locked so the monthly volume is current.
+func tradeFee(tx *database.Tx, account *models.Account, shares decimal.Decimal, value decimal.Decimal) (decimal.Decimal, error) {
+	schedule, err := tx.GetFeeSchedule(account.UserID, account.FeeSchedule)
+	if err != nil {
+		return money.Zero, err
+	}
+	if schedule == nil {
+		return money.Zero, fmt.Errorf("fee schedule %q not found", account.FeeSchedule)
+	}
+
+	volume, err := tx.GetTradeVolume(account.UserID, monthStart(time.Now()))
+	if err != nil {
+		return money.Zero, err
+	}
+	return calculateFee(schedule, shares, value, volume), nil
+}
+
+// GetFees returns the user's fee schedule, their trade volume this month and the plans they can choose
+func (s *PortfolioService) GetFees(ctx context.Context, userID string) (*models.FeeSummary, error) {
+	account, err := s.db.GetAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := s.db.GetTradeVolume(userID, monthStart(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := s.db.GetFeeSchedules(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FeeSummary{
+		FeeSchedule:   account.FeeSchedule,
+		MonthlyVolume: volume,
+		Schedules:     schedules,
+	}, nil
+}
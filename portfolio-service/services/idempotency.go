@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// idempotencyKeyTTL is how long a completed request's response is kept for replay
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLease is how long a claimed key stays in progress. A request that crashes
+	// or cannot store its response leaves its claim behind; once the lease runs out the
+	// key can be claimed again. It is far longer than any trade takes to run.
+	idempotencyLease = 5 * time.Minute
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrIdempotencyKeyInProgress is returned when the original request for a key has not finished
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Idempotent runs fn at most once per user and key. request identifies what is being
+// asked (e.g. method, path and body) so a reused key with a different request is
+// rejected. fn returns a status and an encoded response; responses with status >= 500
+// are not stored so the client can retry. A key whose request never finished can be
+// retried after idempotencyLease. replayed reports whether the stored response of an
+// earlier call was returned instead of running fn.
+func (s *PortfolioService) Idempotent(ctx context.Context, userID string, key string, request []byte,
+	fn func() (int, []byte)) (status int, body []byte, replayed bool, err error) {
+	sum := sha256.Sum256(request)
+	requestHash := hex.EncodeToString(sum[:])
+
+	record, claimed, err := s.db.ClaimIdempotencyKey(userID, key, requestHash, idempotencyKeyTTL, idempotencyLease)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	if !claimed {
+		if record.RequestHash != requestHash {
+			return 0, nil, false, ErrIdempotencyKeyReused
+		}
+		if !record.Completed {
+			return 0, nil, false, ErrIdempotencyKeyInProgress
+		}
+		fmt.Printf("Replaying response for idempotency key %s (user %s)\n", key, userID)
+		return record.ResponseStatus, record.ResponseBody, true, nil
+	}
+
+	status, body = fn()
+	if status >= 500 {
+		if err := s.db.ReleaseIdempotencyKey(userID, key); err != nil {
+			fmt.Printf("Failed to release idempotency key %s: %v\n", key, err)
+		}
+		return status, body, false, nil
+	}
+
+	if err := s.db.SaveIdempotentResponse(userID, key, status, body); err != nil {
+		// The trade already happened; report it, but retries see the key as in progress
+		// until the lease runs out
+		fmt.Printf("Failed to store response for idempotency key %s: %v\n", key, err)
+	}
+	return status, body, false, nil
+}
+
+// PurgeIdempotencyKeys deletes expired keys and abandoned claims, which are otherwise only
+// removed when the same user sends the same key again
+func (s *PortfolioService) PurgeIdempotencyKeys(ctx context.Context) error {
+	purged, err := s.db.PurgeIdempotencyKeys(idempotencyKeyTTL, idempotencyLease)
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		fmt.Printf("Purged %d expired idempotency keys\n", purged)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IdempotencyPurger periodically deletes expired idempotency keys
+type IdempotencyPurger struct {
+	portfolioService *PortfolioService
+	interval         time.Duration
+}
+
+// NewIdempotencyPurger creates a purger that runs every interval
+func NewIdempotencyPurger(portfolioService *PortfolioService, interval time.Duration) *IdempotencyPurger {
+	return &IdempotencyPurger{
+		portfolioService: portfolioService,
+		interval:         interval,
+	}
+}
+
+// Run purges until ctx is cancelled
+func (p *IdempotencyPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Idempotency key purger running every %s\n", p.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Idempotency key purger stopped")
+			return
+		case <-ticker.C:
+			if err := p.portfolioService.PurgeIdempotencyKeys(ctx); err != nil {
+				fmt.Printf("Idempotency key purge failed: %v\n", err)
+			}
+		}
+	}
+}
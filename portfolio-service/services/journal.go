@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"portfolio-service/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// journalEntryLimit caps the entries returned by GetJournal
+const journalEntryLimit = 100
+
+// tradePostings returns the journal postings balancing a trade's cash movement: SECURITIES
+// receives the shares at their cost, and any realized gain is credited to REALIZED_GAINS.
+// quantity is negative when selling.
+func tradePostings(symbol string, quantity decimal.Decimal, cashChange decimal.Decimal, realized decimal.NullDecimal) []models.Posting {
+	gain := decimal.Zero
+	if realized.Valid {
+		gain = realized.Decimal
+	}
+
+	postings := []models.Posting{{
+		Account:  models.LedgerAccountSecurities,
+		Symbol:   symbol,
+		Quantity: quantity,
+		Amount:   cashChange.Neg().Add(gain),
+	}}
+	if !gain.IsZero() {
+		postings = append(postings, models.Posting{Account: models.LedgerAccountRealizedGains, Amount: gain.Neg()})
+	}
+	return postings
+}
+
+// GetJournal returns the user's balances computed from postings and their latest journal entries
+func (s *PortfolioService) GetJournal(ctx context.Context, userID string) (*models.Journal, error) {
+	cash, positions, err := s.db.GetJournalBalances(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.db.GetJournalEntries(userID, journalEntryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Journal{
+		Cash:      cash,
+		Positions: positions,
+		Entries:   entries,
+	}, nil
+}
+
+// Reconcile checks every user's stored cash and holdings against the journal and cash
+// ledger, and every journal entry for balance. It returns the disagreements found.
+func (s *PortfolioService) Reconcile(ctx context.Context) ([]models.ReconciliationIssue, error) {
+	checks := []func() ([]models.ReconciliationIssue, error){
+		s.db.FindUnbalancedEntries,
+		s.db.FindCashMismatches,
+		s.db.FindLedgerMismatches,
+		s.db.FindHoldingMismatches,
+	}
+
+	issues := []models.ReconciliationIssue{}
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := check()
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+	return issues, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidLots is returned when the lots chosen for a sale cannot be used
+var ErrInvalidLots = errors.New("invalid lot selection")
+
+// lotMatch describes how a trade closes the open tax lots of a position
+type lotMatch struct {
+	sales    []models.LotSale
+	closed   decimal.Decimal     // shares of the existing position closed by the trade
+	realized decimal.NullDecimal // set when the trade closes shares
+	avgPrice decimal.NullDecimal // cost per share of the lots left open, when they cover the position
+}
+
+// matchLots picks the lots closed by a trade of delta shares (negative when selling) against
+// a position of heldShares at heldAvg, using the account's cost-basis method. lotIDs selects
+// the lots to close for the SPECIFIC method.
+func matchLots(tx *database.Tx, account *models.Account, symbol string, heldShares decimal.Decimal, heldAvg decimal.Decimal,
+	delta decimal.Decimal, price decimal.Decimal, lotIDs []int) (*lotMatch, error) {
+	match := &lotMatch{closed: decimal.Zero}
+	if heldShares.IsZero() || heldShares.Sign() == delta.Sign() {
+		// Opening or adding to a position realizes nothing
+		return match, nil
+	}
+	match.closed = decimal.Min(delta.Abs(), heldShares.Abs())
+
+	lots, err := tx.GetOpenLotsForUpdate(account.UserID, symbol, account.CostBasisMethod == models.CostBasisLIFO)
+	if err != nil {
+		return nil, err
+	}
+	// Only lots on the same side as the position can be closed. Shares not covered by them
+	// were held before lots were tracked, so they are the oldest in the position.
+	var open []models.TaxLot
+	untracked := heldShares.Abs()
+	for _, lot := range lots {
+		if lot.RemainingShares.Sign() == heldShares.Sign() {
+			open = append(open, lot)
+			untracked = untracked.Sub(lot.RemainingShares.Abs())
+		}
+	}
+	untracked = decimal.Max(untracked, decimal.Zero)
+
+	specific := account.CostBasisMethod == models.CostBasisSpecific && len(lotIDs) > 0
+	if specific {
+		if open, err = selectLots(open, lotIDs); err != nil {
+			return nil, err
+		}
+	} else if len(lotIDs) > 0 {
+		return nil, fmt.Errorf("%w: lot_ids require the %s cost-basis method", ErrInvalidLots, models.CostBasisSpecific)
+	}
+
+	// Long lots gain when the price rises, short lots when it falls
+	direction := decimal.NewFromInt(1)
+	if heldShares.IsNegative() {
+		direction = direction.Neg()
+	}
+
+	realized := money.Zero
+	remaining := match.closed
+	// Untracked shares are closed at the position's average price: first under FIFO, and
+	// after every lot under LIFO
+	if !specific && account.CostBasisMethod != models.CostBasisLIFO && untracked.IsPositive() {
+		shares := decimal.Min(remaining, untracked)
+		realized = realized.Add(money.Total(price.Sub(heldAvg), shares).Mul(direction))
+		remaining = remaining.Sub(shares)
+	}
+
+	taken := make(map[int]decimal.Decimal)
+	for _, lot := range open {
+		if remaining.IsZero() {
+			break
+		}
+		shares := decimal.Min(remaining, lot.RemainingShares.Abs())
+		cost := lot.CostPerShare
+		if account.CostBasisMethod == models.CostBasisAverage {
+			cost = heldAvg
+		}
+
+		sale := models.LotSale{
+			LotID:     lot.ID,
+			Shares:    shares,
+			CostBasis: money.Total(cost, shares),
+			Proceeds:  money.Total(price, shares),
+		}
+		sale.RealizedGain = sale.Proceeds.Sub(sale.CostBasis).Mul(direction)
+		match.sales = append(match.sales, sale)
+
+		realized = realized.Add(sale.RealizedGain)
+		taken[lot.ID] = shares
+		remaining = remaining.Sub(shares)
+	}
+
+	if remaining.IsPositive() {
+		if specific {
+			return nil, fmt.Errorf("%w: selected lots cover %s of the %s shares sold", ErrInvalidLots, match.closed.Sub(remaining).String(), match.closed.String())
+		}
+		gain := money.Total(price.Sub(heldAvg), remaining).Mul(direction)
+		realized = realized.Add(gain)
+	}
+	match.realized = decimal.NewNullDecimal(realized)
+
+	// With lot accounting the position's average price is the cost of the lots left open
+	if account.CostBasisMethod != models.CostBasisAverage && match.closed.LessThan(heldShares.Abs()) {
+		left := heldShares.Abs().Sub(match.closed)
+		shares, cost := decimal.Zero, money.Zero
+		for _, lot := range lots {
+			if lot.RemainingShares.Sign() != heldShares.Sign() {
+				continue
+			}
+			lotShares := lot.RemainingShares.Abs().Sub(taken[lot.ID])
+			shares = shares.Add(lotShares)
+			cost = cost.Add(lot.CostPerShare.Mul(lotShares))
+		}
+		if shares.Equal(left) {
+			match.avgPrice = decimal.NewNullDecimal(cost.DivRound(left, money.AvgPricePlaces))
+		}
+	}
+
+	return match, nil
+}
+
+// selectLots returns the lots named by lotIDs, in that order
+func selectLots(lots []models.TaxLot, lotIDs []int) ([]models.TaxLot, error) {
+	byID := make(map[int]models.TaxLot, len(lots))
+	for _, lot := range lots {
+		byID[lot.ID] = lot
+	}
+
+	selected := make([]models.TaxLot, 0, len(lotIDs))
+	seen := make(map[int]bool, len(lotIDs))
+	for _, id := range lotIDs {
+		lot, ok := byID[id]
+		if !ok || seen[id] {
+			return nil, fmt.Errorf("%w: lot %d is not an open lot of this position", ErrInvalidLots, id)
+		}
+		seen[id] = true
+		selected = append(selected, lot)
+	}
+	return selected, nil
+}
+
+// recordLots stores the lots a transaction closed and opens a lot for any shares it added
+func recordLots(tx *database.Tx, userID string, symbol string, transactionID int, match *lotMatch, delta decimal.Decimal, price decimal.Decimal) error {
+	if err := tx.RecordLotSales(transactionID, match.sales); err != nil {
+		return err
+	}
+
+	opened := delta.Abs().Sub(match.closed)
+	if opened.IsZero() {
+		return nil
+	}
+	if delta.IsNegative() {
+		opened = opened.Neg()
+	}
+	return tx.CreateLot(userID, symbol, transactionID, opened, price)
+}
+
+// GetLots returns a user's open tax lots, optionally for a single symbol
+func (s *PortfolioService) GetLots(ctx context.Context, userID string, symbol string) ([]models.TaxLot, error) {
+	return s.db.GetUserLots(userID, strings.ToUpper(symbol))
+}
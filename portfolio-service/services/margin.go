@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	one        = decimal.NewFromInt(1)
+	daysInYear = decimal.NewFromInt(365)
+)
+
+// applyTrade returns the position after adding delta shares (negative when selling) at price.
+// Adding to a position in the same direction averages the price in; reducing it keeps the
+// average; flipping from long to short or back starts a new position at price.
+func applyTrade(heldShares decimal.Decimal, heldAvg decimal.Decimal, delta decimal.Decimal, price decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	newShares := heldShares.Add(delta)
+
+	switch {
+	case heldShares.IsZero():
+		return newShares, price
+	case heldShares.Sign() == delta.Sign():
+		cost := money.Total(price, delta.Abs())
+		return newShares, money.AverageCost(heldShares.Abs(), heldAvg, delta.Abs(), cost)
+	case newShares.IsZero() || newShares.Sign() == heldShares.Sign():
+		return newShares, heldAvg
+	default:
+		return newShares, price
+	}
+}
+
+// currentPrices looks up market prices, falling back to the stock_prices table.
+// Symbols missing from the result should be valued at their average price.
+func (s *PortfolioService) currentPrices(ctx context.Context, symbols []string) map[string]decimal.Decimal {
+	if len(symbols) == 0 {
+		return make(map[string]decimal.Decimal)
+	}
+
+	// Get current prices from Market Data Service via gRPC
+	marketPrices, err := s.marketClient.GetMultipleStockPrices(ctx, symbols)
+	if err == nil {
+		return money.FromPrices(marketPrices)
+	}
+
+	fmt.Printf("Failed to get prices from market service: %v, falling back to database prices\n", err)
+	// Fall back to database prices
+	prices, err := s.db.GetStockPrices(symbols)
+	if err != nil {
+		fmt.Printf("Failed to get prices from database: %v, using average prices\n", err)
+		return make(map[string]decimal.Decimal) // Empty map will cause fallback to avg prices
+	}
+	return prices
+}
+
+// marginMarks prices every position of a margin account before a trade so the margin
+// check inside the transaction does not call the market service. Returns nil for cash accounts.
+func (s *PortfolioService) marginMarks(ctx context.Context, userID string, symbol string, price decimal.Decimal) (map[string]decimal.Decimal, error) {
+	account, err := s.db.GetAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	if account.AccountType != models.AccountTypeMargin {
+		return nil, nil
+	}
+
+	holdings, err := s.db.GetAllUserHoldings(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user holdings: %w", err)
+	}
+
+	var symbols []string
+	for _, holding := range holdings {
+		if holding.Symbol != symbol {
+			symbols = append(symbols, holding.Symbol)
+		}
+	}
+
+	marks := s.currentPrices(ctx, symbols)
+	marks[symbol] = price
+	return marks, nil
+}
+
+// marginSummary values an account's positions at prices (falling back to average prices)
+func marginSummary(account *models.Account, cash decimal.Decimal, reservedCash decimal.Decimal,
+	holdings []models.Holding, prices map[string]decimal.Decimal) *models.MarginSummary {
+	equity := cash.Sub(reservedCash)
+	gross := money.Zero
+	for _, holding := range holdings {
+		price, ok := prices[holding.Symbol]
+		if !ok {
+			price = holding.AvgPrice
+		}
+		value := money.Total(price, holding.Shares)
+		equity = equity.Add(value)
+		gross = gross.Add(value.Abs())
+	}
+
+	initial := money.RoundCash(gross.Mul(account.InitialMargin))
+	summary := &models.MarginSummary{
+		Equity:                 money.RoundCash(equity),
+		GrossPositionValue:     money.RoundCash(gross),
+		Borrowed:               decimal.Max(cash.Neg(), money.Zero),
+		InitialRequirement:     initial,
+		MaintenanceRequirement: money.RoundCash(gross.Mul(account.MaintenanceMargin)),
+		BuyingPower:            money.Zero,
+	}
+	summary.MarginCall = summary.Equity.LessThan(summary.MaintenanceRequirement)
+
+	// Excess equity over the initial requirement can support InitialMargin times as much new exposure
+	if excess := summary.Equity.Sub(initial); excess.IsPositive() && account.InitialMargin.IsPositive() {
+		summary.BuyingPower = excess.DivRound(account.InitialMargin, money.CashPlaces)
+	}
+	return summary
+}
+
+// checkInitialMargin rejects a trade that leaves a margin account with less equity than
+// the initial requirement. cash is the balance after the trade and newShares the
+// traded symbol's position after it.
+func checkInitialMargin(tx *database.Tx, account *models.Account, cash decimal.Decimal, reservedCash decimal.Decimal,
+	symbol string, newShares decimal.Decimal, prices map[string]decimal.Decimal) error {
+	holdings, err := tx.GetAllUserHoldings(account.UserID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range holdings {
+		if holdings[i].Symbol == symbol {
+			holdings[i].Shares = newShares
+			found = true
+		}
+	}
+	if !found {
+		holdings = append(holdings, models.Holding{Symbol: symbol, Shares: newShares})
+	}
+
+	summary := marginSummary(account, cash, reservedCash, holdings, prices)
+	if summary.Equity.LessThan(summary.InitialRequirement) {
+		return fmt.Errorf("%w: margin equity $%s is below the initial requirement of $%s", ErrInsufficientFunds,
+			summary.Equity.StringFixed(money.CashPlaces), summary.InitialRequirement.StringFixed(money.CashPlaces))
+	}
+	return nil
+}
+
+// accrueInterest charges interest on borrowed cash since the last accrual and returns the
+// new cash balance. The user's row must already be locked.
+func accrueInterest(tx *database.Tx, account *models.Account, cash decimal.Decimal, now time.Time) (decimal.Decimal, error) {
+	from := account.InterestAccruedAt
+	if !now.After(from) {
+		return cash, nil
+	}
+
+	interest := money.Zero
+	borrowed := decimal.Max(cash.Neg(), money.Zero)
+	if account.AccountType == models.AccountTypeMargin && borrowed.IsPositive() {
+		// Simple daily interest: borrowed * rate * days / 365
+		days := decimal.NewFromInt(int64(now.Sub(from))).Div(decimal.NewFromInt(int64(24 * time.Hour)))
+		interest = money.RoundCash(borrowed.Mul(account.InterestRate).Mul(days).Div(daysInYear))
+	}
+
+	if interest.IsPositive() {
+		description := fmt.Sprintf("margin interest on $%s borrowed", borrowed.StringFixed(money.CashPlaces))
+		entry, err := postCash(tx, account.UserID, models.LedgerInterest, interest.Neg(), nil, description)
+		if err != nil {
+			return cash, fmt.Errorf("failed to charge margin interest: %w", err)
+		}
+		cash = entry.BalanceAfter
+		fmt.Printf("Charged $%s margin interest to user %s\n", interest.StringFixed(money.CashPlaces), account.UserID)
+	} else if borrowed.IsPositive() {
+		// Less than a cent so far; keep accruing from the same date
+		return cash, nil
+	}
+
+	if err := tx.RecordMarginInterest(account.UserID, borrowed, interest, from, now); err != nil {
+		return cash, err
+	}
+	return cash, nil
+}
+
+// settleInterest locks the user's row and charges the margin interest owed so far, returning
+// the cash balance after it and the cash reserved for open orders
+func settleInterest(tx *database.Tx, userID string) (decimal.Decimal, decimal.Decimal, error) {
+	cash, reserved, err := tx.GetUserCashForUpdate(userID)
+	if err != nil {
+		return cash, reserved, fmt.Errorf("failed to get user cash: %w", err)
+	}
+	account, err := tx.GetAccount(userID)
+	if err != nil {
+		return cash, reserved, err
+	}
+	cash, err = accrueInterest(tx, account, cash, time.Now())
+	return cash, reserved, err
+}
+
+// CheckMarginAccounts charges the interest owed by every margin account, records a margin
+// call for each whose equity is below the maintenance requirement and resolves calls on
+// accounts that have recovered. It is called by MarginMonitor.
+func (s *PortfolioService) CheckMarginAccounts(ctx context.Context) error {
+	userIDs, err := s.db.GetMarginAccountIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+			_, _, err := settleInterest(tx, userID)
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Failed to accrue margin interest for user %s: %v\n", userID, err)
+			continue
+		}
+
+		portfolio, err := s.GetPortfolio(ctx, userID)
+		if err != nil {
+			fmt.Printf("Failed to value margin account of user %s: %v\n", userID, err)
+			continue
+		}
+		if portfolio.Margin != nil {
+			s.checkMarginCall(userID, portfolio.Margin)
+		}
+	}
+	return nil
+}
+
+// ErrInvalidAccount is returned when account settings are unknown or cannot apply to the account
+var ErrInvalidAccount = errors.New("invalid account settings")
+
+// GetAccount returns a user's account type, cost-basis method and margin settings
+func (s *PortfolioService) GetAccount(ctx context.Context, userID string) (*models.Account, error) {
+	return s.db.GetAccount(userID)
+}
+
+// UpdateAccount switches a user between cash and margin accounts and sets the cost-basis
+// method and fee schedule. Margin accounts use the requirements operators set on the account.
+func (s *PortfolioService) UpdateAccount(ctx context.Context, userID string, req models.AccountRequest) (*models.Account, error) {
+	var account *models.Account
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		cash, _, err := tx.GetUserCashForUpdate(userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user cash: %w", err)
+		}
+		account, err = tx.GetAccount(userID)
+		if err != nil {
+			return err
+		}
+		// Settle interest under the old settings before changing them
+		if cash, err = accrueInterest(tx, account, cash, time.Now()); err != nil {
+			return err
+		}
+
+		if req.AccountType != "" {
+			account.AccountType = strings.ToUpper(req.AccountType)
+		}
+		if req.CostBasisMethod != "" {
+			switch method := strings.ToUpper(req.CostBasisMethod); method {
+			case models.CostBasisFIFO, models.CostBasisLIFO, models.CostBasisSpecific, models.CostBasisAverage:
+				account.CostBasisMethod = method
+			default:
+				return fmt.Errorf("%w: invalid cost_basis_method %q", ErrInvalidAccount, req.CostBasisMethod)
+			}
+		}
+		if req.FeeSchedule != "" {
+			schedule, err := tx.GetFeeSchedule(userID, req.FeeSchedule)
+			if err != nil {
+				return err
+			}
+			if schedule == nil {
+				return fmt.Errorf("%w: unknown fee_schedule %q", ErrInvalidAccount, req.FeeSchedule)
+			}
+			if !schedule.Selectable && schedule.Name != account.FeeSchedule {
+				return fmt.Errorf("%w: fee_schedule %q is assigned by operators only", ErrInvalidAccount, req.FeeSchedule)
+			}
+			account.FeeSchedule = schedule.Name
+		}
+
+		switch account.AccountType {
+		case models.AccountTypeCash:
+			// Borrowed cash and short positions only exist in margin accounts
+			if cash.IsNegative() {
+				return fmt.Errorf("%w: cannot switch to a cash account while borrowing $%s", ErrInvalidAccount, cash.Neg().StringFixed(money.CashPlaces))
+			}
+			holdings, err := tx.GetAllUserHoldings(userID)
+			if err != nil {
+				return err
+			}
+			for _, holding := range holdings {
+				if holding.Shares.IsNegative() {
+					return fmt.Errorf("%w: cannot switch to a cash account with a short position in %s", ErrInvalidAccount, holding.Symbol)
+				}
+			}
+		case models.AccountTypeMargin:
+		default:
+			return fmt.Errorf("%w: invalid account_type %q", ErrInvalidAccount, req.AccountType)
+		}
+
+		return tx.UpdateAccount(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("User %s account updated: %s, %s cost basis (initial %s, maintenance %s), %s fees\n", userID, account.AccountType,
+		account.CostBasisMethod, account.InitialMargin.String(), account.MaintenanceMargin.String(), account.FeeSchedule)
+	return account, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MarginMonitor periodically charges margin interest and checks margin accounts for margin calls
+type MarginMonitor struct {
+	portfolioService *PortfolioService
+	interval         time.Duration
+}
+
+// NewMarginMonitor creates a monitor that checks margin accounts every interval
+func NewMarginMonitor(portfolioService *PortfolioService, interval time.Duration) *MarginMonitor {
+	return &MarginMonitor{
+		portfolioService: portfolioService,
+		interval:         interval,
+	}
+}
+
+// Run checks margin accounts until ctx is cancelled
+func (m *MarginMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Margin monitor running every %s\n", m.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Margin monitor stopped")
+			return
+		case <-ticker.C:
+			if err := m.portfolioService.CheckMarginAccounts(ctx); err != nil {
+				fmt.Printf("Margin account check failed: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OrderMatcher periodically fills and expires resting orders
+type OrderMatcher struct {
+	portfolioService *PortfolioService
+	interval         time.Duration
+}
+
+// NewOrderMatcher creates a matcher that polls market prices every interval
+func NewOrderMatcher(portfolioService *PortfolioService, interval time.Duration) *OrderMatcher {
+	return &OrderMatcher{
+		portfolioService: portfolioService,
+		interval:         interval,
+	}
+}
+
+// Run polls until ctx is cancelled
+func (m *OrderMatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Order matcher running every %s\n", m.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Order matcher stopped")
+			return
+		case <-ticker.C:
+			if err := m.portfolioService.ProcessOrders(ctx); err != nil {
+				fmt.Printf("Order matching failed: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+)
+
+var (
+	// ErrOrderNotFound is returned when an order does not exist or belongs to another user
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrInvalidOrderTransition is returned when an order cannot move to the requested status
+	ErrInvalidOrderTransition = errors.New("invalid order transition")
+	// ErrInvalidOrder is returned when an order request is missing or misuses a field
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+// orderTransitions lists the statuses each status may move to.
+// FILLED, CANCELLED, REJECTED and EXPIRED are final.
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending: {
+		models.OrderStatusPartiallyFilled,
+		models.OrderStatusFilled,
+		models.OrderStatusCancelled,
+		models.OrderStatusRejected,
+		models.OrderStatusExpired,
+	},
+	models.OrderStatusPartiallyFilled: {
+		models.OrderStatusPartiallyFilled,
+		models.OrderStatusFilled,
+		models.OrderStatusCancelled,
+		models.OrderStatusRejected,
+		models.OrderStatusExpired,
+	},
+}
+
+// openOrderStatuses are the statuses the matching engine still works on
+var openOrderStatuses = []string{models.OrderStatusPending, models.OrderStatusPartiallyFilled}
+
+// isOpenOrderStatus reports whether an order in status can still fill or be cancelled
+func isOpenOrderStatus(status string) bool {
+	return len(orderTransitions[status]) > 0
+}
+
+// canTransitionOrder reports whether an order may move from one status to another
+func canTransitionOrder(from string, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionOrder validates a status change and records it in the order's audit trail.
+// The caller persists the new status on the order row in the same transaction.
+func transitionOrder(tx *database.Tx, order *models.Order, to string, reason string) error {
+	if !canTransitionOrder(order.Status, to) {
+		return fmt.Errorf("%w: order %d is %s and cannot become %s", ErrInvalidOrderTransition, order.ID, order.Status, to)
+	}
+	if err := tx.RecordOrderEvent(order.ID, order.Status, to, reason); err != nil {
+		return err
+	}
+	order.Status = to
+	return nil
+}
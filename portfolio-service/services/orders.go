@@ -0,0 +1,587 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/events"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PlaceOrder validates a limit, stop or stop-limit order, reserves the cash or
+// shares it needs and stores it for the matching engine
+func (s *PortfolioService) PlaceOrder(ctx context.Context, userID string, req models.OrderRequest) (*models.Order, error) {
+	order, err := newOrder(userID, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireVerifiedEmail(userID); err != nil {
+		return nil, err
+	}
+
+	// Validate symbol exists
+	valid, err := s.marketClient.ValidateSymbol(ctx, order.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate symbol: %w", err)
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSymbol, order.Symbol)
+	}
+
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		if err := reserveForOrder(tx, order); err != nil {
+			return err
+		}
+		if err := tx.CreateOrder(order); err != nil {
+			return err
+		}
+		return tx.RecordOrderEvent(order.ID, "", order.Status, "order placed")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Order %d placed: %s %s %s %s\n", order.ID, order.OrderType, order.Side, order.Shares.String(), order.Symbol)
+	s.publish(userID, events.TypeOrder, order)
+	return order, nil
+}
+
+// reserveForOrder holds back the cash (buy) or shares (sell) the order needs and
+// stores the amounts on order. Any reservation the order already had is replaced,
+// so this is used both when placing and when amending an order.
+func reserveForOrder(tx *database.Tx, order *models.Order) error {
+	cash, reserved, err := tx.GetUserCashForUpdate(order.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user cash: %w", err)
+	}
+
+	if order.Side == models.OrderSideBuy {
+		// Hold back enough cash to pay the worst price the order accepts and its commission
+		account, err := tx.GetAccount(order.UserID)
+		if err != nil {
+			return err
+		}
+		needed := money.Total(reservationPrice(order), order.Shares)
+		fee, err := tradeFee(tx, account, order.Shares, needed)
+		if err != nil {
+			return fmt.Errorf("failed to calculate fee: %w", err)
+		}
+		needed = needed.Add(fee)
+		available := cash.Sub(reserved).Add(order.ReservedCash)
+		if available.LessThan(needed) {
+			return fmt.Errorf("%w: have $%s, need $%s", ErrInsufficientFunds,
+				available.StringFixed(money.CashPlaces), needed.StringFixed(money.CashPlaces))
+		}
+		if err := tx.AdjustReservedCash(order.UserID, needed.Sub(order.ReservedCash)); err != nil {
+			return err
+		}
+		order.ReservedCash = needed
+		return nil
+	}
+
+	holding, err := tx.GetUserHoldingForUpdate(order.UserID, order.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get holding: %w", err)
+	}
+	if holding == nil {
+		return fmt.Errorf("%w: no holdings found for symbol: %s", ErrInsufficientShares, order.Symbol)
+	}
+	available := holding.Shares.Sub(holding.ReservedShares).Add(order.ReservedShares)
+	if available.LessThan(order.Shares) {
+		return fmt.Errorf("%w: have %s, trying to sell %s", ErrInsufficientShares, available.String(), order.Shares.String())
+	}
+	if err := tx.AdjustReservedShares(order.UserID, order.Symbol, order.Shares.Sub(order.ReservedShares)); err != nil {
+		return err
+	}
+	order.ReservedShares = order.Shares
+	return nil
+}
+
+// newOrder normalizes and validates an order request
+func newOrder(userID string, req models.OrderRequest, now time.Time) (*models.Order, error) {
+	order := &models.Order{
+		UserID:      userID,
+		Symbol:      strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Side:        strings.ToUpper(req.Side),
+		OrderType:   strings.ToUpper(req.OrderType),
+		Shares:      req.Shares,
+		TimeInForce: strings.ToUpper(req.TimeInForce),
+		Status:      models.OrderStatusPending,
+	}
+
+	if order.Symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidOrder)
+	}
+	if !money.ValidShares(order.Shares) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidShares, order.Shares.String())
+	}
+	if order.Side != models.OrderSideBuy && order.Side != models.OrderSideSell {
+		return nil, fmt.Errorf("%w: invalid side %q", ErrInvalidOrder, req.Side)
+	}
+
+	if order.TimeInForce == "" {
+		order.TimeInForce = models.TimeInForceDay
+	}
+	switch order.TimeInForce {
+	case models.TimeInForceDay:
+		// DAY orders expire at the end of the current UTC trading day
+		endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+		order.ExpiresAt = &endOfDay
+	case models.TimeInForceGTC:
+	default:
+		return nil, fmt.Errorf("%w: invalid time_in_force %q", ErrInvalidOrder, req.TimeInForce)
+	}
+
+	needLimit := order.OrderType == models.OrderTypeLimit || order.OrderType == models.OrderTypeStopLimit
+	needStop := order.OrderType == models.OrderTypeStop || order.OrderType == models.OrderTypeStopLimit
+	if !needLimit && !needStop {
+		return nil, fmt.Errorf("%w: invalid order_type %q", ErrInvalidOrder, req.OrderType)
+	}
+
+	if needLimit {
+		if !req.LimitPrice.Valid || !req.LimitPrice.Decimal.IsPositive() {
+			return nil, fmt.Errorf("%w: limit_price is required for %s orders", ErrInvalidOrder, order.OrderType)
+		}
+		order.LimitPrice = decimal.NewNullDecimal(money.RoundCash(req.LimitPrice.Decimal))
+	}
+	if needStop {
+		if !req.StopPrice.Valid || !req.StopPrice.Decimal.IsPositive() {
+			return nil, fmt.Errorf("%w: stop_price is required for %s orders", ErrInvalidOrder, order.OrderType)
+		}
+		order.StopPrice = decimal.NewNullDecimal(money.RoundCash(req.StopPrice.Decimal))
+	}
+
+	return order, nil
+}
+
+// reservationPrice is the per-share price used to reserve cash for a buy order:
+// the limit price when there is one, otherwise the stop price
+func reservationPrice(order *models.Order) decimal.Decimal {
+	if order.LimitPrice.Valid {
+		return order.LimitPrice.Decimal
+	}
+	return order.StopPrice.Decimal
+}
+
+// GetOrders retrieves a user's orders
+func (s *PortfolioService) GetOrders(ctx context.Context, userID string) ([]models.Order, error) {
+	return s.db.GetUserOrders(userID)
+}
+
+// GetOrder retrieves one of a user's orders with its audit trail; returns nil if it does not exist
+func (s *PortfolioService) GetOrder(ctx context.Context, userID string, orderID int) (*models.Order, error) {
+	order, err := s.db.GetUserOrder(userID, orderID)
+	if err != nil || order == nil {
+		return order, err
+	}
+
+	order.Events, err = s.db.GetOrderEvents(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CancelOrder cancels one of a user's open orders and releases its reservation
+func (s *PortfolioService) CancelOrder(ctx context.Context, userID string, orderID int) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		order, err = lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil || order.UserID != userID {
+			return ErrOrderNotFound
+		}
+
+		if err := transitionOrder(tx, order, models.OrderStatusCancelled, "cancelled by user"); err != nil {
+			return err
+		}
+		if err := releaseReservation(tx, order); err != nil {
+			return err
+		}
+		return tx.CloseOrder(order.ID, models.OrderStatusCancelled, "cancelled by user")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Order %d cancelled by user %s\n", orderID, userID)
+	s.publishOrder(userID, orderID)
+	return s.GetOrder(ctx, userID, orderID)
+}
+
+// AmendOrder changes the shares, prices or time in force of a pending order,
+// re-reserving cash or shares for the new terms
+func (s *PortfolioService) AmendOrder(ctx context.Context, userID string, orderID int, req models.AmendOrderRequest) (*models.Order, error) {
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		order, err := lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil || order.UserID != userID {
+			return ErrOrderNotFound
+		}
+		if order.Status != models.OrderStatusPending {
+			return fmt.Errorf("%w: only %s orders can be amended, order %d is %s",
+				ErrInvalidOrderTransition, models.OrderStatusPending, order.ID, order.Status)
+		}
+
+		// Merge the requested changes into the current terms and validate them like a new order
+		merged := models.OrderRequest{
+			Symbol:      order.Symbol,
+			Side:        order.Side,
+			OrderType:   order.OrderType,
+			Shares:      order.Shares,
+			LimitPrice:  order.LimitPrice,
+			StopPrice:   order.StopPrice,
+			TimeInForce: order.TimeInForce,
+		}
+		if req.Shares.Valid {
+			merged.Shares = req.Shares.Decimal
+		}
+		if req.LimitPrice.Valid {
+			merged.LimitPrice = req.LimitPrice
+		}
+		if req.StopPrice.Valid {
+			merged.StopPrice = req.StopPrice
+		}
+		if req.TimeInForce != "" {
+			merged.TimeInForce = req.TimeInForce
+		}
+
+		amended, err := newOrder(userID, merged, time.Now())
+		if err != nil {
+			return err
+		}
+		if merged.TimeInForce == order.TimeInForce {
+			// Keep the original expiry unless the time in force changed
+			amended.ExpiresAt = order.ExpiresAt
+		}
+
+		// A new stop price has to be reached again before the order can execute
+		if order.StopPrice.Valid != amended.StopPrice.Valid || !order.StopPrice.Decimal.Equal(amended.StopPrice.Decimal) {
+			order.Triggered = false
+		}
+		order.Shares = amended.Shares
+		order.LimitPrice = amended.LimitPrice
+		order.StopPrice = amended.StopPrice
+		order.TimeInForce = amended.TimeInForce
+		order.ExpiresAt = amended.ExpiresAt
+
+		if err := reserveForOrder(tx, order); err != nil {
+			return err
+		}
+		if err := tx.AmendOrder(order); err != nil {
+			return err
+		}
+		return tx.RecordOrderEvent(order.ID, order.Status, order.Status, "amended by user")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Order %d amended by user %s\n", orderID, userID)
+	s.publishOrder(userID, orderID)
+	return s.GetOrder(ctx, userID, orderID)
+}
+
+// evaluateOrder decides what to do with an order at the given market price.
+// triggered reports whether a stop price is reached; fill reports whether the order executes now.
+func evaluateOrder(order *models.Order, price decimal.Decimal) (triggered bool, fill bool) {
+	buy := order.Side == models.OrderSideBuy
+
+	triggered = order.Triggered
+	if !triggered && order.StopPrice.Valid {
+		stop := order.StopPrice.Decimal
+		// Buy stops trigger when the price rises to the stop, sell stops when it falls to it
+		triggered = (buy && price.GreaterThanOrEqual(stop)) || (!buy && price.LessThanOrEqual(stop))
+	}
+
+	switch order.OrderType {
+	case models.OrderTypeStop:
+		return triggered, triggered
+	case models.OrderTypeStopLimit:
+		if !triggered {
+			return false, false
+		}
+	}
+
+	limit := order.LimitPrice.Decimal
+	if buy {
+		return triggered, price.LessThanOrEqual(limit)
+	}
+	return triggered, price.GreaterThanOrEqual(limit)
+}
+
+// ProcessOrders expires stale orders and fills every open order whose
+// conditions are met at current market prices. It is called by OrderMatcher.
+func (s *PortfolioService) ProcessOrders(ctx context.Context) error {
+	expired, err := s.db.GetExpiredOrderIDs(time.Now(), openOrderStatuses...)
+	if err != nil {
+		return err
+	}
+	for _, id := range expired {
+		if err := s.closeOrder(ctx, id, models.OrderStatusExpired, "time in force ended"); err != nil {
+			fmt.Printf("Failed to expire order %d: %v\n", id, err)
+		}
+	}
+
+	orders, err := s.db.GetOrdersByStatus(openOrderStatuses...)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, order := range orders {
+		if !seen[order.Symbol] {
+			seen[order.Symbol] = true
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+
+	marketPrices, err := s.marketClient.GetMultipleStockPrices(ctx, symbols)
+	if err != nil {
+		return fmt.Errorf("failed to get prices: %w", err)
+	}
+	prices := money.FromPrices(marketPrices)
+
+	for i := range orders {
+		order := &orders[i]
+		price, ok := prices[order.Symbol]
+		if !ok {
+			continue
+		}
+
+		triggered, fill := evaluateOrder(order, price)
+		if !fill {
+			if triggered && !order.Triggered {
+				err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+					if err := tx.MarkOrderTriggered(order.ID); err != nil {
+						return err
+					}
+					return tx.RecordOrderEvent(order.ID, order.Status, order.Status, "stop price reached")
+				})
+				if err != nil {
+					fmt.Printf("Failed to trigger order %d: %v\n", order.ID, err)
+				} else {
+					s.publishOrder(order.UserID, order.ID)
+				}
+			}
+			continue
+		}
+
+		err := s.fillOrder(ctx, order, price)
+		if errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrInsufficientShares) {
+			// Nothing more can be filled (e.g. the rest of a partially filled order is unfunded)
+			if err := s.closeOrder(ctx, order.ID, models.OrderStatusRejected, err.Error()); err != nil {
+				fmt.Printf("Failed to reject order %d: %v\n", order.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Failed to fill order %d: %v\n", order.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// fillOrder executes a pending order at price through the same path as BuyStock/SellStock
+func (s *PortfolioService) fillOrder(ctx context.Context, pending *models.Order, price decimal.Decimal) error {
+	marks, err := s.marginMarks(ctx, pending.UserID, pending.Symbol, price)
+	if err != nil {
+		return err
+	}
+
+	var result *models.TradeResult
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		order, err := lockOrder(tx, pending.ID)
+		if err != nil || order == nil || !isOpenOrderStatus(order.Status) {
+			return err
+		}
+		// The order may have been amended since it was evaluated; only fill it if it still executes
+		triggered, fill := evaluateOrder(order, price)
+		if !fill {
+			return nil
+		}
+
+		// A fill consumes the order's whole reservation, so a partial fill leaves the rest unfunded
+		shares := order.Shares.Sub(order.FilledShares)
+		if order.Side == models.OrderSideBuy {
+			shares, err = fundedShares(tx, order, shares, price)
+			if err != nil {
+				return err
+			}
+			result, err = s.buyInTx(tx, order.UserID, order.Symbol, shares, price, order.ReservedCash, marks)
+		} else {
+			result, err = s.sellInTx(tx, order.UserID, order.Symbol, shares, price, order.ReservedShares, marks, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		status := models.OrderStatusFilled
+		filled := order.FilledShares.Add(shares)
+		if filled.LessThan(order.Shares) {
+			status = models.OrderStatusPartiallyFilled
+		}
+		reason := fmt.Sprintf("filled %s shares at $%s", shares.String(), price.StringFixed(money.CashPlaces))
+		if err := transitionOrder(tx, order, status, reason); err != nil {
+			return err
+		}
+
+		// filled_price is the average price over all of the order's fills
+		paid := money.Total(price, shares)
+		if order.FilledPrice.Valid {
+			paid = paid.Add(money.Total(order.FilledPrice.Decimal, order.FilledShares))
+		}
+		order.FilledPrice = decimal.NewNullDecimal(paid.DivRound(filled, money.CashPlaces))
+		order.FilledShares = filled
+		order.Triggered = triggered
+		order.ReservedCash, order.ReservedShares = money.Zero, decimal.Zero
+		if err := tx.FillOrder(order, result.TransactionID); err != nil {
+			return err
+		}
+
+		fmt.Printf("Order %d %s: %s %s %s at $%s\n", order.ID, strings.ToLower(status), order.Side, shares.String(), order.Symbol, price.StringFixed(money.CashPlaces))
+		return nil
+	})
+	if err != nil || result == nil {
+		return err
+	}
+
+	s.publish(pending.UserID, events.TypeTransaction, result)
+	s.publishOrder(pending.UserID, pending.ID)
+	return nil
+}
+
+// fundedShares returns how many of an order's remaining shares a cash account can pay for
+// at price with the order's reservation and its free cash. This is all of them unless the
+// price gapped through a stop, in which case the order fills what the cash covers and the
+// rest stays open until a later pass either pays for it or rejects it. Margin accounts may
+// borrow and are checked by buyInTx instead.
+func fundedShares(tx *database.Tx, order *models.Order, remaining decimal.Decimal, price decimal.Decimal) (decimal.Decimal, error) {
+	account, err := tx.GetAccount(order.UserID)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if account.AccountType == models.AccountTypeMargin {
+		return remaining, nil
+	}
+
+	cash, reserved, err := tx.GetUserCashForUpdate(order.UserID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get user cash: %w", err)
+	}
+	schedule, err := tx.GetFeeSchedule(account.UserID, account.FeeSchedule)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if schedule == nil {
+		return decimal.Zero, fmt.Errorf("fee schedule %q not found", account.FeeSchedule)
+	}
+	volume, err := tx.GetTradeVolume(account.UserID, monthStart(time.Now()))
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	available := cash.Sub(reserved).Add(order.ReservedCash)
+	shares := affordableShares(remaining, available, func(shares decimal.Decimal) decimal.Decimal {
+		value := money.Total(price, shares)
+		return value.Add(calculateFee(schedule, shares, value, volume))
+	})
+	if !shares.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: have $%s, cannot buy %s shares of %s at $%s", ErrInsufficientFunds,
+			available.StringFixed(money.CashPlaces), decimal.New(1, -money.SharePlaces).String(), order.Symbol, price.StringFixed(money.CashPlaces))
+	}
+	return shares, nil
+}
+
+// affordableShares returns the largest quantity up to max, in steps of SharePlaces, whose
+// cost is within available. cost must not decrease as the quantity grows.
+func affordableShares(max decimal.Decimal, available decimal.Decimal, cost func(decimal.Decimal) decimal.Decimal) decimal.Decimal {
+	if cost(max).LessThanOrEqual(available) {
+		return max
+	}
+	// Binary search over whole units of the smallest share fraction
+	low, high := int64(0), max.Shift(money.SharePlaces).IntPart()
+	for low < high {
+		mid := low + (high-low+1)/2
+		if cost(decimal.New(mid, -money.SharePlaces)).LessThanOrEqual(available) {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return decimal.New(low, -money.SharePlaces)
+}
+
+// closeOrder moves an open order to a final status and releases its reservation
+func (s *PortfolioService) closeOrder(ctx context.Context, orderID int, status string, reason string) error {
+	var userID string
+	err := s.db.WithTx(ctx, func(tx *database.Tx) error {
+		order, err := lockOrder(tx, orderID)
+		if err != nil || order == nil || !isOpenOrderStatus(order.Status) {
+			return err
+		}
+
+		if err := transitionOrder(tx, order, status, reason); err != nil {
+			return err
+		}
+		if err := releaseReservation(tx, order); err != nil {
+			return err
+		}
+		if err := tx.CloseOrder(order.ID, status, reason); err != nil {
+			return err
+		}
+		userID = order.UserID
+		return nil
+	})
+	if err != nil || userID == "" {
+		return err
+	}
+
+	s.publishOrder(userID, orderID)
+	return nil
+}
+
+// lockOrder locks an order and then its user's row. Orders are always locked
+// before users, and nothing locks a user before an existing order, so this
+// cannot deadlock with trades. Returns nil if the order does not exist.
+func lockOrder(tx *database.Tx, orderID int) (*models.Order, error) {
+	order, err := tx.GetOrderForUpdate(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if _, _, err := tx.GetUserCashForUpdate(order.UserID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	return order, nil
+}
+
+// releaseReservation returns an order's reserved cash or shares to the user
+func releaseReservation(tx *database.Tx, order *models.Order) error {
+	if !order.ReservedCash.IsZero() {
+		if err := tx.AdjustReservedCash(order.UserID, order.ReservedCash.Neg()); err != nil {
+			return err
+		}
+	}
+	if !order.ReservedShares.IsZero() {
+		if err := tx.AdjustReservedShares(order.UserID, order.Symbol, order.ReservedShares.Neg()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"portfolio-service/models"
+	"portfolio-service/money"
+)
+
+func TestAffordableShares(t *testing.T) {
+	// $10 a share plus a $1 commission with a 1% fee on the value
+	schedule := &models.FeeSchedule{FlatFee: decimal.NewFromInt(1), PercentFee: decimal.RequireFromString("0.01")}
+	price := decimal.NewFromInt(10)
+	cost := func(shares decimal.Decimal) decimal.Decimal {
+		value := money.Total(price, shares)
+		return value.Add(calculateFee(schedule, shares, value, decimal.Zero))
+	}
+
+	tests := []struct {
+		name      string
+		max       string
+		available string
+		want      string
+	}{
+		{"covers everything", "5", "100", "5"},
+		{"exactly covers", "5", "51.50", "5"},
+		// Values round to the cent: 2.970499 shares cost 29.70 + 1.30 = 31.00, 2.9705 cost 31.01
+		{"partial", "5", "31", "2.970499"},
+		// 0.297499 shares cost 2.97 + 1.03 = 4.00
+		{"fractional remainder", "0.5", "4", "0.297499"},
+		{"less than the flat fee", "5", "0.99", "0"},
+	}
+	for _, tt := range tests {
+		max, available := decimal.RequireFromString(tt.max), decimal.RequireFromString(tt.available)
+		got := affordableShares(max, available, cost)
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: affordableShares(%s, $%s) = %s, want %s", tt.name, tt.max, tt.available, got, tt.want)
+		}
+		if got.IsPositive() && cost(got).GreaterThan(available) {
+			t.Errorf("%s: %s shares cost $%s, more than $%s", tt.name, got, cost(got), tt.available)
+		}
+	}
+}
+
+func TestEvaluateStopLimitOrder(t *testing.T) {
+	order := &models.Order{
+		Side:       models.OrderSideBuy,
+		OrderType:  models.OrderTypeStopLimit,
+		StopPrice:  decimal.NewNullDecimal(decimal.NewFromInt(100)),
+		LimitPrice: decimal.NewNullDecimal(decimal.NewFromInt(102)),
+	}
+
+	if triggered, fill := evaluateOrder(order, decimal.NewFromInt(99)); triggered || fill {
+		t.Errorf("below the stop: triggered %t, fill %t, want neither", triggered, fill)
+	}
+	if triggered, fill := evaluateOrder(order, decimal.NewFromInt(105)); !triggered || fill {
+		t.Errorf("through the stop above the limit: triggered %t, fill %t, want triggered only", triggered, fill)
+	}
+
+	// Once triggered the order fills at or below its limit, even back under the stop
+	order.Triggered = true
+	if _, fill := evaluateOrder(order, decimal.NewFromInt(98)); !fill {
+		t.Error("triggered order under its limit did not fill")
+	}
+}
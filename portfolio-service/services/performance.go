@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"portfolio-service/analytics"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// riskFreeRate reads RISK_FREE_RATE (an annual fraction such as 0.04) for Sharpe ratios, defaulting to zero
+func riskFreeRate() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("RISK_FREE_RATE"), 64)
+	if err != nil {
+		return 0
+	}
+	return rate
+}
+
+// GetPerformance computes return and risk statistics for a user's holdings between from and to
+// (inclusive dates). Valuations come from daily snapshots; buys count as money invested and
+// sells as money taken out, so returns are not skewed by when trades were made.
+func (s *PortfolioService) GetPerformance(ctx context.Context, userID string, from time.Time, to time.Time) (*models.Performance, error) {
+	snapshots, err := s.GetHistory(ctx, userID, from, to, models.IntervalDay)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) < 2 {
+		return nil, fmt.Errorf("%w: found %d snapshots between %s and %s", analytics.ErrNotEnoughData, len(snapshots),
+			from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+
+	points := make([]analytics.Point, len(snapshots))
+	for i, snapshot := range snapshots {
+		points[i] = analytics.Point{Time: snapshot.CreatedAt, Value: snapshot.HoldingsValue.InexactFloat64()}
+	}
+
+	transactions, err := s.db.GetUserTransactionsBetween(userID, first.Date, last.Date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	var flows []analytics.CashFlow
+	netFlow := money.Zero
+	for _, transaction := range transactions {
+		// Fees are part of what a buy costs and come out of what a sale returns. Dividends
+		// leave the holdings as cash; splits and renames move no money.
+		var amount decimal.Decimal
+		switch transaction.TransactionType {
+		case "BUY":
+			amount = transaction.TotalAmount.Add(transaction.Fee)
+		case "SELL":
+			amount = transaction.TotalAmount.Sub(transaction.Fee).Neg()
+		case models.CorporateActionDividend:
+			amount = transaction.TotalAmount.Neg()
+		default:
+			continue
+		}
+		flows = append(flows, analytics.CashFlow{Time: transaction.Timestamp, Amount: amount.InexactFloat64()})
+		if transaction.Timestamp.After(first.CreatedAt) && !transaction.Timestamp.After(last.CreatedAt) {
+			netFlow = netFlow.Add(amount)
+		}
+	}
+
+	returns := analytics.PeriodReturns(points, flows)
+	performance := &models.Performance{
+		From:               first.Date,
+		To:                 last.Date,
+		StartValue:         first.HoldingsValue,
+		EndValue:           last.HoldingsValue,
+		NetCashFlow:        netFlow,
+		TimeWeightedReturn: analytics.TimeWeightedReturn(returns),
+		MaxDrawdown:        analytics.MaxDrawdown(returns),
+		Volatility:         analytics.Volatility(returns),
+		Benchmark:          analytics.DefaultBenchmark,
+	}
+
+	if mwr, err := analytics.MoneyWeightedReturn(points, flows); err == nil {
+		performance.MoneyWeightedReturn = &mwr
+	}
+	if sharpe, ok := analytics.SharpeRatio(returns, riskFreeRate()); ok {
+		performance.SharpeRatio = &sharpe
+	}
+
+	// price_history only has the days a snapshot was taken, so a window that ends today is
+	// measured to the current price, which falls back to stock_prices
+	start, end, err := s.db.GetPriceRange(analytics.DefaultBenchmark, first.Date, last.Date)
+	if err != nil {
+		return nil, err
+	}
+	if start.Valid && last.Date.Equal(snapshotDate(time.Now())) {
+		if price, ok := s.currentPrices(ctx, []string{analytics.DefaultBenchmark})[analytics.DefaultBenchmark]; ok {
+			end = decimal.NewNullDecimal(price)
+		}
+	}
+	if start.Valid && end.Valid {
+		if benchmark, ok := analytics.SimpleReturn(start.Decimal.InexactFloat64(), end.Decimal.InexactFloat64()); ok {
+			excess := performance.TimeWeightedReturn - benchmark
+			performance.BenchmarkReturn = &benchmark
+			performance.ExcessReturn = &excess
+		}
+	}
+
+	return performance, nil
+}
@@ -2,68 +2,77 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"portfolio-service/database"
+	"portfolio-service/events"
 	grpcclient "portfolio-service/grpc-client"
 	"portfolio-service/models"
+	"portfolio-service/money"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type PortfolioService struct {
-	db           *database.DB
-	marketClient *grpcclient.MarketClient
+	db             *database.DB
+	marketClient   *grpcclient.MarketClient
+	events         *events.Bus
+	streamInterval time.Duration
 }
 
 func NewPortfolioService(db *database.DB, marketClient *grpcclient.MarketClient) *PortfolioService {
 	return &PortfolioService{
-		db:           db,
-		marketClient: marketClient,
+		db:             db,
+		marketClient:   marketClient,
+		events:         events.NewBus(streamBuffer),
+		streamInterval: streamInterval(),
 	}
 }
 
-// GetPortfolio retrieves a user's complete portfolio with current prices
+// GetPortfolio retrieves a user's complete portfolio with current prices. It only reads:
+// margin interest is charged by trades, cash movements and MarginMonitor.
 func (s *PortfolioService) GetPortfolio(ctx context.Context, userID string) (*models.Portfolio, error) {
+	account, err := s.db.GetAccount(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
 	// Get user's cash balance
 	cash, err := s.db.GetUserCash(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user cash: %w", err)
 	}
 
+	// Get cash held back for open buy orders
+	reservedCash, err := s.db.GetUserReservedCash(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserved cash: %w", err)
+	}
+
 	// Get user's stock holdings
 	holdings, err := s.db.GetAllUserHoldings(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user holdings: %w", err)
 	}
 
-	// If no holdings, return portfolio with just cash
-	if len(holdings) == 0 {
-		return &models.Portfolio{
-			UserID:     userID,
-			Cash:       cash,
-			TotalValue: cash,
-			Holdings:   []models.Holding{},
-		}, nil
-	}
-
 	// Get symbols for price lookup
 	symbols := make([]string, len(holdings))
 	for i, holding := range holdings {
 		symbols[i] = holding.Symbol
 	}
 
-	// Get current prices from Market Data Service via gRPC
-	prices, err := s.marketClient.GetMultipleStockPrices(ctx, symbols)
+	prices := s.currentPrices(ctx, symbols)
+
+	// Get gains realized by past sales
+	realized, err := s.db.GetRealizedGain(userID)
 	if err != nil {
-		fmt.Printf("Failed to get prices from market service: %v, falling back to database prices\n", err)
-		// Fall back to database prices
-		prices, err = s.db.GetStockPrices(symbols)
-		if err != nil {
-			fmt.Printf("Failed to get prices from database: %v, using average prices\n", err)
-			prices = make(map[string]float64) // Empty map will cause fallback to avg prices
-		}
+		return nil, fmt.Errorf("failed to get realized gain: %w", err)
 	}
 
-	// Calculate portfolio values
+	// Calculate portfolio values; short positions have negative shares and value
 	totalValue := cash
+	unrealized := money.Zero
 	for i := range holdings {
 		currentPrice, exists := prices[holdings[i].Symbol]
 		if !exists {
@@ -71,182 +80,454 @@ func (s *PortfolioService) GetPortfolio(ctx context.Context, userID string) (*mo
 		}
 
 		holdings[i].CurrentPrice = currentPrice
-		holdings[i].TotalValue = float64(holdings[i].Shares) * currentPrice
-		holdings[i].GainLoss = holdings[i].TotalValue - (float64(holdings[i].Shares) * holdings[i].AvgPrice)
+		holdings[i].TotalValue = money.RoundCash(money.Total(currentPrice, holdings[i].Shares))
+		holdings[i].GainLoss = money.RoundCash(holdings[i].TotalValue.Sub(money.Total(holdings[i].AvgPrice, holdings[i].Shares)))
 
-		totalValue += holdings[i].TotalValue
+		totalValue = totalValue.Add(holdings[i].TotalValue)
+		unrealized = unrealized.Add(holdings[i].GainLoss)
 	}
 
-	return &models.Portfolio{
-		UserID:     userID,
-		Cash:       cash,
-		TotalValue: totalValue,
-		Holdings:   holdings,
-	}, nil
+	if holdings == nil {
+		holdings = []models.Holding{}
+	}
+
+	portfolio := &models.Portfolio{
+		UserID:       userID,
+		Cash:         cash,
+		ReservedCash: reservedCash,
+		TotalValue:   totalValue,
+		Holdings:     holdings,
+
+		RealizedGainLoss:   realized,
+		UnrealizedGainLoss: unrealized,
+	}
+
+	if account.AccountType == models.AccountTypeMargin {
+		portfolio.Margin = marginSummary(account, cash, reservedCash, holdings, prices)
+	}
+
+	return portfolio, nil
 }
 
-// BuyStock processes a stock purchase
-func (s *PortfolioService) BuyStock(ctx context.Context, userID string, symbol string, shares int) error {
-	if shares <= 0 {
-		return fmt.Errorf("invalid shares amount: %d", shares)
+// checkMarginCall records a margin call when equity falls below the maintenance
+// requirement and resolves open calls once it recovers
+func (s *PortfolioService) checkMarginCall(userID string, summary *models.MarginSummary) {
+	if !summary.MarginCall {
+		if err := s.db.ResolveMarginCalls(userID); err != nil {
+			fmt.Printf("Failed to resolve margin calls for user %s: %v\n", userID, err)
+		}
+		return
+	}
+
+	opened, err := s.db.OpenMarginCall(userID, summary.Equity, summary.MaintenanceRequirement)
+	if err != nil {
+		fmt.Printf("Failed to record margin call for user %s: %v\n", userID, err)
+		return
+	}
+	if opened {
+		fmt.Printf("Margin call for user %s: equity $%s below maintenance requirement $%s\n", userID,
+			summary.Equity.StringFixed(money.CashPlaces), summary.MaintenanceRequirement.StringFixed(money.CashPlaces))
+	}
+}
+
+// ErrInsufficientFunds and ErrInsufficientShares are returned when a trade cannot be covered
+var (
+	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrInsufficientShares = errors.New("insufficient shares")
+)
+
+// ErrEmailNotVerified is returned when an account that has not confirmed its email tries to trade
+var ErrEmailNotVerified = errors.New("confirm your email address before trading")
+
+// requireVerifiedEmail refuses to trade for accounts whose email is not confirmed yet
+func (s *PortfolioService) requireVerifiedEmail(userID string) error {
+	verified, err := s.db.IsEmailVerified(userID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// ErrInvalidShares is returned for share quantities that are not positive or have too many decimal places
+var ErrInvalidShares = fmt.Errorf("shares must be positive with at most %d decimal places", money.SharePlaces)
+
+// ErrInvalidSymbol is returned for symbols the market data service does not quote
+var ErrInvalidSymbol = errors.New("invalid stock symbol")
+
+// ErrAmountTooSmall is returned when a dollar amount buys less than the smallest fractional share
+var ErrAmountTooSmall = errors.New("amount is too small")
+
+// rejections are the errors that refuse a trade because of what was asked (bad input, too
+// little cash or shares) rather than because something failed along the way
+var rejections = []error{
+	ErrInsufficientFunds, ErrInsufficientShares, ErrEmailNotVerified, ErrInvalidShares, ErrInvalidAmount,
+	ErrInvalidSymbol, ErrAmountTooSmall, ErrInvalidOrder, ErrInvalidLots, ErrOrderNotFound, ErrInvalidOrderTransition,
+	ErrInvalidAccount,
+}
+
+// IsRejection reports whether err refused a trade for what was asked, so that asking again
+// would fail the same way. Other errors, such as the database or market data service being
+// unavailable, may succeed when retried.
+func IsRejection(err error) bool {
+	for _, target := range rejections {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuyStock processes a stock purchase of a whole or fractional number of shares
+func (s *PortfolioService) BuyStock(ctx context.Context, userID string, symbol string, shares decimal.Decimal) (*models.TradeResult, error) {
+	if !money.ValidShares(shares) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidShares, shares.String())
+	}
+	return s.buy(ctx, userID, symbol, shares, decimal.NullDecimal{})
+}
+
+// BuyStockAmount buys amount dollars' worth of shares at the current price, rounded down to
+// SharePlaces; the commission is charged on top of the amount
+func (s *PortfolioService) BuyStockAmount(ctx context.Context, userID string, symbol string, amount decimal.Decimal) (*models.TradeResult, error) {
+	if !amount.IsPositive() || !money.RoundCash(amount).Equal(amount) {
+		return nil, ErrInvalidAmount
+	}
+	return s.buy(ctx, userID, symbol, decimal.Zero, decimal.NewNullDecimal(amount))
+}
+
+// buy prices and executes a purchase of shares, or of amount dollars' worth when amount is set
+func (s *PortfolioService) buy(ctx context.Context, userID string, symbol string, shares decimal.Decimal, amount decimal.NullDecimal) (*models.TradeResult, error) {
+	if err := s.requireVerifiedEmail(userID); err != nil {
+		return nil, err
 	}
 
 	// Validate symbol exists
 	valid, err := s.marketClient.ValidateSymbol(ctx, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to validate symbol: %w", err)
+		return nil, fmt.Errorf("failed to validate symbol: %w", err)
 	}
 	if !valid {
-		return fmt.Errorf("invalid stock symbol: %s", symbol)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSymbol, symbol)
 	}
 
-	// Get current stock price
-	price, _, err := s.marketClient.GetStockPrice(ctx, symbol)
+	// Get current stock price before opening the transaction so no locks are held during the gRPC call
+	marketPrice, _, err := s.marketClient.GetStockPrice(ctx, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to get stock price: %w", err)
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
 	}
 
-	totalCost := float64(shares) * price
+	price := money.FromPrice(marketPrice)
+	if !price.IsPositive() {
+		return nil, fmt.Errorf("no price available for %s", symbol)
+	}
 
-	// Check if user has enough cash
-	cash, err := s.db.GetUserCash(userID)
+	if amount.Valid {
+		shares = money.RoundShares(amount.Decimal.Div(price))
+		if !shares.IsPositive() {
+			return nil, fmt.Errorf("%w: $%s buys less than %s shares of %s at $%s", ErrAmountTooSmall, amount.Decimal.StringFixed(money.CashPlaces),
+				decimal.New(1, -money.SharePlaces).String(), symbol, price.StringFixed(money.CashPlaces))
+		}
+	}
+
+	marks, err := s.marginMarks(ctx, userID, symbol, price)
 	if err != nil {
-		return fmt.Errorf("failed to get user cash: %w", err)
+		return nil, err
 	}
 
-	if cash < totalCost {
-		return fmt.Errorf("insufficient funds: have $%.2f, need $%.2f", cash, totalCost)
+	var result *models.TradeResult
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		result, err = s.buyInTx(tx, userID, symbol, shares, price, money.Zero, marks)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Start database transaction
-	tx, err := s.db.BeginTx(ctx)
+	fmt.Printf("Successfully bought %s shares of %s for $%s plus $%s fee\n", shares.String(), symbol,
+		result.TotalAmount.StringFixed(money.CashPlaces), result.Fee.StringFixed(money.CashPlaces))
+	s.publish(userID, events.TypeTransaction, result)
+	return result, nil
+}
+
+// buyInTx applies a purchase at price inside tx. releasedCash is the part of the
+// user's reserved cash that belonged to the order being filled (zero for market orders).
+// marks prices the user's positions for the margin check (nil for cash accounts).
+func (s *PortfolioService) buyInTx(tx *database.Tx, userID string, symbol string, shares decimal.Decimal, price decimal.Decimal,
+	releasedCash decimal.Decimal, marks map[string]decimal.Decimal) (*models.TradeResult, error) {
+	value := money.Total(price, shares)
+
+	// Lock the user's row so concurrent orders see each other's cash updates
+	cash, reserved, err := tx.GetUserCashForUpdate(userID)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to get user cash: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Update cash balance
-	newCash := cash - totalCost
-	err = s.db.UpdateUserCash(userID, newCash)
+	account, err := tx.GetAccount(userID)
 	if err != nil {
-		return fmt.Errorf("failed to update cash: %w", err)
+		return nil, err
 	}
+	cash, err = accrueInterest(tx, account, cash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	margin := account.AccountType == models.AccountTypeMargin
 
-	// Get existing holding
-	existingHolding, err := s.db.GetUserHolding(userID, symbol)
+	// The commission is paid on top of the shares and becomes part of their cost basis
+	fee, err := tradeFee(tx, account, shares, value)
 	if err != nil {
-		return fmt.Errorf("failed to get existing holding: %w", err)
+		return nil, fmt.Errorf("failed to calculate fee: %w", err)
 	}
+	totalCost := value.Add(fee)
+	costPrice := totalCost.DivRound(shares, money.AvgPricePlaces)
 
-	// Calculate new holding values
-	var newShares int
-	var newAvgPrice float64
+	// Cash reserved by other open orders cannot be spent; margin accounts may borrow
+	// and are checked against the initial margin requirement below instead
+	available := cash.Sub(reserved).Add(releasedCash)
+	if !margin && available.LessThan(totalCost) {
+		return nil, fmt.Errorf("%w: have $%s, need $%s", ErrInsufficientFunds, available.StringFixed(money.CashPlaces), totalCost.StringFixed(money.CashPlaces))
+	}
 
-	if existingHolding == nil {
-		// New holding
-		newShares = shares
-		newAvgPrice = price
-	} else {
-		// Update existing holding
-		totalValue := (float64(existingHolding.Shares) * existingHolding.AvgPrice) + totalCost
-		newShares = existingHolding.Shares + shares
-		newAvgPrice = totalValue / float64(newShares)
+	if !releasedCash.IsZero() {
+		err = tx.AdjustReservedCash(userID, releasedCash.Neg())
+		if err != nil {
+			return nil, fmt.Errorf("failed to release reserved cash: %w", err)
+		}
 	}
 
-	// Update holdings
-	err = s.db.UpsertHolding(userID, symbol, newShares, newAvgPrice)
+	// Cash balance after settlement; posted to the ledger once the transaction is recorded
+	newCash := cash.Sub(totalCost)
+
+	// Get existing holding
+	existingHolding, err := tx.GetUserHoldingForUpdate(userID, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to update holding: %w", err)
+		return nil, fmt.Errorf("failed to get existing holding: %w", err)
 	}
 
-	// Record transaction
-	err = s.db.CreateTransaction(userID, symbol, shares, price, "BUY", totalCost)
+	// Calculate new holding values; buying against a short position covers it
+	heldShares, heldAvg := decimal.Zero, money.Zero
+	if existingHolding != nil {
+		heldShares, heldAvg = existingHolding.Shares, existingHolding.AvgPrice
+	}
+	newShares, newAvgPrice := applyTrade(heldShares, heldAvg, shares, costPrice)
+
+	// Covering a short position closes its tax lots
+	lots, err := matchLots(tx, account, symbol, heldShares, heldAvg, shares, costPrice, nil)
 	if err != nil {
-		return fmt.Errorf("failed to record transaction: %w", err)
+		return nil, err
+	}
+	if lots.avgPrice.Valid {
+		newAvgPrice = lots.avgPrice.Decimal
 	}
 
-	// Commit transaction
-	err = tx.Commit()
+	// Trades that add exposure must leave margin accounts above the initial requirement
+	if margin && newShares.Abs().GreaterThan(heldShares.Abs()) {
+		err = checkInitialMargin(tx, account, newCash, reserved.Sub(releasedCash), symbol, newShares, marks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Update holdings
+	err = tx.UpsertHolding(userID, symbol, newShares, newAvgPrice)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to update holding: %w", err)
 	}
 
-	fmt.Printf("Successfully bought %d shares of %s for $%.2f\n", shares, symbol, totalCost)
-	return nil
+	// Record transaction
+	transactionID, err := tx.CreateTransaction(userID, symbol, shares, price, "BUY", value, fee, lots.realized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	description := tradeDescription("buy", shares, symbol, price, fee)
+	postings := tradePostings(symbol, shares, totalCost.Neg(), lots.realized)
+	if _, err := postCash(tx, userID, models.LedgerTrade, totalCost.Neg(), &transactionID, description, postings...); err != nil {
+		return nil, fmt.Errorf("failed to settle cash: %w", err)
+	}
+	if err := recordLots(tx, userID, symbol, transactionID, lots, shares, costPrice); err != nil {
+		return nil, fmt.Errorf("failed to record tax lots: %w", err)
+	}
+
+	return &models.TradeResult{
+		TransactionID:   transactionID,
+		TransactionType: "BUY",
+		Symbol:          symbol,
+		Shares:          shares,
+		Price:           price,
+		TotalAmount:     value,
+		Fee:             fee,
+		RemainingCash:   newCash,
+		RealizedGain:    lots.realized,
+	}, nil
 }
 
 // SellStock processes a stock sale
-func (s *PortfolioService) SellStock(ctx context.Context, userID string, symbol string, shares int) error {
-	if shares <= 0 {
-		return fmt.Errorf("invalid shares amount: %d", shares)
+func (s *PortfolioService) SellStock(ctx context.Context, userID string, symbol string, shares decimal.Decimal, lotIDs []int) (*models.TradeResult, error) {
+	if !money.ValidShares(shares) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidShares, shares.String())
+	}
+	if err := s.requireVerifiedEmail(userID); err != nil {
+		return nil, err
 	}
 
-	// Get current holding
-	holding, err := s.db.GetUserHolding(userID, symbol)
+	// Get current stock price
+	marketPrice, _, err := s.marketClient.GetStockPrice(ctx, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to get holding: %w", err)
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
 	}
-	if holding == nil {
-		return fmt.Errorf("no holdings found for symbol: %s", symbol)
+
+	price := money.FromPrice(marketPrice)
+	if !price.IsPositive() {
+		return nil, fmt.Errorf("no price available for %s", symbol)
 	}
-	if holding.Shares < shares {
-		return fmt.Errorf("insufficient shares: have %d, trying to sell %d", holding.Shares, shares)
+
+	marks, err := s.marginMarks(ctx, userID, symbol, price)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get current stock price
-	price, _, err := s.marketClient.GetStockPrice(ctx, symbol)
+	var result *models.TradeResult
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		result, err = s.sellInTx(tx, userID, symbol, shares, price, decimal.Zero, marks, lotIDs)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get stock price: %w", err)
+		return nil, err
 	}
 
-	totalReceived := float64(shares) * price
+	fmt.Printf("Successfully sold %s shares of %s for $%s less $%s fee\n", shares.String(), symbol,
+		result.TotalAmount.StringFixed(money.CashPlaces), result.Fee.StringFixed(money.CashPlaces))
+	s.publish(userID, events.TypeTransaction, result)
+	return result, nil
+}
+
+// sellInTx applies a sale at price inside tx. releasedShares is the part of the
+// holding's reserved shares that belonged to the order being filled (zero for market orders).
+// marks prices the user's positions for the margin check (nil for cash accounts) and
+// lotIDs picks the tax lots to sell for the SPECIFIC cost-basis method.
+func (s *PortfolioService) sellInTx(tx *database.Tx, userID string, symbol string, shares decimal.Decimal, price decimal.Decimal,
+	releasedShares decimal.Decimal, marks map[string]decimal.Decimal, lotIDs []int) (*models.TradeResult, error) {
+	value := money.Total(price, shares)
 
-	// Start database transaction
-	tx, err := s.db.BeginTx(ctx)
+	// Lock the user's row first, in the same order as buyInTx
+	cash, reserved, err := tx.GetUserCashForUpdate(userID)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to get user cash: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Update cash balance
-	cash, err := s.db.GetUserCash(userID)
+	account, err := tx.GetAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	cash, err = accrueInterest(tx, account, cash, time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to get user cash: %w", err)
+		return nil, err
 	}
+	margin := account.AccountType == models.AccountTypeMargin
 
-	newCash := cash + totalReceived
-	err = s.db.UpdateUserCash(userID, newCash)
+	// Get current holding
+	holding, err := tx.GetUserHoldingForUpdate(userID, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to update cash: %w", err)
+		return nil, fmt.Errorf("failed to get holding: %w", err)
+	}
+	if holding == nil {
+		if !margin {
+			return nil, fmt.Errorf("%w: no holdings found for symbol: %s", ErrInsufficientShares, symbol)
+		}
+		// Margin accounts may open a short position in a symbol they do not hold
+		holding = &models.Holding{Symbol: symbol, Shares: decimal.Zero, ReservedShares: decimal.Zero, AvgPrice: money.Zero}
 	}
 
-	// Update holdings
-	newShares := holding.Shares - shares
-	if newShares == 0 {
-		// If selling all shares, set to 0
-		err = s.db.UpsertHolding(userID, symbol, 0, holding.AvgPrice)
-	} else {
-		// Keep same average price when selling
-		err = s.db.UpsertHolding(userID, symbol, newShares, holding.AvgPrice)
+	// Shares reserved by other open sell orders cannot be sold; margin accounts may
+	// sell beyond their long position and go short instead
+	available := holding.Shares.Sub(holding.ReservedShares).Add(releasedShares)
+	if !margin && available.LessThan(shares) {
+		return nil, fmt.Errorf("%w: have %s, trying to sell %s", ErrInsufficientShares, available.String(), shares.String())
+	}
+
+	if !releasedShares.IsZero() {
+		err = tx.AdjustReservedShares(userID, symbol, releasedShares.Neg())
+		if err != nil {
+			return nil, fmt.Errorf("failed to release reserved shares: %w", err)
+		}
 	}
+
+	// The commission comes out of the proceeds, so gains are realized net of it
+	fee, err := tradeFee(tx, account, shares, value)
 	if err != nil {
-		return fmt.Errorf("failed to update holding: %w", err)
+		return nil, fmt.Errorf("failed to calculate fee: %w", err)
 	}
+	totalReceived := value.Sub(fee)
+	netPrice := totalReceived.DivRound(shares, money.AvgPricePlaces)
 
-	// Record transaction
-	err = s.db.CreateTransaction(userID, symbol, shares, price, "SELL", totalReceived)
+	// Cash balance after settlement; posted to the ledger once the transaction is recorded
+	newCash := cash.Add(totalReceived)
+	if !margin && newCash.LessThan(reserved) {
+		return nil, fmt.Errorf("%w: the $%s fee exceeds the proceeds and available cash", ErrInsufficientFunds,
+			fee.StringFixed(money.CashPlaces))
+	}
+
+	// Update holdings, keeping the same average price when reducing a long position
+	newShares, newAvgPrice := applyTrade(holding.Shares, holding.AvgPrice, shares.Neg(), netPrice)
+
+	// Selling a long position closes its tax lots using the account's cost-basis method
+	lots, err := matchLots(tx, account, symbol, holding.Shares, holding.AvgPrice, shares.Neg(), netPrice, lotIDs)
 	if err != nil {
-		return fmt.Errorf("failed to record transaction: %w", err)
+		return nil, err
+	}
+	if lots.avgPrice.Valid {
+		newAvgPrice = lots.avgPrice.Decimal
+	}
+
+	// Trades that add exposure (opening or extending a short) must leave margin
+	// accounts above the initial requirement
+	if margin && newShares.Abs().GreaterThan(holding.Shares.Abs()) {
+		err = checkInitialMargin(tx, account, newCash, reserved, symbol, newShares, marks)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Commit transaction
-	err = tx.Commit()
+	err = tx.UpsertHolding(userID, symbol, newShares, newAvgPrice)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to update holding: %w", err)
 	}
 
-	fmt.Printf("Successfully sold %d shares of %s for $%.2f\n", shares, symbol, totalReceived)
-	return nil
+	// Record transaction
+	transactionID, err := tx.CreateTransaction(userID, symbol, shares, price, "SELL", value, fee, lots.realized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	description := tradeDescription("sell", shares, symbol, price, fee)
+	postings := tradePostings(symbol, shares.Neg(), totalReceived, lots.realized)
+	if _, err := postCash(tx, userID, models.LedgerTrade, totalReceived, &transactionID, description, postings...); err != nil {
+		return nil, fmt.Errorf("failed to settle cash: %w", err)
+	}
+	if err := recordLots(tx, userID, symbol, transactionID, lots, shares.Neg(), netPrice); err != nil {
+		return nil, fmt.Errorf("failed to record tax lots: %w", err)
+	}
+
+	return &models.TradeResult{
+		TransactionID:   transactionID,
+		TransactionType: "SELL",
+		Symbol:          symbol,
+		Shares:          shares,
+		Price:           price,
+		TotalAmount:     value,
+		Fee:             fee,
+		RemainingCash:   newCash,
+		RealizedGain:    lots.realized,
+	}, nil
+}
+
+// tradeDescription describes a trade for the cash ledger and journal
+func tradeDescription(action string, shares decimal.Decimal, symbol string, price decimal.Decimal, fee decimal.Decimal) string {
+	description := fmt.Sprintf("%s %s %s at $%s", action, shares.String(), symbol, price.StringFixed(money.CashPlaces))
+	if !fee.IsZero() {
+		description += fmt.Sprintf(", $%s fee", fee.StringFixed(money.CashPlaces))
+	}
+	return description
 }
 
 // GetTransactions retrieves user's transaction history
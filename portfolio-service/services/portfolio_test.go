@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/FUNfarik/finance_microservices/proto/go/market"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+
+	"portfolio-service/database"
+	grpcclient "portfolio-service/grpc-client"
+	"portfolio-service/models"
+)
+
+// fakeMarket quotes every symbol at a fixed price
+type fakeMarket struct {
+	pb.UnimplementedMarketDataServiceServer
+	price float64
+}
+
+func (m *fakeMarket) GetStockPrice(ctx context.Context, req *pb.GetStockPriceRequest) (*pb.GetStockPriceResponse, error) {
+	return &pb.GetStockPriceResponse{Symbol: req.Symbol, Name: req.Symbol, CurrentPrice: m.price, Success: true}, nil
+}
+
+// startFakeMarket serves fakeMarket on a local port and connects a MarketClient to it
+func startFakeMarket(t *testing.T, price float64) *grpcclient.MarketClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterMarketDataServiceServer(server, &fakeMarket{price: price})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	t.Setenv("MARKET_GRPC_URL", lis.Addr().String())
+	client, err := grpcclient.Connect()
+	if err != nil {
+		t.Fatalf("connect to fake market: %v", err)
+	}
+	return client
+}
+
+// createTestUser opens a verified cash account with the given balance the way auth-service does
+func createTestUser(t *testing.T, dsn string, cash decimal.Decimal) string {
+	t.Helper()
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+
+	name := fmt.Sprintf("concurrency-%d", time.Now().UnixNano())
+	var userID int
+	err = tx.QueryRow(`INSERT INTO users (username, password_hash, email, cash, email_verified, created_at)
+		VALUES ($1, 'x', $2, $3, TRUE, NOW()) RETURNING id`, name, name+"@example.com", cash).Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	var journalID int
+	err = tx.QueryRow("INSERT INTO journal_entries (user_id, entry_type, description) VALUES ($1, 'OPENING', 'opening balance') RETURNING id",
+		userID).Scan(&journalID)
+	if err != nil {
+		t.Fatalf("journal opening balance: %v", err)
+	}
+	_, err = tx.Exec(`INSERT INTO postings (entry_id, user_id, account, amount)
+		VALUES ($1, $2, 'CASH', $3::numeric), ($1, $2, 'OPENING_BALANCE', -$3::numeric)`, journalID, userID, cash)
+	if err != nil {
+		t.Fatalf("post opening balance: %v", err)
+	}
+	_, err = tx.Exec(`INSERT INTO cash_ledger (user_id, entry_type, debit_account, credit_account, amount, balance_after, journal_entry_id, description)
+		VALUES ($1, 'OPENING', 'CASH', 'OPENING_BALANCE', $2, $2, $3, 'opening balance')`, userID, cash, journalID)
+	if err != nil {
+		t.Fatalf("ledger opening balance: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	return fmt.Sprint(userID)
+}
+
+// TestConcurrentBuysNeverOverdraw races many purchases by one user against a real database.
+// It needs TEST_DATABASE_URL to point at a disposable database loaded with database/init.sql.
+func TestConcurrentBuysNeverOverdraw(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := database.ConnectURL(dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	startingCash := decimal.NewFromInt(1000)
+	userID := createTestUser(t, dsn, startingCash)
+	svc := NewPortfolioService(db, startFakeMarket(t, 100))
+
+	const buyers = 20
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []*models.TradeResult
+		errs    []error
+	)
+	ctx := context.Background()
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := svc.BuyStock(ctx, userID, "TEST", decimal.NewFromInt(1))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			results = append(results, result)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("buy failed with %v, want ErrInsufficientFunds", err)
+		}
+	}
+	if len(results) == 0 {
+		t.Fatal("no buy succeeded")
+	}
+
+	// Every buy is one share at the same price and fee, so the balance covers exactly floor(cash/cost)
+	cost := results[0].TotalAmount.Add(results[0].Fee)
+	want := startingCash.Div(cost).Floor().IntPart()
+	if int64(len(results)) != want {
+		t.Errorf("%d buys succeeded, want %d at $%s each", len(results), want, cost.StringFixed(2))
+	}
+
+	cash, err := db.GetUserCash(userID)
+	if err != nil {
+		t.Fatalf("get cash: %v", err)
+	}
+	if cash.IsNegative() {
+		t.Errorf("cash went negative: $%s", cash.StringFixed(2))
+	}
+	wantCash := startingCash.Sub(cost.Mul(decimal.NewFromInt(int64(len(results)))))
+	if !cash.Equal(wantCash) {
+		t.Errorf("cash is $%s, want $%s", cash.StringFixed(2), wantCash.StringFixed(2))
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SnapshotScheduler takes a snapshot of every portfolio once a day
+type SnapshotScheduler struct {
+	portfolioService *PortfolioService
+	at               time.Duration // time of day, in UTC, to take snapshots
+}
+
+// NewSnapshotScheduler creates a scheduler that runs at the given offset from midnight UTC
+func NewSnapshotScheduler(portfolioService *PortfolioService, at time.Duration) *SnapshotScheduler {
+	return &SnapshotScheduler{
+		portfolioService: portfolioService,
+		at:               at,
+	}
+}
+
+// nextRun returns the first scheduled time after now
+func (s *SnapshotScheduler) nextRun(now time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(s.at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Run takes daily snapshots until ctx is cancelled
+func (s *SnapshotScheduler) Run(ctx context.Context) {
+	for {
+		next := s.nextRun(time.Now())
+		fmt.Printf("Next portfolio snapshot at %s\n", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			fmt.Println("Snapshot scheduler stopped")
+			return
+		case <-timer.C:
+			if err := s.portfolioService.SnapshotAll(ctx); err != nil {
+				fmt.Printf("Portfolio snapshots failed: %v\n", err)
+			}
+		}
+	}
+}
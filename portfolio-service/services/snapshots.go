@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/analytics"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"time"
+)
+
+// snapshotDate returns the UTC date a snapshot taken at t belongs to
+func snapshotDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// TakeSnapshot values a user's portfolio at current prices and stores it as today's snapshot,
+// recording the benchmark's price alongside it
+func (s *PortfolioService) TakeSnapshot(ctx context.Context, userID string) (*models.PortfolioSnapshot, error) {
+	s.recordBenchmark(ctx)
+	return s.takeSnapshot(ctx, userID)
+}
+
+// takeSnapshot values a user's portfolio at current prices and stores it as today's snapshot
+func (s *PortfolioService) takeSnapshot(ctx context.Context, userID string) (*models.PortfolioSnapshot, error) {
+	portfolio, err := s.GetPortfolio(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.PortfolioSnapshot{
+		UserID:        userID,
+		Date:          snapshotDate(time.Now()),
+		TotalValue:    portfolio.TotalValue,
+		Cash:          portfolio.Cash,
+		HoldingsValue: money.Zero,
+		Holdings:      make([]models.SnapshotHolding, len(portfolio.Holdings)),
+	}
+	for i, holding := range portfolio.Holdings {
+		snapshot.Holdings[i] = models.SnapshotHolding{
+			Symbol: holding.Symbol,
+			Shares: holding.Shares,
+			Price:  holding.CurrentPrice,
+			Value:  holding.TotalValue,
+		}
+		snapshot.HoldingsValue = snapshot.HoldingsValue.Add(holding.TotalValue)
+	}
+
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		return tx.SaveSnapshot(snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// SnapshotAll takes today's snapshot of every user's portfolio and records the benchmark's
+// price for performance comparisons
+func (s *PortfolioService) SnapshotAll(ctx context.Context) error {
+	s.recordBenchmark(ctx)
+
+	userIDs, err := s.db.GetUserIDs()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.takeSnapshot(ctx, userID); err != nil {
+			fmt.Printf("Failed to snapshot portfolio of user %s: %v\n", userID, err)
+			failed++
+		}
+	}
+
+	fmt.Printf("Portfolio snapshots taken for %d of %d users\n", len(userIDs)-failed, len(userIDs))
+	return nil
+}
+
+// recordBenchmark stores the benchmark's current price as today's close. Performance can
+// only compare against the benchmark over days that have a recorded price.
+func (s *PortfolioService) recordBenchmark(ctx context.Context) {
+	prices := s.currentPrices(ctx, []string{analytics.DefaultBenchmark})
+	price, ok := prices[analytics.DefaultBenchmark]
+	if !ok {
+		fmt.Printf("No %s price available for today's benchmark\n", analytics.DefaultBenchmark)
+		return
+	}
+	if err := s.db.RecordDailyPrice(analytics.DefaultBenchmark, snapshotDate(time.Now()), price); err != nil {
+		fmt.Printf("Failed to record %s price: %v\n", analytics.DefaultBenchmark, err)
+	}
+}
+
+var (
+	// ErrInvalidInterval is returned for history intervals other than day, week and month
+	ErrInvalidInterval = errors.New("invalid interval")
+	// ErrInvalidDateRange is returned when a history or performance range ends before it starts
+	ErrInvalidDateRange = errors.New("from must not be after to")
+)
+
+// GetHistory returns the user's snapshots between from and to, one per interval
+func (s *PortfolioService) GetHistory(ctx context.Context, userID string, from time.Time, to time.Time, interval string) ([]models.PortfolioSnapshot, error) {
+	switch interval {
+	case models.IntervalDay, models.IntervalWeek, models.IntervalMonth:
+	default:
+		return nil, fmt.Errorf("%w: %q (use day, week or month)", ErrInvalidInterval, interval)
+	}
+	if to.Before(from) {
+		return nil, ErrInvalidDateRange
+	}
+
+	return s.db.GetSnapshots(userID, from, to, interval)
+}
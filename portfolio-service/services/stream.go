@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"portfolio-service/events"
+	"time"
+)
+
+// streamBuffer is how many events a slow stream may fall behind before it misses some
+const streamBuffer = 64
+
+// streamInterval reads STREAM_INTERVAL (e.g. "15s"), how often open streams revalue the
+// portfolio between trades, defaulting to 15 seconds
+func streamInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("STREAM_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 15 * time.Second
+	}
+	return interval
+}
+
+// publish sends an update to the user's open streams
+func (s *PortfolioService) publish(userID string, eventType string, data interface{}) {
+	s.events.Publish(events.Event{Type: eventType, UserID: userID, Data: data})
+}
+
+// publishOrder sends the current state of one of the user's orders to their open streams
+func (s *PortfolioService) publishOrder(userID string, orderID int) {
+	order, err := s.db.GetUserOrder(userID, orderID)
+	if err != nil || order == nil {
+		fmt.Printf("Failed to publish order %d update: %v\n", orderID, err)
+		return
+	}
+	s.publish(userID, events.TypeOrder, order)
+}
+
+// StreamUpdates sends the user's portfolio revaluations, order changes and executed trades to
+// send until ctx ends, send fails or CloseStreams is called. The portfolio is revalued when the
+// stream opens, after every trade and every STREAM_INTERVAL.
+func (s *PortfolioService) StreamUpdates(ctx context.Context, userID string, send func(events.Event) error) error {
+	updates, unsubscribe := s.events.Subscribe(userID)
+	defer unsubscribe()
+
+	revalue := func() error {
+		portfolio, err := s.GetPortfolio(ctx, userID)
+		if err != nil {
+			// Keep the stream open; the next trade or tick tries again
+			fmt.Printf("Failed to revalue portfolio of user %s for stream: %v\n", userID, err)
+			return nil
+		}
+		return send(events.Event{Type: events.TypePortfolio, UserID: userID, Data: portfolio, Time: time.Now().UTC()})
+	}
+
+	if err := revalue(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := revalue(); err != nil {
+				return err
+			}
+		case event, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+			if event.Type == events.TypeTransaction {
+				if err := revalue(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// CloseStreams ends every open stream so that servers can shut down
+func (s *PortfolioService) CloseStreams() {
+	s.events.Close()
+}
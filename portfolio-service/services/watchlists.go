@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"portfolio-service/database"
+	"portfolio-service/models"
+	"portfolio-service/money"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrWatchlistNotFound is returned when a watchlist does not exist or belongs to another user
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+	// ErrWatchlistExists is returned when the user already has a watchlist with the requested name
+	ErrWatchlistExists = errors.New("a watchlist with that name already exists")
+)
+
+const (
+	maxWatchlistSymbols    = 50
+	maxWatchlistNameLength = 100
+)
+
+// watchlistName trims a requested watchlist name and checks its length
+func watchlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxWatchlistNameLength {
+		return "", fmt.Errorf("name must be 1 to %d characters", maxWatchlistNameLength)
+	}
+	return name, nil
+}
+
+// validSymbol normalizes symbol and checks with the market service that it exists
+func (s *PortfolioService) validSymbol(ctx context.Context, symbol string) (string, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return "", fmt.Errorf("symbol is required")
+	}
+	valid, err := s.marketClient.ValidateSymbol(ctx, symbol)
+	if err != nil {
+		return "", fmt.Errorf("failed to validate symbol: %w", err)
+	}
+	if !valid {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSymbol, symbol)
+	}
+	return symbol, nil
+}
+
+// CreateWatchlist creates a named watchlist, optionally seeded with symbols
+func (s *PortfolioService) CreateWatchlist(ctx context.Context, userID string, req models.WatchlistRequest) (*models.Watchlist, error) {
+	name, err := watchlistName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate symbols before opening the transaction so no locks are held during the gRPC calls
+	watchlist := &models.Watchlist{Name: name, Symbols: []string{}}
+	seen := make(map[string]bool)
+	for _, requested := range req.Symbols {
+		symbol, err := s.validSymbol(ctx, requested)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[symbol] {
+			seen[symbol] = true
+			watchlist.Symbols = append(watchlist.Symbols, symbol)
+		}
+	}
+	if len(watchlist.Symbols) > maxWatchlistSymbols {
+		return nil, fmt.Errorf("a watchlist holds at most %d symbols", maxWatchlistSymbols)
+	}
+
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		created, err := tx.CreateWatchlist(userID, watchlist)
+		if err != nil {
+			return err
+		}
+		if !created {
+			return ErrWatchlistExists
+		}
+		for _, symbol := range watchlist.Symbols {
+			if err := tx.AddWatchlistSymbol(watchlist.ID, symbol); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("User %s created watchlist %d with %d symbol(s)\n", userID, watchlist.ID, len(watchlist.Symbols))
+	return watchlist, nil
+}
+
+// GetWatchlists returns the user's watchlists and their symbols, without quotes
+func (s *PortfolioService) GetWatchlists(ctx context.Context, userID string) ([]models.Watchlist, error) {
+	return s.db.GetUserWatchlists(userID)
+}
+
+// GetWatchlist returns one of the user's watchlists with live quotes for its symbols,
+// fetched in a single batch. Prices are null when the market service cannot provide them.
+func (s *PortfolioService) GetWatchlist(ctx context.Context, userID string, watchlistID int) (*models.Watchlist, error) {
+	watchlist, err := s.db.GetWatchlist(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	if watchlist == nil {
+		return nil, ErrWatchlistNotFound
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	if len(watchlist.Symbols) > 0 {
+		marketPrices, err := s.marketClient.GetMultipleStockPrices(ctx, watchlist.Symbols)
+		if err != nil {
+			fmt.Printf("Failed to get watchlist prices from market service: %v\n", err)
+		} else {
+			prices = money.FromPrices(marketPrices)
+		}
+	}
+
+	watchlist.Quotes = make([]models.WatchlistQuote, len(watchlist.Symbols))
+	for i, symbol := range watchlist.Symbols {
+		watchlist.Quotes[i] = models.WatchlistQuote{Symbol: symbol}
+		if price, ok := prices[symbol]; ok && price.IsPositive() {
+			watchlist.Quotes[i].Price = decimal.NewNullDecimal(price)
+		}
+	}
+	return watchlist, nil
+}
+
+// RenameWatchlist changes the name of one of the user's watchlists
+func (s *PortfolioService) RenameWatchlist(ctx context.Context, userID string, watchlistID int, req models.WatchlistRequest) (*models.Watchlist, error) {
+	name, err := watchlistName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var watchlist *models.Watchlist
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		watchlist, err = tx.GetWatchlistForUpdate(userID, watchlistID)
+		if err != nil {
+			return err
+		}
+		if watchlist == nil {
+			return ErrWatchlistNotFound
+		}
+		renamed, err := tx.RenameWatchlist(userID, watchlistID, name)
+		if err != nil {
+			return err
+		}
+		if !renamed {
+			return ErrWatchlistExists
+		}
+		watchlist.Name = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// DeleteWatchlist removes one of the user's watchlists
+func (s *PortfolioService) DeleteWatchlist(ctx context.Context, userID string, watchlistID int) error {
+	deleted, err := s.db.DeleteWatchlist(userID, watchlistID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+// AddWatchlistSymbol validates symbol with the market service and adds it to one of the user's watchlists
+func (s *PortfolioService) AddWatchlistSymbol(ctx context.Context, userID string, watchlistID int, symbol string) (*models.Watchlist, error) {
+	symbol, err := s.validSymbol(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var watchlist *models.Watchlist
+	err = s.db.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		watchlist, err = tx.GetWatchlistForUpdate(userID, watchlistID)
+		if err != nil {
+			return err
+		}
+		if watchlist == nil {
+			return ErrWatchlistNotFound
+		}
+		for _, existing := range watchlist.Symbols {
+			if existing == symbol {
+				return nil
+			}
+		}
+		if len(watchlist.Symbols) >= maxWatchlistSymbols {
+			return fmt.Errorf("a watchlist holds at most %d symbols", maxWatchlistSymbols)
+		}
+		if err := tx.AddWatchlistSymbol(watchlistID, symbol); err != nil {
+			return err
+		}
+		watchlist.Symbols = append(watchlist.Symbols, symbol)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// RemoveWatchlistSymbol removes symbol from one of the user's watchlists
+func (s *PortfolioService) RemoveWatchlistSymbol(ctx context.Context, userID string, watchlistID int, symbol string) error {
+	removed, err := s.db.RemoveWatchlistSymbol(userID, watchlistID, strings.ToUpper(symbol))
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
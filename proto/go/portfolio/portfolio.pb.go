@@ -0,0 +1,1443 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v4.25.3
+// source: portfolio.proto
+
+package portfolio
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Buy stock request
+type BuyStockRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Token          string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`                                         // User authentication
+	Symbol         string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`                                       // Stock symbol (AAPL, GOOGL, etc.)
+	Shares         int32                  `protobuf:"varint,3,opt,name=shares,proto3" json:"shares,omitempty"`                                      // Number of whole shares to buy
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"` // Optional; retries with the same key return the original response
+	Quantity       float64                `protobuf:"fixed64,5,opt,name=quantity,proto3" json:"quantity,omitempty"`                                 // Fractional number of shares (up to 6 decimals); used instead of shares when set
+	Amount         float64                `protobuf:"fixed64,6,opt,name=amount,proto3" json:"amount,omitempty"`                                     // Dollar amount to invest instead of a number of shares
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BuyStockRequest) Reset() {
+	*x = BuyStockRequest{}
+	mi := &file_portfolio_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuyStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuyStockRequest) ProtoMessage() {}
+
+func (x *BuyStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuyStockRequest.ProtoReflect.Descriptor instead.
+func (*BuyStockRequest) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BuyStockRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *BuyStockRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *BuyStockRequest) GetShares() int32 {
+	if x != nil {
+		return x.Shares
+	}
+	return 0
+}
+
+func (x *BuyStockRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *BuyStockRequest) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *BuyStockRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type BuyStockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	TransactionId string                 `protobuf:"bytes,2,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`   // Unique ID for this transaction
+	TotalCost     float64                `protobuf:"fixed64,3,opt,name=total_cost,json=totalCost,proto3" json:"total_cost,omitempty"`             // Total amount spent, including the fee
+	RemainingCash float64                `protobuf:"fixed64,4,opt,name=remaining_cash,json=remainingCash,proto3" json:"remaining_cash,omitempty"` // User's cash after purchase
+	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Fee           float64                `protobuf:"fixed64,6,opt,name=fee,proto3" json:"fee,omitempty"`           // Commission charged on the trade
+	Quantity      float64                `protobuf:"fixed64,7,opt,name=quantity,proto3" json:"quantity,omitempty"` // Shares bought, which may be fractional
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuyStockResponse) Reset() {
+	*x = BuyStockResponse{}
+	mi := &file_portfolio_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuyStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuyStockResponse) ProtoMessage() {}
+
+func (x *BuyStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuyStockResponse.ProtoReflect.Descriptor instead.
+func (*BuyStockResponse) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BuyStockResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BuyStockResponse) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *BuyStockResponse) GetTotalCost() float64 {
+	if x != nil {
+		return x.TotalCost
+	}
+	return 0
+}
+
+func (x *BuyStockResponse) GetRemainingCash() float64 {
+	if x != nil {
+		return x.RemainingCash
+	}
+	return 0
+}
+
+func (x *BuyStockResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *BuyStockResponse) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *BuyStockResponse) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// Sell stock request
+type SellStockRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Token          string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Symbol         string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Shares         int32                  `protobuf:"varint,3,opt,name=shares,proto3" json:"shares,omitempty"` // Number of whole shares to sell
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	LotIds         []int32                `protobuf:"varint,5,rep,packed,name=lot_ids,json=lotIds,proto3" json:"lot_ids,omitempty"` // Tax lots to sell from, for the SPECIFIC cost-basis method
+	Quantity       float64                `protobuf:"fixed64,6,opt,name=quantity,proto3" json:"quantity,omitempty"`                 // Fractional number of shares (up to 6 decimals); used instead of shares when set
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SellStockRequest) Reset() {
+	*x = SellStockRequest{}
+	mi := &file_portfolio_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SellStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SellStockRequest) ProtoMessage() {}
+
+func (x *SellStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SellStockRequest.ProtoReflect.Descriptor instead.
+func (*SellStockRequest) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SellStockRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *SellStockRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *SellStockRequest) GetShares() int32 {
+	if x != nil {
+		return x.Shares
+	}
+	return 0
+}
+
+func (x *SellStockRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *SellStockRequest) GetLotIds() []int32 {
+	if x != nil {
+		return x.LotIds
+	}
+	return nil
+}
+
+func (x *SellStockRequest) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type SellStockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	TransactionId string                 `protobuf:"bytes,2,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	TotalReceived float64                `protobuf:"fixed64,3,opt,name=total_received,json=totalReceived,proto3" json:"total_received,omitempty"` // Money received from sale, net of the fee
+	RemainingCash float64                `protobuf:"fixed64,4,opt,name=remaining_cash,json=remainingCash,proto3" json:"remaining_cash,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	RealizedGain  float64                `protobuf:"fixed64,6,opt,name=realized_gain,json=realizedGain,proto3" json:"realized_gain,omitempty"` // Profit/loss realized against the sold lots, net of the fee
+	Fee           float64                `protobuf:"fixed64,7,opt,name=fee,proto3" json:"fee,omitempty"`                                       // Commission charged on the trade
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SellStockResponse) Reset() {
+	*x = SellStockResponse{}
+	mi := &file_portfolio_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SellStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SellStockResponse) ProtoMessage() {}
+
+func (x *SellStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SellStockResponse.ProtoReflect.Descriptor instead.
+func (*SellStockResponse) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SellStockResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SellStockResponse) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *SellStockResponse) GetTotalReceived() float64 {
+	if x != nil {
+		return x.TotalReceived
+	}
+	return 0
+}
+
+func (x *SellStockResponse) GetRemainingCash() float64 {
+	if x != nil {
+		return x.RemainingCash
+	}
+	return 0
+}
+
+func (x *SellStockResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *SellStockResponse) GetRealizedGain() float64 {
+	if x != nil {
+		return x.RealizedGain
+	}
+	return 0
+}
+
+func (x *SellStockResponse) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+// Get user's portfolio
+type GetPortfolioRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPortfolioRequest) Reset() {
+	*x = GetPortfolioRequest{}
+	mi := &file_portfolio_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPortfolioRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPortfolioRequest) ProtoMessage() {}
+
+func (x *GetPortfolioRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPortfolioRequest.ProtoReflect.Descriptor instead.
+func (*GetPortfolioRequest) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetPortfolioRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type Holding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	CompanyName   string                 `protobuf:"bytes,2,opt,name=company_name,json=companyName,proto3" json:"company_name,omitempty"`
+	Shares        int32                  `protobuf:"varint,3,opt,name=shares,proto3" json:"shares,omitempty"`
+	AvgPrice      float64                `protobuf:"fixed64,4,opt,name=avg_price,json=avgPrice,proto3" json:"avg_price,omitempty"`             // Average price paid
+	CurrentPrice  float64                `protobuf:"fixed64,5,opt,name=current_price,json=currentPrice,proto3" json:"current_price,omitempty"` // Current market price
+	TotalValue    float64                `protobuf:"fixed64,6,opt,name=total_value,json=totalValue,proto3" json:"total_value,omitempty"`       // shares * current_price
+	GainLoss      float64                `protobuf:"fixed64,7,opt,name=gain_loss,json=gainLoss,proto3" json:"gain_loss,omitempty"`             // Profit/loss on this holding
+	Quantity      float64                `protobuf:"fixed64,8,opt,name=quantity,proto3" json:"quantity,omitempty"`                             // Exact, possibly fractional, number of shares; shares is its whole part
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Holding) Reset() {
+	*x = Holding{}
+	mi := &file_portfolio_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Holding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Holding) ProtoMessage() {}
+
+func (x *Holding) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Holding.ProtoReflect.Descriptor instead.
+func (*Holding) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Holding) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Holding) GetCompanyName() string {
+	if x != nil {
+		return x.CompanyName
+	}
+	return ""
+}
+
+func (x *Holding) GetShares() int32 {
+	if x != nil {
+		return x.Shares
+	}
+	return 0
+}
+
+func (x *Holding) GetAvgPrice() float64 {
+	if x != nil {
+		return x.AvgPrice
+	}
+	return 0
+}
+
+func (x *Holding) GetCurrentPrice() float64 {
+	if x != nil {
+		return x.CurrentPrice
+	}
+	return 0
+}
+
+func (x *Holding) GetTotalValue() float64 {
+	if x != nil {
+		return x.TotalValue
+	}
+	return 0
+}
+
+func (x *Holding) GetGainLoss() float64 {
+	if x != nil {
+		return x.GainLoss
+	}
+	return 0
+}
+
+func (x *Holding) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type GetPortfolioResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Holdings           []*Holding             `protobuf:"bytes,1,rep,name=holdings,proto3" json:"holdings,omitempty"`
+	TotalValue         float64                `protobuf:"fixed64,2,opt,name=total_value,json=totalValue,proto3" json:"total_value,omitempty"` // Sum of all holdings
+	CashBalance        float64                `protobuf:"fixed64,3,opt,name=cash_balance,json=cashBalance,proto3" json:"cash_balance,omitempty"`
+	TotalGainLoss      float64                `protobuf:"fixed64,4,opt,name=total_gain_loss,json=totalGainLoss,proto3" json:"total_gain_loss,omitempty"` // Total profit/loss
+	Success            bool                   `protobuf:"varint,5,opt,name=success,proto3" json:"success,omitempty"`
+	ErrorMessage       string                 `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	RealizedGainLoss   float64                `protobuf:"fixed64,7,opt,name=realized_gain_loss,json=realizedGainLoss,proto3" json:"realized_gain_loss,omitempty"`       // Gains realized by past sales
+	UnrealizedGainLoss float64                `protobuf:"fixed64,8,opt,name=unrealized_gain_loss,json=unrealizedGainLoss,proto3" json:"unrealized_gain_loss,omitempty"` // Gains on open positions
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *GetPortfolioResponse) Reset() {
+	*x = GetPortfolioResponse{}
+	mi := &file_portfolio_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPortfolioResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPortfolioResponse) ProtoMessage() {}
+
+func (x *GetPortfolioResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPortfolioResponse.ProtoReflect.Descriptor instead.
+func (*GetPortfolioResponse) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetPortfolioResponse) GetHoldings() []*Holding {
+	if x != nil {
+		return x.Holdings
+	}
+	return nil
+}
+
+func (x *GetPortfolioResponse) GetTotalValue() float64 {
+	if x != nil {
+		return x.TotalValue
+	}
+	return 0
+}
+
+func (x *GetPortfolioResponse) GetCashBalance() float64 {
+	if x != nil {
+		return x.CashBalance
+	}
+	return 0
+}
+
+func (x *GetPortfolioResponse) GetTotalGainLoss() float64 {
+	if x != nil {
+		return x.TotalGainLoss
+	}
+	return 0
+}
+
+func (x *GetPortfolioResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetPortfolioResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *GetPortfolioResponse) GetRealizedGainLoss() float64 {
+	if x != nil {
+		return x.RealizedGainLoss
+	}
+	return 0
+}
+
+func (x *GetPortfolioResponse) GetUnrealizedGainLoss() float64 {
+	if x != nil {
+		return x.UnrealizedGainLoss
+	}
+	return 0
+}
+
+// Get return and risk statistics for the user's holdings
+type GetPerformanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"` // YYYY-MM-DD, defaults to a year before to
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`     // YYYY-MM-DD, defaults to today
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPerformanceRequest) Reset() {
+	*x = GetPerformanceRequest{}
+	mi := &file_portfolio_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPerformanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPerformanceRequest) ProtoMessage() {}
+
+func (x *GetPerformanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPerformanceRequest.ProtoReflect.Descriptor instead.
+func (*GetPerformanceRequest) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPerformanceRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *GetPerformanceRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetPerformanceRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type GetPerformanceResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Success             bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ErrorMessage        string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	From                string                 `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"` // Dates of the first and last snapshots used
+	To                  string                 `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	StartValue          float64                `protobuf:"fixed64,5,opt,name=start_value,json=startValue,proto3" json:"start_value,omitempty"`
+	EndValue            float64                `protobuf:"fixed64,6,opt,name=end_value,json=endValue,proto3" json:"end_value,omitempty"`
+	NetCashFlow         float64                `protobuf:"fixed64,7,opt,name=net_cash_flow,json=netCashFlow,proto3" json:"net_cash_flow,omitempty"`
+	TimeWeightedReturn  float64                `protobuf:"fixed64,8,opt,name=time_weighted_return,json=timeWeightedReturn,proto3" json:"time_weighted_return,omitempty"`
+	MoneyWeightedReturn *float64               `protobuf:"fixed64,9,opt,name=money_weighted_return,json=moneyWeightedReturn,proto3,oneof" json:"money_weighted_return,omitempty"` // Annualized XIRR
+	MaxDrawdown         float64                `protobuf:"fixed64,10,opt,name=max_drawdown,json=maxDrawdown,proto3" json:"max_drawdown,omitempty"`
+	Volatility          float64                `protobuf:"fixed64,11,opt,name=volatility,proto3" json:"volatility,omitempty"` // Annualized
+	SharpeRatio         *float64               `protobuf:"fixed64,12,opt,name=sharpe_ratio,json=sharpeRatio,proto3,oneof" json:"sharpe_ratio,omitempty"`
+	Benchmark           string                 `protobuf:"bytes,13,opt,name=benchmark,proto3" json:"benchmark,omitempty"`
+	BenchmarkReturn     *float64               `protobuf:"fixed64,14,opt,name=benchmark_return,json=benchmarkReturn,proto3,oneof" json:"benchmark_return,omitempty"`
+	ExcessReturn        *float64               `protobuf:"fixed64,15,opt,name=excess_return,json=excessReturn,proto3,oneof" json:"excess_return,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *GetPerformanceResponse) Reset() {
+	*x = GetPerformanceResponse{}
+	mi := &file_portfolio_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPerformanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPerformanceResponse) ProtoMessage() {}
+
+func (x *GetPerformanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPerformanceResponse.ProtoReflect.Descriptor instead.
+func (*GetPerformanceResponse) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPerformanceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetPerformanceResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *GetPerformanceResponse) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetPerformanceResponse) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *GetPerformanceResponse) GetStartValue() float64 {
+	if x != nil {
+		return x.StartValue
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetEndValue() float64 {
+	if x != nil {
+		return x.EndValue
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetNetCashFlow() float64 {
+	if x != nil {
+		return x.NetCashFlow
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetTimeWeightedReturn() float64 {
+	if x != nil {
+		return x.TimeWeightedReturn
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetMoneyWeightedReturn() float64 {
+	if x != nil && x.MoneyWeightedReturn != nil {
+		return *x.MoneyWeightedReturn
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetMaxDrawdown() float64 {
+	if x != nil {
+		return x.MaxDrawdown
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetVolatility() float64 {
+	if x != nil {
+		return x.Volatility
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetSharpeRatio() float64 {
+	if x != nil && x.SharpeRatio != nil {
+		return *x.SharpeRatio
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetBenchmark() string {
+	if x != nil {
+		return x.Benchmark
+	}
+	return ""
+}
+
+func (x *GetPerformanceResponse) GetBenchmarkReturn() float64 {
+	if x != nil && x.BenchmarkReturn != nil {
+		return *x.BenchmarkReturn
+	}
+	return 0
+}
+
+func (x *GetPerformanceResponse) GetExcessReturn() float64 {
+	if x != nil && x.ExcessReturn != nil {
+		return *x.ExcessReturn
+	}
+	return 0
+}
+
+// Stream the user's portfolio revaluations, order changes and executed trades
+type StreamUpdatesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamUpdatesRequest) Reset() {
+	*x = StreamUpdatesRequest{}
+	mi := &file_portfolio_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUpdatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUpdatesRequest) ProtoMessage() {}
+
+func (x *StreamUpdatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUpdatesRequest.ProtoReflect.Descriptor instead.
+func (*StreamUpdatesRequest) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamUpdatesRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type OrderUpdate struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OrderId        int32                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Symbol         string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Side           string                 `protobuf:"bytes,3,opt,name=side,proto3" json:"side,omitempty"`                            // BUY or SELL
+	OrderType      string                 `protobuf:"bytes,4,opt,name=order_type,json=orderType,proto3" json:"order_type,omitempty"` // LIMIT, STOP or STOP_LIMIT
+	Status         string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Quantity       float64                `protobuf:"fixed64,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	FilledQuantity float64                `protobuf:"fixed64,7,opt,name=filled_quantity,json=filledQuantity,proto3" json:"filled_quantity,omitempty"`
+	LimitPrice     *float64               `protobuf:"fixed64,8,opt,name=limit_price,json=limitPrice,proto3,oneof" json:"limit_price,omitempty"`
+	StopPrice      *float64               `protobuf:"fixed64,9,opt,name=stop_price,json=stopPrice,proto3,oneof" json:"stop_price,omitempty"`
+	FilledPrice    *float64               `protobuf:"fixed64,10,opt,name=filled_price,json=filledPrice,proto3,oneof" json:"filled_price,omitempty"`
+	Reason         string                 `protobuf:"bytes,11,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *OrderUpdate) Reset() {
+	*x = OrderUpdate{}
+	mi := &file_portfolio_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderUpdate) ProtoMessage() {}
+
+func (x *OrderUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderUpdate.ProtoReflect.Descriptor instead.
+func (*OrderUpdate) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *OrderUpdate) GetOrderId() int32 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetOrderType() string {
+	if x != nil {
+		return x.OrderType
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetFilledQuantity() float64 {
+	if x != nil {
+		return x.FilledQuantity
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetLimitPrice() float64 {
+	if x != nil && x.LimitPrice != nil {
+		return *x.LimitPrice
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetStopPrice() float64 {
+	if x != nil && x.StopPrice != nil {
+		return *x.StopPrice
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetFilledPrice() float64 {
+	if x != nil && x.FilledPrice != nil {
+		return *x.FilledPrice
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type TransactionUpdate struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId   string                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	TransactionType string                 `protobuf:"bytes,2,opt,name=transaction_type,json=transactionType,proto3" json:"transaction_type,omitempty"` // BUY or SELL
+	Symbol          string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Quantity        float64                `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Price           float64                `protobuf:"fixed64,5,opt,name=price,proto3" json:"price,omitempty"`
+	TotalAmount     float64                `protobuf:"fixed64,6,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"` // quantity * price, before the fee
+	Fee             float64                `protobuf:"fixed64,7,opt,name=fee,proto3" json:"fee,omitempty"`
+	RemainingCash   float64                `protobuf:"fixed64,8,opt,name=remaining_cash,json=remainingCash,proto3" json:"remaining_cash,omitempty"`
+	RealizedGain    *float64               `protobuf:"fixed64,9,opt,name=realized_gain,json=realizedGain,proto3,oneof" json:"realized_gain,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TransactionUpdate) Reset() {
+	*x = TransactionUpdate{}
+	mi := &file_portfolio_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionUpdate) ProtoMessage() {}
+
+func (x *TransactionUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionUpdate.ProtoReflect.Descriptor instead.
+func (*TransactionUpdate) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TransactionUpdate) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *TransactionUpdate) GetTransactionType() string {
+	if x != nil {
+		return x.TransactionType
+	}
+	return ""
+}
+
+func (x *TransactionUpdate) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *TransactionUpdate) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *TransactionUpdate) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *TransactionUpdate) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *TransactionUpdate) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *TransactionUpdate) GetRemainingCash() float64 {
+	if x != nil {
+		return x.RemainingCash
+	}
+	return 0
+}
+
+func (x *TransactionUpdate) GetRealizedGain() float64 {
+	if x != nil && x.RealizedGain != nil {
+		return *x.RealizedGain
+	}
+	return 0
+}
+
+type PortfolioUpdate struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`           // portfolio, order or transaction
+	Timestamp string                 `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // RFC 3339
+	// Types that are valid to be assigned to Update:
+	//
+	//	*PortfolioUpdate_Portfolio
+	//	*PortfolioUpdate_Order
+	//	*PortfolioUpdate_Transaction
+	Update        isPortfolioUpdate_Update `protobuf_oneof:"update"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortfolioUpdate) Reset() {
+	*x = PortfolioUpdate{}
+	mi := &file_portfolio_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortfolioUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortfolioUpdate) ProtoMessage() {}
+
+func (x *PortfolioUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_portfolio_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortfolioUpdate.ProtoReflect.Descriptor instead.
+func (*PortfolioUpdate) Descriptor() ([]byte, []int) {
+	return file_portfolio_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PortfolioUpdate) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PortfolioUpdate) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *PortfolioUpdate) GetUpdate() isPortfolioUpdate_Update {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *PortfolioUpdate) GetPortfolio() *GetPortfolioResponse {
+	if x != nil {
+		if x, ok := x.Update.(*PortfolioUpdate_Portfolio); ok {
+			return x.Portfolio
+		}
+	}
+	return nil
+}
+
+func (x *PortfolioUpdate) GetOrder() *OrderUpdate {
+	if x != nil {
+		if x, ok := x.Update.(*PortfolioUpdate_Order); ok {
+			return x.Order
+		}
+	}
+	return nil
+}
+
+func (x *PortfolioUpdate) GetTransaction() *TransactionUpdate {
+	if x != nil {
+		if x, ok := x.Update.(*PortfolioUpdate_Transaction); ok {
+			return x.Transaction
+		}
+	}
+	return nil
+}
+
+type isPortfolioUpdate_Update interface {
+	isPortfolioUpdate_Update()
+}
+
+type PortfolioUpdate_Portfolio struct {
+	Portfolio *GetPortfolioResponse `protobuf:"bytes,3,opt,name=portfolio,proto3,oneof"`
+}
+
+type PortfolioUpdate_Order struct {
+	Order *OrderUpdate `protobuf:"bytes,4,opt,name=order,proto3,oneof"`
+}
+
+type PortfolioUpdate_Transaction struct {
+	Transaction *TransactionUpdate `protobuf:"bytes,5,opt,name=transaction,proto3,oneof"`
+}
+
+func (*PortfolioUpdate_Portfolio) isPortfolioUpdate_Update() {}
+
+func (*PortfolioUpdate_Order) isPortfolioUpdate_Update() {}
+
+func (*PortfolioUpdate_Transaction) isPortfolioUpdate_Update() {}
+
+var File_portfolio_proto protoreflect.FileDescriptor
+
+const file_portfolio_proto_rawDesc = "" +
+	"\n" +
+	"\x0fportfolio.proto\x12\tportfolio\"\xb4\x01\n" +
+	"\x0fBuyStockRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06shares\x18\x03 \x01(\x05R\x06shares\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x01R\bquantity\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\x01R\x06amount\"\xec\x01\n" +
+	"\x10BuyStockResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12%\n" +
+	"\x0etransaction_id\x18\x02 \x01(\tR\rtransactionId\x12\x1d\n" +
+	"\n" +
+	"total_cost\x18\x03 \x01(\x01R\ttotalCost\x12%\n" +
+	"\x0eremaining_cash\x18\x04 \x01(\x01R\rremainingCash\x12#\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\x12\x10\n" +
+	"\x03fee\x18\x06 \x01(\x01R\x03fee\x12\x1a\n" +
+	"\bquantity\x18\a \x01(\x01R\bquantity\"\xb6\x01\n" +
+	"\x10SellStockRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06shares\x18\x03 \x01(\x05R\x06shares\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\x12\x17\n" +
+	"\alot_ids\x18\x05 \x03(\x05R\x06lotIds\x12\x1a\n" +
+	"\bquantity\x18\x06 \x01(\x01R\bquantity\"\xfe\x01\n" +
+	"\x11SellStockResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12%\n" +
+	"\x0etransaction_id\x18\x02 \x01(\tR\rtransactionId\x12%\n" +
+	"\x0etotal_received\x18\x03 \x01(\x01R\rtotalReceived\x12%\n" +
+	"\x0eremaining_cash\x18\x04 \x01(\x01R\rremainingCash\x12#\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\x12#\n" +
+	"\rrealized_gain\x18\x06 \x01(\x01R\frealizedGain\x12\x10\n" +
+	"\x03fee\x18\a \x01(\x01R\x03fee\"+\n" +
+	"\x13GetPortfolioRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\xf8\x01\n" +
+	"\aHolding\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12!\n" +
+	"\fcompany_name\x18\x02 \x01(\tR\vcompanyName\x12\x16\n" +
+	"\x06shares\x18\x03 \x01(\x05R\x06shares\x12\x1b\n" +
+	"\tavg_price\x18\x04 \x01(\x01R\bavgPrice\x12#\n" +
+	"\rcurrent_price\x18\x05 \x01(\x01R\fcurrentPrice\x12\x1f\n" +
+	"\vtotal_value\x18\x06 \x01(\x01R\n" +
+	"totalValue\x12\x1b\n" +
+	"\tgain_loss\x18\a \x01(\x01R\bgainLoss\x12\x1a\n" +
+	"\bquantity\x18\b \x01(\x01R\bquantity\"\xd1\x02\n" +
+	"\x14GetPortfolioResponse\x12.\n" +
+	"\bholdings\x18\x01 \x03(\v2\x12.portfolio.HoldingR\bholdings\x12\x1f\n" +
+	"\vtotal_value\x18\x02 \x01(\x01R\n" +
+	"totalValue\x12!\n" +
+	"\fcash_balance\x18\x03 \x01(\x01R\vcashBalance\x12&\n" +
+	"\x0ftotal_gain_loss\x18\x04 \x01(\x01R\rtotalGainLoss\x12\x18\n" +
+	"\asuccess\x18\x05 \x01(\bR\asuccess\x12#\n" +
+	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\x12,\n" +
+	"\x12realized_gain_loss\x18\a \x01(\x01R\x10realizedGainLoss\x120\n" +
+	"\x14unrealized_gain_loss\x18\b \x01(\x01R\x12unrealizedGainLoss\"Q\n" +
+	"\x15GetPerformanceRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\"\xfd\x04\n" +
+	"\x16GetPerformanceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x1f\n" +
+	"\vstart_value\x18\x05 \x01(\x01R\n" +
+	"startValue\x12\x1b\n" +
+	"\tend_value\x18\x06 \x01(\x01R\bendValue\x12\"\n" +
+	"\rnet_cash_flow\x18\a \x01(\x01R\vnetCashFlow\x120\n" +
+	"\x14time_weighted_return\x18\b \x01(\x01R\x12timeWeightedReturn\x127\n" +
+	"\x15money_weighted_return\x18\t \x01(\x01H\x00R\x13moneyWeightedReturn\x88\x01\x01\x12!\n" +
+	"\fmax_drawdown\x18\n" +
+	" \x01(\x01R\vmaxDrawdown\x12\x1e\n" +
+	"\n" +
+	"volatility\x18\v \x01(\x01R\n" +
+	"volatility\x12&\n" +
+	"\fsharpe_ratio\x18\f \x01(\x01H\x01R\vsharpeRatio\x88\x01\x01\x12\x1c\n" +
+	"\tbenchmark\x18\r \x01(\tR\tbenchmark\x12.\n" +
+	"\x10benchmark_return\x18\x0e \x01(\x01H\x02R\x0fbenchmarkReturn\x88\x01\x01\x12(\n" +
+	"\rexcess_return\x18\x0f \x01(\x01H\x03R\fexcessReturn\x88\x01\x01B\x18\n" +
+	"\x16_money_weighted_returnB\x0f\n" +
+	"\r_sharpe_ratioB\x13\n" +
+	"\x11_benchmark_returnB\x10\n" +
+	"\x0e_excess_return\",\n" +
+	"\x14StreamUpdatesRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\x8a\x03\n" +
+	"\vOrderUpdate\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x05R\aorderId\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04side\x18\x03 \x01(\tR\x04side\x12\x1d\n" +
+	"\n" +
+	"order_type\x18\x04 \x01(\tR\torderType\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x1a\n" +
+	"\bquantity\x18\x06 \x01(\x01R\bquantity\x12'\n" +
+	"\x0ffilled_quantity\x18\a \x01(\x01R\x0efilledQuantity\x12$\n" +
+	"\vlimit_price\x18\b \x01(\x01H\x00R\n" +
+	"limitPrice\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"stop_price\x18\t \x01(\x01H\x01R\tstopPrice\x88\x01\x01\x12&\n" +
+	"\ffilled_price\x18\n" +
+	" \x01(\x01H\x02R\vfilledPrice\x88\x01\x01\x12\x16\n" +
+	"\x06reason\x18\v \x01(\tR\x06reasonB\x0e\n" +
+	"\f_limit_priceB\r\n" +
+	"\v_stop_priceB\x0f\n" +
+	"\r_filled_price\"\xc7\x02\n" +
+	"\x11TransactionUpdate\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x12)\n" +
+	"\x10transaction_type\x18\x02 \x01(\tR\x0ftransactionType\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x01R\bquantity\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x01R\x05price\x12!\n" +
+	"\ftotal_amount\x18\x06 \x01(\x01R\vtotalAmount\x12\x10\n" +
+	"\x03fee\x18\a \x01(\x01R\x03fee\x12%\n" +
+	"\x0eremaining_cash\x18\b \x01(\x01R\rremainingCash\x12(\n" +
+	"\rrealized_gain\x18\t \x01(\x01H\x00R\frealizedGain\x88\x01\x01B\x10\n" +
+	"\x0e_realized_gain\"\x80\x02\n" +
+	"\x0fPortfolioUpdate\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\tR\ttimestamp\x12?\n" +
+	"\tportfolio\x18\x03 \x01(\v2\x1f.portfolio.GetPortfolioResponseH\x00R\tportfolio\x12.\n" +
+	"\x05order\x18\x04 \x01(\v2\x16.portfolio.OrderUpdateH\x00R\x05order\x12@\n" +
+	"\vtransaction\x18\x05 \x01(\v2\x1c.portfolio.TransactionUpdateH\x00R\vtransactionB\b\n" +
+	"\x06update2\x97\x03\n" +
+	"\x10PortfolioService\x12C\n" +
+	"\bBuyStock\x12\x1a.portfolio.BuyStockRequest\x1a\x1b.portfolio.BuyStockResponse\x12F\n" +
+	"\tSellStock\x12\x1b.portfolio.SellStockRequest\x1a\x1c.portfolio.SellStockResponse\x12O\n" +
+	"\fGetPortfolio\x12\x1e.portfolio.GetPortfolioRequest\x1a\x1f.portfolio.GetPortfolioResponse\x12U\n" +
+	"\x0eGetPerformance\x12 .portfolio.GetPerformanceRequest\x1a!.portfolio.GetPerformanceResponse\x12N\n" +
+	"\rStreamUpdates\x12\x1f.portfolio.StreamUpdatesRequest\x1a\x1a.portfolio.PortfolioUpdate0\x01B>Z<github.com/FUNfarik/finance_microservices/proto/go/portfoliob\x06proto3"
+
+var (
+	file_portfolio_proto_rawDescOnce sync.Once
+	file_portfolio_proto_rawDescData []byte
+)
+
+func file_portfolio_proto_rawDescGZIP() []byte {
+	file_portfolio_proto_rawDescOnce.Do(func() {
+		file_portfolio_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_portfolio_proto_rawDesc), len(file_portfolio_proto_rawDesc)))
+	})
+	return file_portfolio_proto_rawDescData
+}
+
+var file_portfolio_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_portfolio_proto_goTypes = []any{
+	(*BuyStockRequest)(nil),        // 0: portfolio.BuyStockRequest
+	(*BuyStockResponse)(nil),       // 1: portfolio.BuyStockResponse
+	(*SellStockRequest)(nil),       // 2: portfolio.SellStockRequest
+	(*SellStockResponse)(nil),      // 3: portfolio.SellStockResponse
+	(*GetPortfolioRequest)(nil),    // 4: portfolio.GetPortfolioRequest
+	(*Holding)(nil),                // 5: portfolio.Holding
+	(*GetPortfolioResponse)(nil),   // 6: portfolio.GetPortfolioResponse
+	(*GetPerformanceRequest)(nil),  // 7: portfolio.GetPerformanceRequest
+	(*GetPerformanceResponse)(nil), // 8: portfolio.GetPerformanceResponse
+	(*StreamUpdatesRequest)(nil),   // 9: portfolio.StreamUpdatesRequest
+	(*OrderUpdate)(nil),            // 10: portfolio.OrderUpdate
+	(*TransactionUpdate)(nil),      // 11: portfolio.TransactionUpdate
+	(*PortfolioUpdate)(nil),        // 12: portfolio.PortfolioUpdate
+}
+var file_portfolio_proto_depIdxs = []int32{
+	5,  // 0: portfolio.GetPortfolioResponse.holdings:type_name -> portfolio.Holding
+	6,  // 1: portfolio.PortfolioUpdate.portfolio:type_name -> portfolio.GetPortfolioResponse
+	10, // 2: portfolio.PortfolioUpdate.order:type_name -> portfolio.OrderUpdate
+	11, // 3: portfolio.PortfolioUpdate.transaction:type_name -> portfolio.TransactionUpdate
+	0,  // 4: portfolio.PortfolioService.BuyStock:input_type -> portfolio.BuyStockRequest
+	2,  // 5: portfolio.PortfolioService.SellStock:input_type -> portfolio.SellStockRequest
+	4,  // 6: portfolio.PortfolioService.GetPortfolio:input_type -> portfolio.GetPortfolioRequest
+	7,  // 7: portfolio.PortfolioService.GetPerformance:input_type -> portfolio.GetPerformanceRequest
+	9,  // 8: portfolio.PortfolioService.StreamUpdates:input_type -> portfolio.StreamUpdatesRequest
+	1,  // 9: portfolio.PortfolioService.BuyStock:output_type -> portfolio.BuyStockResponse
+	3,  // 10: portfolio.PortfolioService.SellStock:output_type -> portfolio.SellStockResponse
+	6,  // 11: portfolio.PortfolioService.GetPortfolio:output_type -> portfolio.GetPortfolioResponse
+	8,  // 12: portfolio.PortfolioService.GetPerformance:output_type -> portfolio.GetPerformanceResponse
+	12, // 13: portfolio.PortfolioService.StreamUpdates:output_type -> portfolio.PortfolioUpdate
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_portfolio_proto_init() }
+func file_portfolio_proto_init() {
+	if File_portfolio_proto != nil {
+		return
+	}
+	file_portfolio_proto_msgTypes[8].OneofWrappers = []any{}
+	file_portfolio_proto_msgTypes[10].OneofWrappers = []any{}
+	file_portfolio_proto_msgTypes[11].OneofWrappers = []any{}
+	file_portfolio_proto_msgTypes[12].OneofWrappers = []any{
+		(*PortfolioUpdate_Portfolio)(nil),
+		(*PortfolioUpdate_Order)(nil),
+		(*PortfolioUpdate_Transaction)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_portfolio_proto_rawDesc), len(file_portfolio_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_portfolio_proto_goTypes,
+		DependencyIndexes: file_portfolio_proto_depIdxs,
+		MessageInfos:      file_portfolio_proto_msgTypes,
+	}.Build()
+	File_portfolio_proto = out.File
+	file_portfolio_proto_goTypes = nil
+	file_portfolio_proto_depIdxs = nil
+}